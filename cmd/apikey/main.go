@@ -0,0 +1,107 @@
+// Command apikey manages the keys of the management API, e.g. to create
+// the first admin key of a new deployment:
+//
+//	CONFIG_PATH=config/local.yaml go run ./cmd/apikey create -name ops -scopes admin
+//	CONFIG_PATH=config/local.yaml go run ./cmd/apikey list
+//	CONFIG_PATH=config/local.yaml go run ./cmd/apikey revoke -id 2
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/config"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/postgres"
+	"github.com/popvaleks/url-shortener/internal/storage/sqlite"
+)
+
+const usage = `usage: apikey <command> [flags]
+
+commands:
+  create -name N -scopes S    create a key, S is a comma separated list of read, write, admin
+  list                        print the keys, revoked ones included
+  revoke -id ID               revoke a key
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	name := flags.String("name", "", "name of the key")
+	scopes := flags.String("scopes", auth.ScopeRead, "comma separated scopes of the key")
+	id := flags.Int64("id", 0, "id of the key to revoke")
+	_ = flags.Parse(os.Args[2:])
+
+	cfg := config.MustLoad()
+
+	repo, err := setupStorage(cfg)
+	if err != nil {
+		fail(err)
+	}
+	defer repo.Close()
+
+	keys := auth.New(repo)
+
+	switch command {
+	case "create":
+		if *name == "" {
+			fail(fmt.Errorf("create needs -name"))
+		}
+		plain, key, err := keys.Create(*name, strings.Split(*scopes, ","))
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("created key %d %q with scopes %s, it is shown only once:\n%s\n",
+			key.ID, key.Name, strings.Join(key.Scopes, ","), plain)
+	case "list":
+		list, err := keys.List()
+		if err != nil {
+			fail(err)
+		}
+		for _, key := range list {
+			state := "active"
+			if key.RevokedAt != nil {
+				state = "revoked " + key.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Prefix, key.Name,
+				strings.Join(key.Scopes, ","), key.CreatedAt.Format(time.RFC3339), state)
+		}
+	case "revoke":
+		if *id == 0 {
+			fail(fmt.Errorf("revoke needs -id"))
+		}
+		if err := keys.Revoke(*id); err != nil {
+			fail(err)
+		}
+		fmt.Printf("revoked key %d\n", *id)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func setupStorage(cfg *config.Config) (storage.Repository, error) {
+	switch cfg.StorageType {
+	case config.StoragePostgres:
+		return postgres.New(cfg.StorageDSN)
+	case config.StorageMemory:
+		return nil, fmt.Errorf("memory storage keeps no keys between runs, the server logs an admin key at start")
+	default:
+		return sqlite.New(cfg.StoragePath)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "apikey:", err)
+	os.Exit(1)
+}
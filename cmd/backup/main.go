@@ -0,0 +1,85 @@
+// Command backup snapshots the configured sqlite database while the
+// server keeps running and restores it from a snapshot:
+//
+//	CONFIG_PATH=config/local.yaml go run ./cmd/backup create
+//	CONFIG_PATH=config/local.yaml go run ./cmd/backup list
+//	CONFIG_PATH=config/local.yaml go run ./cmd/backup restore -file storage/backups/backup-20250301T100000.000Z.db
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/backup"
+	"github.com/popvaleks/url-shortener/internal/config"
+	"github.com/popvaleks/url-shortener/internal/storage/sqlite"
+)
+
+const usage = `usage: backup <command> [flags]
+
+commands:
+  create             snapshot the database into the backup dir
+  list               print the backups, newest first
+  restore -file F    replace the database with backup F, the server must be stopped
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	file := flags.String("file", "", "backup file to restore")
+	_ = flags.Parse(os.Args[2:])
+
+	cfg := config.MustLoad()
+
+	if cfg.StorageType != config.StorageSqlite {
+		fail(fmt.Errorf("storage %q has no backups, only sqlite does", cfg.StorageType))
+	}
+
+	backups := backup.New(sqlite.File(cfg.StoragePath), cfg.Backup.Dir, cfg.Backup.Keep)
+
+	switch command {
+	case "create":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		info, err := backups.Create(ctx)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("created %s (%d bytes)\n", info.Path, info.Size)
+	case "list":
+		list, err := backups.List()
+		if err != nil {
+			fail(err)
+		}
+		for _, info := range list {
+			fmt.Printf("%s\t%d\t%s\n", info.CreatedAt.Format(time.RFC3339), info.Size, info.Path)
+		}
+	case "restore":
+		if *file == "" {
+			fail(fmt.Errorf("restore needs -file"))
+		}
+		if err := backup.Restore(*file, cfg.StoragePath); err != nil {
+			fail(err)
+		}
+		fmt.Printf("restored %s from %s\n", cfg.StoragePath, *file)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "backup:", err)
+	os.Exit(1)
+}
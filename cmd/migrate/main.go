@@ -0,0 +1,101 @@
+// Command migrate manages the schema of the configured storage
+// without starting the server, e.g. before a deploy:
+//
+//	CONFIG_PATH=config/local.yaml go run ./cmd/migrate up
+//	CONFIG_PATH=config/local.yaml go run ./cmd/migrate down -steps 1
+//	CONFIG_PATH=config/local.yaml go run ./cmd/migrate version
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/popvaleks/url-shortener/internal/config"
+	"github.com/popvaleks/url-shortener/internal/storage/migrator"
+	"github.com/popvaleks/url-shortener/internal/storage/postgres"
+	"github.com/popvaleks/url-shortener/internal/storage/sqlite"
+)
+
+const usage = `usage: migrate <command> [flags]
+
+commands:
+  up               apply all pending migrations
+  down -steps N    revert the last N migrations (default 1)
+  version          print the current and the latest known schema version
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+	_ = flags.Parse(os.Args[2:])
+
+	cfg := config.MustLoad()
+
+	m, db, err := setupMigrator(cfg)
+	if err != nil {
+		fail(err)
+	}
+	defer db.Close()
+
+	switch command {
+	case "up":
+		applied, err := m.Up()
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("applied %d migration(s)\n", applied)
+	case "down":
+		reverted, err := m.Down(*steps)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("reverted %d migration(s)\n", reverted)
+	case "version":
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("schema version %d, latest known %d\n", version, m.Latest())
+}
+
+func setupMigrator(cfg *config.Config) (*migrator.Migrator, *sql.DB, error) {
+	var (
+		db  *sql.DB
+		m   *migrator.Migrator
+		err error
+	)
+
+	switch cfg.StorageType {
+	case config.StorageSqlite:
+		if db, err = sql.Open(migrator.SQLite, cfg.StoragePath); err == nil {
+			m, err = sqlite.NewMigrator(db)
+		}
+	case config.StoragePostgres:
+		if db, err = sql.Open(migrator.Postgres, cfg.StorageDSN); err == nil {
+			m, err = postgres.NewMigrator(db)
+		}
+	default:
+		return nil, nil, fmt.Errorf("storage %q has no schema to migrate", cfg.StorageType)
+	}
+
+	return m, db, err
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "migrate:", err)
+	os.Exit(1)
+}
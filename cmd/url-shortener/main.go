@@ -1,21 +1,60 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	_ "github.com/popvaleks/url-shortener/docs"
+	"github.com/popvaleks/url-shortener/internal/analytics"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/auth/token"
+	"github.com/popvaleks/url-shortener/internal/backup"
 	"github.com/popvaleks/url-shortener/internal/config"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/admin/createBackup"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/admin/createKey"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/admin/createWorkspace"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/admin/listKeys"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/admin/listWorkspaces"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/admin/revokeKey"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/addTags"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/exportUrls"
 	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/getAllUrls"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/importUrls"
 	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/redirect"
 	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/remove"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/removeByTag"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/removeTag"
 	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/save"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/saveBatch"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/search"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/stats"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/transferOwner"
 	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/updateUrl"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/workspace/listMembers"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/workspace/removeMember"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/workspace/setMember"
+	"github.com/popvaleks/url-shortener/internal/http-server/middleware/apikey"
+	"github.com/popvaleks/url-shortener/internal/http-server/middleware/jwtauth"
 	mwLogger "github.com/popvaleks/url-shortener/internal/http-server/middleware/logger"
+	"github.com/popvaleks/url-shortener/internal/http-server/middleware/ratelimit"
+	"github.com/popvaleks/url-shortener/internal/http-server/middleware/workspace"
+	aliasgen "github.com/popvaleks/url-shortener/internal/lib/alias"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/reaper"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/memory"
+	"github.com/popvaleks/url-shortener/internal/storage/postgres"
 	"github.com/popvaleks/url-shortener/internal/storage/sqlite"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
 const (
@@ -24,26 +63,72 @@ const (
 	envProd  = "prod"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 // @title Url shortener
 // @version 1.0
 // @description Shortener service
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description "Bearer " followed by the key or a JWT, or the key alone in X-API-Key
 func main() {
 	// CONFIG_PATH=config/local.yaml
 	cfg := config.MustLoad()
 
 	log := setupLogger(cfg.Env)
 
-	log.Info("starting url-shortener", slog.String("ENVIRONMENT", cfg.Env))
+	log.Info("starting url-shortener",
+		slog.String("ENVIRONMENT", cfg.Env),
+		slog.String("storage", cfg.StorageType),
+	)
 	log.Debug("debug start message")
 
-	storage, err := sqlite.New(cfg.StoragePath)
-
+	storage, err := setupStorage(cfg)
 	if err != nil {
 		log.Error("error creating storage", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
+	defer storage.Close()
+
+	aliasGenerator, err := aliasgen.NewGenerator(cfg.Alias.Generator, cfg.Alias.Alphabet, cfg.Alias.Salt)
+	if err != nil {
+		log.Error("error creating alias generator", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	aliasPolicy := aliasgen.NewPolicy(aliasgen.PolicyOptions{
+		MinLength:       cfg.Alias.Policy.MinLength,
+		MaxLength:       cfg.Alias.Policy.MaxLength,
+		Charset:         cfg.Alias.Policy.Charset,
+		Reserved:        cfg.Alias.Policy.Reserved,
+		Profanity:       cfg.Alias.Policy.Profanity,
+		RejectAmbiguous: cfg.Alias.Policy.RejectAmbiguous,
+	})
+
+	aliases := aliasgen.NewSource(aliasGenerator, aliasgen.Options{
+		Length:      cfg.Alias.Length,
+		MaxAttempts: cfg.Alias.MaxAttempts,
+		GrowAfter:   cfg.Alias.GrowAfter,
+		Policy:      aliasPolicy,
+	})
+
+	validate := resp.NewValidator()
+	if err := aliasPolicy.Register(validate); err != nil {
+		log.Error("error registering alias validation", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	clickPipeline := analytics.NewPipeline(log, storage, analytics.Options{
+		BufferSize:    cfg.Analytics.BufferSize,
+		Workers:       cfg.Analytics.Workers,
+		BatchSize:     cfg.Analytics.BatchSize,
+		FlushInterval: cfg.Analytics.FlushInterval,
+		Policy:        cfg.Analytics.Policy,
+		BlockTimeout:  cfg.Analytics.BlockTimeout,
+	})
+	expvar.Publish("analytics", expvar.Func(func() any { return clickPipeline.Counters() }))
 
 	router := chi.NewRouter()
 
@@ -52,16 +137,135 @@ func main() {
 	router.Use(mwLogger.New(log))
 	router.Use(middleware.Recoverer) // anti panic
 	router.Use(middleware.URLFormat) // routing
+	if cfg.LegacyErrors {
+		router.Use(resp.Legacy)
+	}
+
+	keys := auth.New(storage)
+	requireScope := func(scope string) func(http.Handler) http.Handler {
+		return apikey.New(log, keys, scope)
+	}
+	// principal tells handlers who a request acts for, links belong to it
+	principal := apikey.Principal
+	if !cfg.Auth.Enabled {
+		log.Warn("auth is disabled, anyone can manage the links")
+		requireScope = func(string) func(http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler { return next }
+		}
+		principal = func(*http.Request) auth.Principal { return auth.Unrestricted }
+	} else if cfg.StorageType == config.StorageMemory {
+		// nothing else can create the first key of a storage that lives in the process
+		plain, _, err := keys.Create("bootstrap", []string{auth.ScopeAdmin})
+		if err != nil {
+			log.Error("error creating api key", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		log.Warn("created an admin api key for the memory storage", slog.String("key", plain))
+	} else if list, err := keys.List(); err == nil && len(list) == 0 && !cfg.Auth.JWT.Enabled {
+		log.Warn("auth is enabled but there are no api keys, create one with cmd/apikey")
+	}
+
+	if cfg.Auth.Enabled && cfg.Auth.JWT.Enabled {
+		verifier, err := setupTokens(cfg.Auth.JWT)
+		if err != nil {
+			log.Error("error loading jwt keys", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
+		// a valid token stands in for a key, the scope is checked the same way
+		tokens := jwtauth.New(log, verifier)
+		requireKey := requireScope
+		requireScope = func(scope string) func(http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler { return tokens(requireKey(scope)(next)) }
+		}
+	}
 
 	router.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"),
 	))
 
-	router.Post("/url", save.New(log, storage))
-	router.Get("/{alias}", redirect.New(log, storage))
-	router.Delete("/{alias}", remove.New(log, storage))
-	router.Get("/url", getAllUrls.New(log, storage))
-	router.Patch("/{alias}", updateUrl.New(log, storage))
+	// the workspace comes from the path or the host, the role in it is
+	// checked after the scope of the key
+	resolver := workspace.NewResolver(cfg.Workspaces.Hosts)
+	requireRole := func(role string) func(http.Handler) http.Handler {
+		return workspace.New(log, storage, resolver, principal, role)
+	}
+	linkPrincipal := workspace.Principal(principal)
+	ws := resolver.Of
+
+	// the limiters are shared by the routes in the root and under /w/,
+	// the guards count failed authentications by IP in front of the keys
+	guardRead, limitRead := setupRateLimit(log, cfg.RateLimit.Read)
+	guardWrite, limitWrite := setupRateLimit(log, cfg.RateLimit.Write)
+	_, limitRedirect := setupRateLimit(log, cfg.RateLimit.Redirect)
+	guardAdmin, limitAdmin := setupRateLimit(log, cfg.RateLimit.Admin)
+
+	router.With(guardAdmin, requireScope(auth.ScopeAdmin)).Handle("/debug/vars", expvar.Handler())
+
+	mountLinks := func(r chi.Router) {
+		// redirects are public, everything else needs a key
+		r.With(limitRedirect).Get("/{alias}", redirect.New(log, storage, clickPipeline, ws))
+
+		r.Group(func(r chi.Router) {
+			r.Use(guardRead, requireScope(auth.ScopeRead), limitRead, requireRole(auth.RoleViewer))
+
+			r.Get("/url", getAllUrls.New(log, storage, ws))
+			r.Get("/url/search", search.New(log, storage, ws))
+			r.Get("/url/export", exportUrls.New(log, storage, ws))
+			r.Get("/url/{alias}/stats", stats.New(log, storage, ws))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(guardWrite, requireScope(auth.ScopeWrite), limitWrite, requireRole(auth.RoleEditor))
+
+			r.Post("/url", save.New(log, storage, validate, aliases, cfg.Dedup, linkPrincipal, ws))
+			r.Post("/url/batch", saveBatch.New(log, storage, validate, aliases, linkPrincipal, ws))
+			r.Delete("/{alias}", remove.New(log, storage, linkPrincipal, ws))
+			r.Post("/url/import", importUrls.New(log, storage, linkPrincipal, ws))
+			r.Patch("/{alias}", updateUrl.New(log, storage, validate, linkPrincipal, ws))
+			r.Put("/url/{alias}/owner", transferOwner.New(log, storage, linkPrincipal, ws))
+			r.Post("/url/{alias}/tags", addTags.New(log, storage, linkPrincipal, ws))
+			r.Delete("/url/{alias}/tags/{tag}", removeTag.New(log, storage, linkPrincipal, ws))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(guardWrite, requireScope(auth.ScopeWrite), limitWrite, requireRole(auth.RoleAdmin))
+
+			// a tag is shared by all owners, so deleting by tag is for admins
+			r.Delete("/url", removeByTag.New(log, storage, ws))
+
+			r.Get("/members", listMembers.New(log, storage, ws))
+			r.Put("/members/{name}", setMember.New(log, storage, ws))
+			r.Delete("/members/{name}", removeMember.New(log, storage, ws))
+		})
+	}
+
+	mountLinks(router)
+	router.Route("/w/{"+workspace.Param+"}", mountLinks)
+
+	router.Group(func(r chi.Router) {
+		r.Use(guardAdmin, requireScope(auth.ScopeAdmin), limitAdmin)
+
+		r.Post("/admin/keys", createKey.New(log, keys))
+		r.Get("/admin/keys", listKeys.New(log, keys))
+		r.Delete("/admin/keys/{id}", revokeKey.New(log, keys))
+		r.Post("/admin/workspaces", createWorkspace.New(log, storage))
+		r.Get("/admin/workspaces", listWorkspaces.New(log, storage))
+
+		// the online backup is a sqlite feature, postgres has its own tools
+		if db, ok := storage.(*sqlite.Storage); ok {
+			backups := backup.New(db, cfg.Backup.Dir, cfg.Backup.Keep)
+			r.Post("/admin/backup", createBackup.New(log, backups))
+		}
+	})
+
+	// an alias named like a route would be shadowed by it
+	aliasPolicy.Reserve(routeWords(router)...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go reaper.New(log, storage, cfg.Reaper.Interval, cfg.Reaper.Mode).Run(ctx)
 
 	log.Info("starting server", slog.String("address", cfg.Address))
 
@@ -73,11 +277,107 @@ func main() {
 		IdleTimeout:  cfg.HttpServer.IdleTimeout,
 	}
 
-	if err := srv.ListenAndServe(); err != nil {
-		log.Error("error starting server", slog.String("error", err.Error()))
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("error starting server", slog.String("error", err.Error()))
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+
+	log.Info("stopping server", slog.String("address", cfg.Address))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("error stopping server", slog.String("error", err.Error()))
 	}
 
-	log.Error("stopping server", slog.String("address", cfg.Address))
+	// the server no longer records clicks, flush what is buffered
+	clickPipeline.Close()
+}
+
+// routeWords returns the static first segments of the routes.
+func routeWords(routes chi.Routes) []string {
+	var words []string
+
+	_ = chi.Walk(routes, func(_, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if segment != "" && !strings.ContainsAny(segment, "{*") {
+			words = append(words, segment)
+		}
+
+		return nil
+	})
+
+	return words
+}
+
+// setupRateLimit returns the middlewares that limit a route group: guard
+// goes in front of authentication, limit after it. Both let everything
+// through when the limit is off.
+func setupRateLimit(log *slog.Logger, limit config.Limit) (guard, limited func(http.Handler) http.Handler) {
+	if limit.Requests == 0 {
+		off := func(next http.Handler) http.Handler { return next }
+		return off, off
+	}
+
+	limiter := ratelimit.NewLimiter(ratelimit.Limit{
+		Requests: limit.Requests,
+		Per:      limit.Per,
+		Burst:    limit.Burst,
+	})
+
+	return ratelimit.Guard(log, limiter), ratelimit.New(log, limiter, ratelimit.Client)
+}
+
+func setupTokens(cfg config.JWT) (*token.Verifier, error) {
+	var keys token.Sources
+
+	if cfg.JWKSFile != "" {
+		jwks, err := token.LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwks)
+	}
+
+	if cfg.PublicKeyFile != "" {
+		key, err := token.LoadPEM(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if cfg.Secret != "" {
+		keys = append(keys, token.Secret(cfg.Secret))
+	}
+
+	return token.NewVerifier(keys, token.Options{
+		Issuer:   cfg.Issuer,
+		Audience: cfg.Audience,
+		Leeway:   cfg.Leeway,
+		Claims: token.Claims{
+			Name:      cfg.Claims.Name,
+			Scopes:    cfg.Claims.Scopes,
+			Workspace: cfg.Claims.Workspace,
+			Role:      cfg.Claims.Role,
+		},
+	}), nil
+}
+
+func setupStorage(cfg *config.Config) (storage.Repository, error) {
+	switch cfg.StorageType {
+	case config.StoragePostgres:
+		return postgres.New(cfg.StorageDSN)
+	case config.StorageMemory:
+		return memory.New(), nil
+	default:
+		return sqlite.New(cfg.StoragePath)
+	}
 }
 
 func setupLogger(env string) (log *slog.Logger) {
@@ -15,33 +15,98 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/url": {
-            "get": {
-                "description": "Returns all existing short URL mappings",
+        "/admin/backup": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Snapshots the SQLite database while the server keeps serving and removes\nthe backups beyond the retention limit. Only available with sqlite storage.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "url"
+                    "admin"
                 ],
-                "summary": "Get all URLs",
+                "summary": "Back up the database",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_url_getAllUrls.Response"
+                            "$ref": "#/definitions/internal_http-server_handlers_admin_createBackup.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the keys of the management API in creation order, revoked ones included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_admin_listKeys.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Creates a short alias for the provided URL",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Issues a key for the management API. Save the key from the response,\nit cannot be shown again.",
                 "consumes": [
                     "application/json"
                 ],
@@ -49,17 +114,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "url"
+                    "admin"
                 ],
-                "summary": "Save URL",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "description": "URL shortening request data",
-                        "name": "input",
+                        "description": "Key name and scopes",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Request"
+                            "$ref": "#/definitions/internal_http-server_handlers_admin_createKey.Request"
                         }
                     }
                 ],
@@ -67,102 +132,267 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Response"
+                            "$ref": "#/definitions/internal_http-server_handlers_admin_createKey.Response"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     }
                 }
             }
         },
-        "/{alias}": {
-            "get": {
-                "description": "Redirects to the original URL associated with the provided alias",
+        "/admin/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revokes a key at once, requests with it are rejected from then on.\nRevoking a revoked key is not an error.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "url"
+                    "admin"
                 ],
-                "summary": "Redirect by alias",
+                "summary": "Revoke an API key",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Alias for the URL to redirect",
-                        "name": "alias",
+                        "type": "integer",
+                        "description": "Id of the key",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "302": {
-                        "description": "Redirects to the original URL"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_admin_revokeKey.Response"
+                        }
                     },
                     "400": {
-                        "description": "Alias is missing",
+                        "description": "Id is not a number",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no admin scope",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     },
                     "404": {
-                        "description": "URL not found for the provided alias",
+                        "description": "API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/workspaces": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the workspaces by name, the default one included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List workspaces",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_admin_listWorkspaces.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     }
                 }
             },
-            "delete": {
-                "description": "Deletes a short URL by its alias",
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a workspace with its own aliases, links and members.\nOnly admin keys work in it until members are added.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "url"
+                    "admin"
                 ],
-                "summary": "Delete URL by alias",
+                "summary": "Create a workspace",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Alias of the URL to delete",
-                        "name": "alias",
-                        "in": "path",
-                        "required": true
+                        "description": "Workspace name",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_admin_createWorkspace.Request"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_url_remove.Response"
+                            "$ref": "#/definitions/internal_http-server_handlers_admin_createWorkspace.Response"
                         }
                     },
                     "400": {
-                        "description": "Alias is missing",
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Workspace already exists",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/members": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the members of the workspace by name. Global admin keys are not listed,\nthey are admins of every workspace. Under /w/{workspace}/members for other workspaces.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspace"
+                ],
+                "summary": "List workspace members",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/internal_http-server_handlers_workspace_listMembers.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "No admin role in the workspace",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     },
                     "404": {
-                        "description": "URL not found",
+                        "description": "Workspace not found",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "Updates original URL for existing alias",
+            }
+        },
+        "/members/{name}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Gives API keys with the name a role in the workspace, or changes the role\nthey have. Under /w/{workspace}/members/{name} for other workspaces.",
                 "consumes": [
                     "application/json"
                 ],
@@ -170,24 +400,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "url"
+                    "workspace"
                 ],
-                "summary": "Update URL by alias",
+                "summary": "Add a workspace member",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Alias to update",
-                        "name": "alias",
+                        "description": "Name of API keys, see POST /admin/keys",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "New URL data",
-                        "name": "input",
+                        "description": "Role",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_url_updateUrl.Request"
+                            "$ref": "#/definitions/internal_http-server_handlers_workspace_setMember.Request"
                         }
                     }
                 ],
@@ -195,58 +425,1591 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_url_updateUrl.Response"
+                            "$ref": "#/definitions/internal_http-server_handlers_workspace_setMember.Response"
                         }
                     },
                     "400": {
-                        "description": "Invalid request or validation error",
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "No admin role in the workspace",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     },
                     "404": {
-                        "description": "Alias not found",
+                        "description": "Workspace not found",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Response"
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "github_com_popvaleks_url-shortener_internal_lib_api_response.Response": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "string"
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Takes the role in the workspace from API keys with the name. Their links stay.\nUnder /w/{workspace}/members/{name} for other workspaces.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspace"
+                ],
+                "summary": "Remove a workspace member",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of API keys",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_workspace_removeMember.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "No admin role in the workspace",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Workspace or member not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
                 }
             }
         },
-        "internal_http-server_handlers_url_getAllUrls.Response": {
-            "description": "Success response containing all URL mappings",
+        "/url": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a page of short URL mappings. Pass next_cursor of the response\nas cursor to get the next page with the same sort and order.\nEvery member of the workspace sees all of its links.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "List URLs",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size, 100 by default, at most 1000",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created",
+                            "alias"
+                        ],
+                        "type": "string",
+                        "default": "created",
+                        "description": "Sort key",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "asc",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only aliases starting with the prefix",
+                        "name": "alias_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links to the domain and its subdomains",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links with the tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links of the owner",
+                        "name": "owner",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_getAllUrls.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no read scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a short alias for the provided URL or, with deduplication,\nreturns the generated alias of an earlier link of the caller to it.\nThe link belongs to the caller.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Save URL",
+                "parameters": [
+                    {
+                        "description": "URL shortening request data",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_save.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or validation error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no write scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Alias already exists",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "No free alias found, retry later",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes every link of the workspace labelled with the tag",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Delete URLs by tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag of the links to delete",
+                        "name": "tag",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_removeByTag.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Tag is missing",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no write scope or no admin role in the workspace",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates short aliases for many URLs in one transaction, the links belong to the caller",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Save URLs in batch",
+                "parameters": [
+                    {
+                        "description": "Batch of URL shortening requests",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_saveBatch.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_saveBatch.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Atomic batch with invalid items",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_saveBatch.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no write scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Atomic batch with taken aliases",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_saveBatch.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams every link of the workspace with its limits, clicks and tags as CSV or NDJSON,\nthe format accepted by POST /url/import.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Export URLs",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "default": "ndjson",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links of the owner",
+                        "name": "owner",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown format",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no read scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/import": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Imports links from a file made by GET /url/export in one transaction.\nOnly alias and url are required. on_conflict decides what happens to aliases\nthat already exist: fail (the default) imports nothing, skip keeps the existing\nlinks, overwrite replaces them. With dry_run nothing is saved and the response\nshows what would change.\nImported links belong to the caller. Only admins keep the owners of the file\nand can overwrite links.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Import URLs",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "File format, taken from Content-Type if omitted",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "fail",
+                            "skip",
+                            "overwrite"
+                        ],
+                        "type": "string",
+                        "default": "fail",
+                        "description": "Conflict policy",
+                        "name": "on_conflict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Report changes without saving them",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_importUrls.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file or parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no write scope or overwrite without admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Aliases already exist",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_importUrls.Response"
+                        }
+                    },
+                    "413": {
+                        "description": "File larger than 64 MB",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Finds links whose alias or target URL contain every word of the query\nas a word prefix. Alias matches rank higher.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Search URLs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "pricing",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only links of the owner",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 20 by default, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "next_offset of the previous page",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_search.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no read scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{alias}/owner": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Gives the link to another owner. Only the owner of the link or an admin\ncan transfer it, links without an owner are transferred by admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Transfer URL ownership",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Alias of the URL",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New owner",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_transferOwner.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_transferOwner.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no write scope or the URL belongs to another owner",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{alias}/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the total and per-day number of redirects of the alias",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Get URL click statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Alias to get statistics for",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_stats.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Alias is missing",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no read scope",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{alias}/tags": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Labels the link with tags, tags it already has are ignored.\nOnly the owner of the link or an admin can tag it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Add tags to URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Alias of the URL",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags to add",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_addTags.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_addTags.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tags",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no write scope or the URL belongs to another owner",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{alias}/tags/{tag}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes a tag from the link. Only the owner of the link or an admin can do it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Remove tag from URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Alias of the URL",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag to remove",
+                        "name": "tag",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_removeTag.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Alias or tag is missing",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no write scope or the URL belongs to another owner",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL or tag not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/{alias}": {
+            "get": {
+                "description": "Redirects to the original URL associated with the provided alias.\nLinks of other workspaces are served under /w/{workspace}/{alias} or from their hosts.",
+                "tags": [
+                    "url"
+                ],
+                "summary": "Redirect by alias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Alias for the URL to redirect",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Redirects to the original URL"
+                    },
+                    "400": {
+                        "description": "Alias is missing",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found for the provided alias",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "Link has expired or used up its clicks",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a short URL by its alias. Only the owner of the link or an admin can delete it.",
+                "tags": [
+                    "url"
+                ],
+                "summary": "Delete URL by alias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Alias of the URL to delete",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_remove.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Alias is missing",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no write scope or the URL belongs to another owner",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "URL not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Updates original URL for existing alias and renames it.\nOnly the owner of the link or an admin can change it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Update URL by alias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Alias to update",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New URL data",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_updateUrl.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_url_updateUrl.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or validation error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "API key required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "API key has no write scope or the alias belongs to another owner",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Alias not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "New alias already exists",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_popvaleks_url-shortener_internal_http-server_handlers_url_save.Request": {
+            "description": "Request to create a short URL. Use either expires_at (RFC 3339) or ttl (e.g. \"90m\", \"168h\", \"7d\") for a temporary link and max_clicks for a link that stops resolving after that many redirects. An alias is made up unless given. A given alias must fit the alias policy of the server: length bounds, allowed characters, no reserved or profane words. Tags are case-insensitive and must not contain \"/\", \"?\", \"#\" or \"%\". With dedup a request without alias, expiration and click limit returns the generated alias of an earlier link to the same url, dedup overrides the server default.",
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "dedup": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "tags": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ttl": {
+                    "type": "string",
+                    "example": "168h"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "Field is the JSON path of the field, e.g. items[2].url",
+                    "type": "string",
+                    "example": "url"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must be a valid URL"
+                },
+                "param": {
+                    "description": "Param is the argument of the rule, e.g. 3 for min=3",
+                    "type": "string"
+                },
+                "rule": {
+                    "description": "Rule is the validation that failed: required, url, min, max, oneof,\nalias_charset, alias_reserved, alias_profane, alias_ambiguous, ...",
+                    "type": "string",
+                    "example": "url"
+                }
+            }
+        },
+        "github_com_popvaleks_url-shortener_internal_lib_api_response.Problem": {
+            "description": "Error details, RFC 7807. code is stable and meant for programs, detail is the message for people.",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "url_not_found"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "url not found"
+                },
+                "errors": {
+                    "description": "Errors are the failed fields of a validation_failed problem",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "internal_http-server_handlers_admin_createBackup.Response": {
+            "description": "Name and size of the backup file",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_admin_createKey.Request": {
+            "description": "Name of the key and its scopes: read, write or admin. write includes read, admin includes both.",
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "ci"
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read",
+                        "write"
+                    ]
+                }
+            }
+        },
+        "internal_http-server_handlers_admin_createKey.Response": {
+            "description": "The key is shown only once, only its hash is stored.",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "us_1a2b3c4d_9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "1a2b3c4d"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_admin_createWorkspace.Request": {
+            "description": "The name goes to paths and hosts: lowercase letters, digits and hyphens.",
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 63,
+                    "example": "docs"
+                }
+            }
+        },
+        "internal_http-server_handlers_admin_createWorkspace.Response": {
+            "description": "Links of the workspace are managed under /w/{name}",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_admin_listKeys.Key": {
+            "description": "The key itself is not stored, the prefix tells keys apart",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "1a2b3c4d"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "internal_http-server_handlers_admin_listKeys.Response": {
+            "description": "All keys, revoked ones included",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_admin_listKeys.Key"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_admin_listWorkspaces.Response": {
+            "description": "All workspaces, the default one included",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "workspaces": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_admin_listWorkspaces.Workspace"
+                    }
+                }
+            }
+        },
+        "internal_http-server_handlers_admin_listWorkspaces.Workspace": {
+            "description": "Workspace with its own aliases, links and members",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "docs"
+                }
+            }
+        },
+        "internal_http-server_handlers_admin_revokeKey.Response": {
+            "description": "Success response for API key revocation",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_addTags.Request": {
+            "description": "Tags to add to the link. Tags are case-insensitive and must not contain \"/\", \"?\", \"#\" or \"%\".",
+            "type": "object",
+            "required": [
+                "tags"
+            ],
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "spring",
+                        "team-a"
+                    ]
+                }
+            }
+        },
+        "internal_http-server_handlers_url_addTags.Response": {
+            "description": "Success response with all tags of the link",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "internal_http-server_handlers_url_getAllUrls.Link": {
+            "description": "Short link with its target and limits",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "clicks": {
+                    "description": "Clicks is only counted for click-limited links",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "type": "integer"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_getAllUrls.Response": {
+            "description": "Success response containing an ordered page of links and the cursor of the next page",
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string"
                 },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
                 "result": {
-                    "type": "object",
-                    "additionalProperties": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_url_getAllUrls.Link"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_importUrls.Response": {
+            "description": "Number of created, overwritten and skipped links and the aliases that already existed",
+            "type": "object",
+            "properties": {
+                "conflicts": {
+                    "type": "array",
+                    "items": {
                         "type": "string"
                     }
                 },
+                "created": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "skipped": {
+                    "type": "integer"
+                },
                 "status": {
                     "type": "string"
+                },
+                "updated": {
+                    "type": "integer"
                 }
             }
         },
@@ -257,13 +2020,58 @@ const docTemplate = `{
                 "error": {
                     "type": "string"
                 },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_removeByTag.Response": {
+            "description": "Success response with the number of deleted links",
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_removeTag.Response": {
+            "description": "Success response for tag removal",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
                 "status": {
                     "type": "string"
                 }
             }
         },
         "internal_http-server_handlers_url_save.Request": {
-            "description": "Request to create a short URL",
+            "description": "Request to create a short URL. Use either expires_at (RFC 3339) or ttl (e.g. \"90m\", \"168h\", \"7d\") for a temporary link and max_clicks for a link that stops resolving after that many redirects. An alias is made up unless given. A given alias must fit the alias policy of the server: length bounds, allowed characters, no reserved or profane words. Tags are case-insensitive and must not contain \"/\", \"?\", \"#\" or \"%\". With dedup a request without alias, expiration and click limit returns the generated alias of an earlier link to the same url, dedup overrides the server default.",
             "type": "object",
             "required": [
                 "url"
@@ -272,6 +2080,27 @@ const docTemplate = `{
                 "alias": {
                     "type": "string"
                 },
+                "dedup": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "tags": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ttl": {
+                    "type": "string",
+                    "example": "168h"
+                },
                 "url": {
                     "type": "string"
                 }
@@ -287,18 +2116,248 @@ const docTemplate = `{
                 "error": {
                     "type": "string"
                 },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "existing": {
+                    "description": "Existing is set when the alias of an earlier link is returned",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "type": "integer"
+                },
                 "status": {
                     "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "internal_http-server_handlers_url_updateUrl.Request": {
-            "description": "Request to update original URL for existing alias",
+        "internal_http-server_handlers_url_saveBatch.Request": {
+            "description": "Up to 1000 links, each item is validated like a POST /url request. In atomic mode (the default) nothing is saved unless every item can be, in best_effort mode valid items are saved and the rest are reported.",
             "type": "object",
             "required": [
-                "url"
+                "items"
+            ],
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_http-server_handlers_url_save.Request"
+                    }
+                },
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "best_effort"
+                    ],
+                    "example": "atomic"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_saveBatch.Response": {
+            "description": "Number of saved links and a result per item",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_url_saveBatch.Result"
+                    }
+                },
+                "saved": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_saveBatch.Result": {
+            "description": "Either the alias of the saved link or the reason it was not saved",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "description": "Errors are the failed fields of an invalid item",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                }
+            }
+        },
+        "internal_http-server_handlers_url_search.Link": {
+            "description": "Short link matching the query",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_search.Response": {
+            "description": "Success response with links ordered by relevance",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "next_offset": {
+                    "description": "NextOffset is set when there are more results",
+                    "type": "integer"
+                },
+                "result": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_url_search.Link"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_stats.DayCount": {
+            "description": "Number of clicks during one UTC day",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string",
+                    "example": "2025-03-01"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_stats.Response": {
+            "description": "Success response with click statistics",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "result": {
+                    "$ref": "#/definitions/internal_http-server_handlers_url_stats.Result"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_stats.Result": {
+            "description": "Total and per-day click counts",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "per_day": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_url_stats.DayCount"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_transferOwner.Request": {
+            "description": "The new owner is the name of API keys, see POST /admin/keys.",
+            "type": "object",
+            "required": [
+                "owner"
             ],
             "properties": {
+                "owner": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "marketing"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_transferOwner.Response": {
+            "description": "Success response with the new owner of the link",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_url_updateUrl.Request": {
+            "description": "Request to update original URL or alias of an existing link, at least one is required. A new alias must fit the alias policy like in POST /url.",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "spring-sale"
+                },
                 "url": {
                     "type": "string"
                 }
@@ -311,6 +2370,12 @@ const docTemplate = `{
                 "error": {
                     "type": "string"
                 },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
                 "result": {
                     "$ref": "#/definitions/internal_http-server_handlers_url_updateUrl.ResponseAlias"
                 },
@@ -327,6 +2392,122 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "internal_http-server_handlers_workspace_listMembers.Member": {
+            "description": "Name of API keys and their role: viewer, editor or admin",
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "ci"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "editor"
+                }
+            }
+        },
+        "internal_http-server_handlers_workspace_listMembers.Response": {
+            "description": "Members of the workspace",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_workspace_listMembers.Member"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "workspace": {
+                    "type": "string",
+                    "example": "docs"
+                }
+            }
+        },
+        "internal_http-server_handlers_workspace_removeMember.Response": {
+            "description": "Success response for member removal",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_workspace_setMember.Request": {
+            "description": "viewer reads links, editor also creates and edits its own, admin also manages all links and the members of the workspace.",
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "viewer",
+                        "editor",
+                        "admin"
+                    ],
+                    "example": "editor"
+                }
+            }
+        },
+        "internal_http-server_handlers_workspace_setMember.Response": {
+            "description": "The member with its new role",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_popvaleks_url-shortener_internal_lib_api_response.FieldError"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ci"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "editor"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "workspace": {
+                    "type": "string",
+                    "example": "docs"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "\"Bearer \" followed by the key or a JWT, or the key alone in X-API-Key",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
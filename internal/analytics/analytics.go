@@ -0,0 +1,204 @@
+// Package analytics records link clicks off the redirect path.
+//
+// Clicks go through a bounded buffer to a few workers that save them
+// in batches, once a batch is full or the flush interval has passed.
+// When the buffer is full, the policy decides whether a click is
+// dropped right away or the redirect waits a little for free space.
+package analytics
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+const (
+	// PolicyDrop drops a click when the buffer is full.
+	PolicyDrop = "drop"
+	// PolicyBlock waits up to BlockTimeout for free space, then drops the click.
+	PolicyBlock = "block"
+)
+
+type ClickSaver interface {
+	SaveClicks(clicks []storage.Click) error
+}
+
+type Options struct {
+	BufferSize    int
+	Workers       int
+	BatchSize     int
+	FlushInterval time.Duration
+	Policy        string
+	BlockTimeout  time.Duration
+}
+
+// Counters are cumulative since the pipeline was started.
+type Counters struct {
+	// Recorded clicks were accepted into the buffer.
+	Recorded int64 `json:"recorded"`
+	// Dropped clicks did not fit into the buffer or came after Close.
+	Dropped int64 `json:"dropped"`
+	// Flushed clicks were saved to the storage.
+	Flushed int64 `json:"flushed"`
+	// Failed clicks were lost because the storage returned an error.
+	Failed int64 `json:"failed"`
+	// Batches is the number of successful SaveClicks calls.
+	Batches int64 `json:"batches"`
+}
+
+type Pipeline struct {
+	log   *slog.Logger
+	saver ClickSaver
+	opts  Options
+
+	// mu guards events against a send after Close.
+	mu     sync.RWMutex
+	closed bool
+	events chan storage.Click
+	wg     sync.WaitGroup
+
+	recorded atomic.Int64
+	dropped  atomic.Int64
+	flushed  atomic.Int64
+	failed   atomic.Int64
+	batches  atomic.Int64
+}
+
+// NewPipeline starts the workers. Close must be called to flush
+// buffered clicks and stop them.
+func NewPipeline(log *slog.Logger, saver ClickSaver, opts Options) *Pipeline {
+	opts.BufferSize = max(opts.BufferSize, 1)
+	opts.Workers = max(opts.Workers, 1)
+	opts.BatchSize = max(opts.BatchSize, 1)
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+
+	p := &Pipeline{
+		log:    log.With(slog.String("component", "analytics")),
+		saver:  saver,
+		opts:   opts,
+		events: make(chan storage.Click, opts.BufferSize),
+	}
+
+	p.wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+// RecordClick queues click according to the buffer policy.
+func (p *Pipeline) RecordClick(click storage.Click) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.dropped.Add(1)
+		return
+	}
+
+	select {
+	case p.events <- click:
+		p.recorded.Add(1)
+		return
+	default:
+	}
+
+	if p.opts.Policy == PolicyBlock && p.opts.BlockTimeout > 0 {
+		timer := time.NewTimer(p.opts.BlockTimeout)
+		defer timer.Stop()
+
+		select {
+		case p.events <- click:
+			p.recorded.Add(1)
+			return
+		case <-timer.C:
+		}
+	}
+
+	p.dropped.Add(1)
+}
+
+// Close stops accepting clicks and returns once the buffered ones are flushed.
+func (p *Pipeline) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.events)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+
+	c := p.Counters()
+	p.log.Info("analytics pipeline stopped",
+		slog.Int64("recorded", c.Recorded),
+		slog.Int64("dropped", c.Dropped),
+		slog.Int64("flushed", c.Flushed),
+		slog.Int64("failed", c.Failed),
+	)
+}
+
+func (p *Pipeline) Counters() Counters {
+	return Counters{
+		Recorded: p.recorded.Load(),
+		Dropped:  p.dropped.Load(),
+		Flushed:  p.flushed.Load(),
+		Failed:   p.failed.Load(),
+		Batches:  p.batches.Load(),
+	}
+}
+
+func (p *Pipeline) work() {
+	defer p.wg.Done()
+
+	batch := make([]storage.Click, 0, p.opts.BatchSize)
+
+	ticker := time.NewTicker(p.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case click, ok := <-p.events:
+			if !ok {
+				p.flush(batch)
+				return
+			}
+
+			batch = append(batch, click)
+			if len(batch) >= p.opts.BatchSize {
+				p.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			p.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (p *Pipeline) flush(batch []storage.Click) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := p.saver.SaveClicks(batch); err != nil {
+		p.failed.Add(int64(len(batch)))
+		p.log.Error("failed to save clicks",
+			slog.Int("count", len(batch)),
+			slog.String("error", err.Error()),
+		)
+
+		return
+	}
+
+	p.flushed.Add(int64(len(batch)))
+	p.batches.Add(1)
+}
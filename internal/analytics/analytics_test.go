@@ -0,0 +1,169 @@
+package analytics
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSaver records batch sizes. While gate is set, SaveClicks waits on it.
+type fakeSaver struct {
+	mu      sync.Mutex
+	batches []int
+	gate    chan struct{}
+	err     error
+}
+
+func (s *fakeSaver) SaveClicks(clicks []storage.Click) error {
+	if s.gate != nil {
+		<-s.gate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	s.batches = append(s.batches, len(clicks))
+
+	return nil
+}
+
+func (s *fakeSaver) saved() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]int(nil), s.batches...)
+}
+
+func click() storage.Click {
+	return storage.Click{Alias: "example", At: time.Now()}
+}
+
+func TestFlushBySize(t *testing.T) {
+	saver := &fakeSaver{}
+	p := NewPipeline(slog.Default(), saver, Options{
+		BufferSize:    100,
+		Workers:       1,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+	})
+
+	for i := 0; i < 25; i++ {
+		p.RecordClick(click())
+	}
+
+	require.Eventually(t, func() bool { return len(saver.saved()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []int{10, 10}, saver.saved())
+
+	p.Close()
+
+	assert.Equal(t, []int{10, 10, 5}, saver.saved(), "close flushes the partial batch")
+	assert.Equal(t, Counters{Recorded: 25, Flushed: 25, Batches: 3}, p.Counters())
+}
+
+func TestFlushByInterval(t *testing.T) {
+	saver := &fakeSaver{}
+	p := NewPipeline(slog.Default(), saver, Options{
+		BufferSize:    100,
+		Workers:       2,
+		BatchSize:     100,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer p.Close()
+
+	p.RecordClick(click())
+	p.RecordClick(click())
+
+	require.Eventually(t, func() bool { return p.Counters().Flushed == 2 }, time.Second, time.Millisecond)
+}
+
+func TestDropPolicy(t *testing.T) {
+	saver := &fakeSaver{gate: make(chan struct{})}
+	p := NewPipeline(slog.Default(), saver, Options{
+		BufferSize:    2,
+		Workers:       1,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		Policy:        PolicyDrop,
+	})
+
+	// the worker takes the first click and blocks in SaveClicks,
+	// the next two fill the buffer and the rest are dropped
+	p.RecordClick(click())
+	require.Eventually(t, func() bool { return len(p.events) == 0 }, time.Second, time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		p.RecordClick(click())
+	}
+
+	assert.Equal(t, Counters{Recorded: 3, Dropped: 3}, p.Counters())
+
+	close(saver.gate)
+	p.Close()
+
+	assert.Equal(t, Counters{Recorded: 3, Dropped: 3, Flushed: 3, Batches: 3}, p.Counters())
+}
+
+func TestBlockPolicy(t *testing.T) {
+	saver := &fakeSaver{gate: make(chan struct{})}
+	p := NewPipeline(slog.Default(), saver, Options{
+		BufferSize:    1,
+		Workers:       1,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		Policy:        PolicyBlock,
+		BlockTimeout:  20 * time.Millisecond,
+	})
+
+	p.RecordClick(click())
+	require.Eventually(t, func() bool { return len(p.events) == 0 }, time.Second, time.Millisecond)
+	p.RecordClick(click())
+
+	start := time.Now()
+	p.RecordClick(click())
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond, "waits for free space before dropping")
+	assert.EqualValues(t, 1, p.Counters().Dropped)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		saver.gate <- struct{}{}
+	}()
+
+	p.RecordClick(click())
+	assert.EqualValues(t, 1, p.Counters().Dropped, "space freed before the timeout")
+
+	close(saver.gate)
+	p.Close()
+
+	assert.EqualValues(t, 3, p.Counters().Flushed)
+}
+
+func TestSaveError(t *testing.T) {
+	saver := &fakeSaver{err: errors.New("database is locked")}
+	p := NewPipeline(slog.Default(), saver, Options{BufferSize: 10, BatchSize: 10})
+
+	p.RecordClick(click())
+	p.RecordClick(click())
+	p.Close()
+
+	assert.Equal(t, Counters{Recorded: 2, Failed: 2}, p.Counters())
+}
+
+func TestRecordAfterClose(t *testing.T) {
+	p := NewPipeline(slog.Default(), &fakeSaver{}, Options{BufferSize: 10})
+	p.Close()
+	p.Close()
+
+	p.RecordClick(click())
+
+	assert.Equal(t, Counters{Dropped: 1}, p.Counters())
+}
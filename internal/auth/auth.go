@@ -0,0 +1,305 @@
+// Package auth issues API keys for the management API and checks them.
+// Keys look like "us_<prefix>_<secret>": the prefix finds the stored key,
+// only a SHA-256 hash of the whole key is stored.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+// Scopes of keys. A scope includes the ones before it: write can read,
+// admin can do everything.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists the scopes from the narrowest to the widest.
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// Roles of the members of a workspace. Like scopes, a role includes the
+// ones before it: viewers read, editors manage their links, admins
+// manage all links and the members.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Roles lists the roles from the narrowest to the widest.
+var Roles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+const (
+	keyPrefix = "us_"
+	// a hex prefix of 8 characters leaves room for billions of keys,
+	// secrets of 32 random bytes cannot be guessed, so a fast hash is enough
+	prefixBytes = 4
+	secretBytes = 32
+)
+
+var (
+	// ErrInvalidKey means the key is malformed, unknown, wrong or revoked.
+	// Callers are not told which, so that keys cannot be probed.
+	ErrInvalidKey   = errors.New("invalid api key")
+	ErrUnknownScope = errors.New("unknown scope")
+	ErrNoScopes     = errors.New("at least one scope is required")
+)
+
+// KeyStore stores keys, see storage.Repository.
+type KeyStore interface {
+	SaveAPIKey(key storage.APIKey) (int64, error)
+	GetAPIKey(prefix string) (storage.APIKey, error)
+	ListAPIKeys() ([]storage.APIKey, error)
+	RevokeAPIKey(id int64, now time.Time) error
+}
+
+// Keys creates, checks and revokes keys.
+type Keys struct {
+	store KeyStore
+	now   func() time.Time
+}
+
+func New(store KeyStore) *Keys {
+	return &Keys{store: store, now: time.Now}
+}
+
+// Create issues a key named name with scopes. The returned key is the
+// only copy of it, it cannot be recovered later.
+func (k *Keys) Create(name string, scopes []string) (string, storage.APIKey, error) {
+	const op = "auth.Create"
+
+	scopes, err := NormalizeScopes(scopes)
+	if err != nil {
+		return "", storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// a taken prefix is as unlikely as a lottery win, but cheap to retry
+	for attempt := 0; attempt < 3; attempt++ {
+		prefix, err := randomHex(prefixBytes)
+		if err != nil {
+			return "", storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		secret, err := randomHex(secretBytes)
+		if err != nil {
+			return "", storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		plain := keyPrefix + prefix + "_" + secret
+		key := storage.APIKey{
+			Name:      name,
+			Prefix:    prefix,
+			Hash:      hash(plain),
+			Scopes:    scopes,
+			CreatedAt: k.now().UTC(),
+		}
+
+		key.ID, err = k.store.SaveAPIKey(key)
+		if errors.Is(err, storage.ErrAPIKeyExists) {
+			continue
+		}
+		if err != nil {
+			return "", storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return plain, key, nil
+	}
+
+	return "", storage.APIKey{}, fmt.Errorf("%s: %w", op, storage.ErrAPIKeyExists)
+}
+
+// Authenticate returns the stored key of plain or ErrInvalidKey.
+func (k *Keys) Authenticate(plain string) (storage.APIKey, error) {
+	const op = "auth.Authenticate"
+
+	rest, ok := strings.CutPrefix(plain, keyPrefix)
+	if !ok {
+		return storage.APIKey{}, ErrInvalidKey
+	}
+
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" {
+		return storage.APIKey{}, ErrInvalidKey
+	}
+
+	key, err := k.store.GetAPIKey(prefix)
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		return storage.APIKey{}, ErrInvalidKey
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash(plain)), []byte(key.Hash)) != 1 || key.RevokedAt != nil {
+		return storage.APIKey{}, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// List returns all keys, revoked ones included.
+func (k *Keys) List() ([]storage.APIKey, error) {
+	const op = "auth.List"
+
+	keys, err := k.store.ListAPIKeys()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// Revoke revokes the key with id, it returns storage.ErrAPIKeyNotFound
+// for an unknown id.
+func (k *Keys) Revoke(id int64) error {
+	const op = "auth.Revoke"
+
+	if err := k.store.RevokeAPIKey(id, k.now().UTC()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Principal is who a request acts for. Links belong to the name of the
+// key or token that created them, so keys with the same name share their
+// links. Workspace membership is given to the name as well.
+type Principal struct {
+	Name   string
+	Scopes []string
+	Admin  bool
+	// Role is the role the scopes give in the default workspace, empty
+	// for a principal without a key.
+	Role string
+	// Workspace and WorkspaceRole are the membership a token carries,
+	// it needs no stored member.
+	Workspace     string
+	WorkspaceRole string
+}
+
+// Unrestricted acts for everybody, it stands for the caller when
+// authentication is off. Links it creates have no owner.
+var Unrestricted = Principal{Scopes: []string{ScopeAdmin}, Admin: true, Role: RoleAdmin}
+
+// NewPrincipal returns the principal of name with scopes. Unknown scopes
+// are ignored.
+func NewPrincipal(name string, scopes []string) Principal {
+	p := Principal{Name: name, Scopes: scopes, Admin: allows(scopes, ScopeAdmin)}
+
+	// scopes and roles go in the same order
+	for i := len(Scopes) - 1; i >= 0; i-- {
+		if allows(scopes, Scopes[i]) {
+			p.Role = Roles[i]
+			break
+		}
+	}
+
+	return p
+}
+
+// PrincipalOf returns the principal of key.
+func PrincipalOf(key storage.APIKey) Principal {
+	return NewPrincipal(key.Name, key.Scopes)
+}
+
+// Allows reports whether p has scope or a wider one.
+func (p Principal) Allows(scope string) bool {
+	return allows(p.Scopes, scope)
+}
+
+type principalCtx struct{}
+
+// NewContext returns a copy of ctx that carries p.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalCtx{}, p)
+}
+
+// FromContext returns the principal ctx carries, see NewContext.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalCtx{}).(Principal)
+
+	return p, ok
+}
+
+// CanManage reports whether p may change or delete a link of owner.
+// Links without an owner are managed by admins only.
+func (p Principal) CanManage(owner string) bool {
+	return p.Admin || owner != "" && owner == p.Name
+}
+
+// Allows reports whether key has scope or a wider one.
+func Allows(key storage.APIKey, scope string) bool {
+	return allows(key.Scopes, scope)
+}
+
+func allows(scopes []string, scope string) bool {
+	need := slices.Index(Scopes, scope)
+	if need < 0 {
+		return false
+	}
+
+	for _, s := range scopes {
+		if slices.Index(Scopes, s) >= need {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RoleAllows reports whether role is need or a wider one.
+func RoleAllows(role, need string) bool {
+	have, want := slices.Index(Roles, role), slices.Index(Roles, need)
+
+	return have >= 0 && want >= 0 && have >= want
+}
+
+// NormalizeScopes lower-cases scopes and drops repeated ones. It returns
+// ErrUnknownScope or ErrNoScopes if scopes are not valid.
+func NormalizeScopes(scopes []string) ([]string, error) {
+	normalized := make([]string, 0, len(scopes))
+
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownScope, scope)
+		}
+
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+
+	if len(normalized) == 0 {
+		return nil, ErrNoScopes
+	}
+
+	return normalized, nil
+}
+
+func hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
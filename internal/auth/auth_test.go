@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	repo := memory.New()
+	keys := New(repo)
+
+	plain, key, err := keys.Create("ci", []string{"Write", "read", "write"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, "us_"+key.Prefix+"_"))
+	assert.Equal(t, []string{"write", "read"}, key.Scopes)
+	assert.NotContains(t, key.Hash, plain)
+
+	stored, err := repo.GetAPIKey(key.Prefix)
+	require.NoError(t, err)
+	assert.NotEqual(t, plain, stored.Hash, "only the hash is stored")
+
+	got, err := keys.Authenticate(plain)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, got.ID)
+
+	for _, bad := range []string{
+		"",
+		"Bearer " + plain,
+		plain + "0",
+		"us_" + key.Prefix,
+		"us_00000000_" + strings.Repeat("0", 64),
+		strings.Replace(plain, key.Prefix, strings.Repeat("f", 8), 1),
+	} {
+		_, err := keys.Authenticate(bad)
+		assert.ErrorIs(t, err, ErrInvalidKey, bad)
+	}
+
+	require.NoError(t, keys.Revoke(key.ID))
+	_, err = keys.Authenticate(plain)
+	assert.ErrorIs(t, err, ErrInvalidKey, "revoked keys do not authenticate")
+
+	assert.ErrorIs(t, keys.Revoke(key.ID+100), storage.ErrAPIKeyNotFound)
+}
+
+func TestCreateRejectsScopes(t *testing.T) {
+	keys := New(memory.New())
+
+	_, _, err := keys.Create("ci", nil)
+	assert.ErrorIs(t, err, ErrNoScopes)
+
+	_, _, err = keys.Create("ci", []string{"read", "root"})
+	assert.ErrorIs(t, err, ErrUnknownScope)
+
+	list, err := keys.List()
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		scopes  []string
+		allowed []string
+	}{
+		{scopes: []string{ScopeRead}, allowed: []string{ScopeRead}},
+		{scopes: []string{ScopeWrite}, allowed: []string{ScopeRead, ScopeWrite}},
+		{scopes: []string{ScopeAdmin}, allowed: []string{ScopeRead, ScopeWrite, ScopeAdmin}},
+		{scopes: []string{ScopeRead, ScopeWrite}, allowed: []string{ScopeRead, ScopeWrite}},
+		{scopes: nil, allowed: nil},
+	}
+
+	for _, tt := range tests {
+		key := storage.APIKey{Scopes: tt.scopes}
+		for _, scope := range Scopes {
+			assert.Equal(t, slices.Contains(tt.allowed, scope), Allows(key, scope), "%v allows %s", tt.scopes, scope)
+		}
+		assert.False(t, Allows(key, "root"))
+	}
+}
+
+func TestCanManage(t *testing.T) {
+	ops := PrincipalOf(storage.APIKey{Name: "ops", Scopes: []string{ScopeWrite}})
+	admin := PrincipalOf(storage.APIKey{Name: "root", Scopes: []string{ScopeAdmin}})
+
+	assert.True(t, ops.CanManage("ops"))
+	assert.False(t, ops.CanManage("dev"))
+	assert.False(t, ops.CanManage(""), "links without an owner are for admins")
+
+	assert.True(t, admin.CanManage("dev"))
+	assert.True(t, admin.CanManage(""))
+
+	assert.True(t, Unrestricted.CanManage("dev"))
+	assert.False(t, Principal{}.CanManage(""))
+}
+
+func TestPrincipalOf(t *testing.T) {
+	assert.Equal(t, Principal{Name: "ci", Scopes: []string{ScopeRead}, Role: RoleViewer},
+		PrincipalOf(storage.APIKey{Name: "ci", Scopes: []string{ScopeRead}}))
+	assert.Equal(t, Principal{Name: "ops", Scopes: []string{ScopeRead, ScopeWrite}, Role: RoleEditor},
+		PrincipalOf(storage.APIKey{Name: "ops", Scopes: []string{ScopeRead, ScopeWrite}}))
+	assert.Equal(t, Principal{Name: "root", Scopes: []string{ScopeAdmin}, Admin: true, Role: RoleAdmin},
+		PrincipalOf(storage.APIKey{Name: "root", Scopes: []string{ScopeAdmin}}))
+	assert.Equal(t, Principal{Name: "none"}, PrincipalOf(storage.APIKey{Name: "none"}))
+
+	// незнакомые права токенов не мешают
+	p := NewPrincipal("gw", []string{"openid", ScopeWrite})
+	assert.Equal(t, RoleEditor, p.Role)
+	assert.True(t, p.Allows(ScopeRead))
+	assert.False(t, p.Allows(ScopeAdmin))
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	p := NewPrincipal("ci", []string{ScopeRead})
+	got, ok := FromContext(NewContext(context.Background(), p))
+	assert.True(t, ok)
+	assert.Equal(t, p, got)
+}
+
+func TestRoleAllows(t *testing.T) {
+	assert.True(t, RoleAllows(RoleEditor, RoleViewer))
+	assert.True(t, RoleAllows(RoleEditor, RoleEditor))
+	assert.False(t, RoleAllows(RoleEditor, RoleAdmin))
+	assert.True(t, RoleAllows(RoleAdmin, RoleEditor))
+	assert.False(t, RoleAllows("", RoleViewer))
+	assert.False(t, RoleAllows(RoleAdmin, "owner"))
+}
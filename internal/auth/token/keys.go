@@ -0,0 +1,351 @@
+package token
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound means a source has no key for the token.
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeySource finds the key that checks the signature of a token.
+type KeySource interface {
+	// Key returns the key for the kid and alg of a token, kid may be
+	// empty. It returns ErrKeyNotFound if there is none.
+	Key(kid, alg string) (any, error)
+}
+
+// Sources asks its sources in order, the first key found is used.
+type Sources []KeySource
+
+func (s Sources) Key(kid, alg string) (any, error) {
+	for _, source := range s {
+		key, err := source.Key(kid, alg)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+
+		return key, err
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+// Secret checks HS256 tokens whatever their kid.
+type Secret []byte
+
+func (s Secret) Key(_, alg string) (any, error) {
+	if alg != "HS256" || len(s) == 0 {
+		return nil, ErrKeyNotFound
+	}
+
+	return []byte(s), nil
+}
+
+// StaticKey checks RS256 or ES256 tokens with one public key whatever
+// their kid.
+type StaticKey struct {
+	key any
+}
+
+// LoadPEM reads an RSA or P-256 public key from a PEM file, a certificate
+// will do as well.
+func LoadPEM(path string) (*StaticKey, error) {
+	const op = "token.LoadPEM"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key, err := ParsePEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// ParsePEM is LoadPEM for the contents of the file.
+func ParsePEM(data []byte) (*StaticKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	var (
+		key any
+		err error
+	)
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if algOf(key) == "" {
+		return nil, fmt.Errorf("unsupported key %T", key)
+	}
+
+	return &StaticKey{key: key}, nil
+}
+
+func (k *StaticKey) Key(_, alg string) (any, error) {
+	if algOf(k.key) != alg {
+		return nil, ErrKeyNotFound
+	}
+
+	return k.key, nil
+}
+
+// JWKSFile checks tokens with the keys of a JSON Web Key Set file, they
+// are picked by kid. The file is read again when it changes and a token
+// names a key it does not have, so keys rotate without a restart.
+type JWKSFile struct {
+	path string
+
+	mu      sync.RWMutex
+	keys    []jwk
+	modTime time.Time
+}
+
+type jwk struct {
+	kid string
+	key any
+}
+
+// LoadJWKS reads the key set at path. Keys of other types, curves or uses
+// than the ones of Algorithms are skipped.
+func LoadJWKS(path string) (*JWKSFile, error) {
+	const op = "token.LoadJWKS"
+
+	f := &JWKSFile{path: path}
+	if _, err := f.reload(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return f, nil
+}
+
+func (f *JWKSFile) Key(kid, alg string) (any, error) {
+	if key, ok := f.find(kid, alg); ok {
+		return key, nil
+	}
+
+	changed, err := f.reload()
+	if err != nil {
+		return nil, fmt.Errorf("token.JWKSFile.Key: %w", err)
+	}
+
+	if changed {
+		if key, ok := f.find(kid, alg); ok {
+			return key, nil
+		}
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+// find returns the key with kid. A token without a kid gets the only
+// key for its alg.
+func (f *JWKSFile) find(kid, alg string) (any, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var (
+		match any
+		found int
+	)
+
+	for _, k := range f.keys {
+		if algOf(k.key) != alg {
+			continue
+		}
+
+		if kid != "" {
+			if k.kid == kid {
+				return k.key, true
+			}
+
+			continue
+		}
+
+		match = k.key
+		found++
+	}
+
+	return match, found == 1
+}
+
+// reload reads the file if it changed since the last read.
+func (f *JWKSFile) reload() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if info.ModTime().Equal(f.modTime) && f.keys != nil {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, err
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return false, err
+	}
+
+	f.keys, f.modTime = keys, info.ModTime()
+
+	return true, nil
+}
+
+func parseJWKS(data []byte) ([]jwk, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse key set: %w", err)
+	}
+
+	keys := make([]jwk, 0, len(set.Keys))
+
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var (
+			key any
+			err error
+		)
+
+		switch {
+		case k.Kty == "RSA":
+			key, err = rsaKey(k.N, k.E)
+		case k.Kty == "EC" && k.Crv == "P-256":
+			key, err = ecKey(k.X, k.Y)
+		case k.Kty == "oct":
+			key, err = base64.RawURLEncoding.DecodeString(k.K)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+
+		// a key bound to another algorithm is not used for this one
+		if k.Alg != "" && k.Alg != algOf(key) {
+			continue
+		}
+
+		keys = append(keys, jwk{kid: k.Kid, key: key})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no usable keys in the key set")
+	}
+
+	return keys, nil
+}
+
+func rsaKey(n, e string) (*rsa.PublicKey, error) {
+	nb, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %w", err)
+	}
+
+	eb, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %w", err)
+	}
+
+	exp := new(big.Int).SetBytes(eb)
+	if len(nb) == 0 || !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("bad RSA key")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(exp.Int64())}, nil
+}
+
+func ecKey(x, y string) (*ecdsa.PublicKey, error) {
+	xb, err := base64.RawURLEncoding.DecodeString(x)
+	if err != nil {
+		return nil, fmt.Errorf("x: %w", err)
+	}
+
+	yb, err := base64.RawURLEncoding.DecodeString(y)
+	if err != nil {
+		return nil, fmt.Errorf("y: %w", err)
+	}
+
+	if len(xb) != 32 || len(yb) != 32 {
+		return nil, errors.New("bad P-256 key")
+	}
+
+	// the point must be on the curve
+	point := append([]byte{4}, append(xb, yb...)...)
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("bad P-256 key: %w", err)
+	}
+
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(xb), Y: new(big.Int).SetBytes(yb)}, nil
+}
+
+// algOf returns the algorithm key checks, empty for unsupported keys.
+func algOf(key any) string {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return "RS256"
+	case *ecdsa.PublicKey:
+		if key.Curve == elliptic.P256() {
+			return "ES256"
+		}
+	case []byte:
+		return "HS256"
+	}
+
+	return ""
+}
@@ -0,0 +1,133 @@
+// Package token checks JWTs issued outside the service, e.g. by a gateway,
+// and makes principals of their claims. Tokens are signed with RS256, ES256
+// or HS256, the keys come from a KeySource.
+package token
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/popvaleks/url-shortener/internal/auth"
+)
+
+// Algorithms lists the accepted signing algorithms, "none" is never one.
+var Algorithms = []string{"RS256", "ES256", "HS256"}
+
+// ErrInvalidToken means the token is malformed, signed by an unknown key,
+// expired or issued for another service. The reason is wrapped for logs,
+// callers are not told it.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims names the claims a principal is made of.
+type Claims struct {
+	// Name owns the links, like the name of an API key. "sub" by default.
+	Name string
+	// Scopes holds read, write or admin as a space separated string or
+	// an array, other values are ignored. "scope" by default.
+	Scopes string
+	// Workspace and Role give the token a role in a workspace without a
+	// stored member. "workspace" and "role" by default.
+	Workspace string
+	Role      string
+}
+
+// Options restrict the accepted tokens. Issuer and Audience are not
+// checked when empty. Leeway allows for clock skew.
+type Options struct {
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+	Claims   Claims
+}
+
+// Verifier checks tokens.
+type Verifier struct {
+	keys   KeySource
+	claims Claims
+	parser *jwt.Parser
+}
+
+// NewVerifier returns a verifier of the tokens signed with the keys of keys.
+func NewVerifier(keys KeySource, opts Options) *Verifier {
+	claims := opts.Claims
+	if claims.Name == "" {
+		claims.Name = "sub"
+	}
+	if claims.Scopes == "" {
+		claims.Scopes = "scope"
+	}
+	if claims.Workspace == "" {
+		claims.Workspace = "workspace"
+	}
+	if claims.Role == "" {
+		claims.Role = "role"
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(Algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	return &Verifier{keys: keys, claims: claims, parser: jwt.NewParser(parserOpts...)}
+}
+
+// Verify returns the principal of raw or an error wrapping ErrInvalidToken.
+func (v *Verifier) Verify(raw string) (auth.Principal, error) {
+	claims := jwt.MapClaims{}
+
+	if _, err := v.parser.ParseWithClaims(raw, claims, v.key); err != nil {
+		return auth.Principal{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	name, _ := claims[v.claims.Name].(string)
+	if name == "" {
+		return auth.Principal{}, fmt.Errorf("%w: no %s claim", ErrInvalidToken, v.claims.Name)
+	}
+
+	p := auth.NewPrincipal(name, scopesOf(claims[v.claims.Scopes]))
+
+	// a role the service does not know gives nothing
+	ws, _ := claims[v.claims.Workspace].(string)
+	role, _ := claims[v.claims.Role].(string)
+	if ws != "" && slices.Contains(auth.Roles, role) {
+		p.Workspace, p.WorkspaceRole = ws, role
+	}
+
+	return p, nil
+}
+
+func (v *Verifier) key(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	return v.keys.Key(kid, t.Method.Alg())
+}
+
+// scopesOf reads "read write" as well as ["read", "write"].
+func scopesOf(claim any) []string {
+	switch claim := claim.(type) {
+	case string:
+		return strings.Fields(claim)
+	case []any:
+		scopes := make([]string, 0, len(claim))
+		for _, s := range claim {
+			if s, ok := s.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+
+		return scopes
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,302 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	tok := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+
+	raw, err := tok.SignedString(key)
+	require.NoError(t, err)
+
+	return raw
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "alg": "RS256", "use": "sig",
+		"n": b64(key.N.Bytes()), "e": b64([]byte{1, 0, 1}),
+	}
+}
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func claims(extra jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"sub":   "gw-user",
+		"iss":   "https://gateway.example",
+		"aud":   "url-shortener",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "openid read write",
+	}
+	for k, v := range extra {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+
+	return c
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, rsaJWK("rsa-1", &rsaKey.PublicKey))
+	jwks, err := LoadJWKS(jwksPath)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	ecPEM, err := ParsePEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+
+	secret := []byte("a shared secret of the gateway!!")
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)})
+
+	verifier := NewVerifier(Sources{jwks, ecPEM, Secret(secret)}, Options{
+		Issuer:   "https://gateway.example",
+		Audience: "url-shortener",
+		Leeway:   time.Minute,
+	})
+
+	editor := auth.NewPrincipal("gw-user", []string{"openid", "read", "write"})
+
+	tests := []struct {
+		name     string
+		raw      string
+		expected auth.Principal
+		invalid  bool
+	}{
+		{
+			name:     "rs256 from the key set",
+			raw:      sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(nil)),
+			expected: editor,
+		},
+		{
+			name:     "es256 from pem",
+			raw:      sign(t, jwt.SigningMethodES256, ecKey, "", claims(nil)),
+			expected: editor,
+		},
+		{
+			name:     "hs256 with the secret",
+			raw:      sign(t, jwt.SigningMethodHS256, secret, "", claims(nil)),
+			expected: editor,
+		},
+		{
+			name: "scopes as an array and a workspace role",
+			raw: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(jwt.MapClaims{
+				"scope": []string{"admin"}, "workspace": "docs", "role": "editor",
+			})),
+			expected: auth.Principal{
+				Name: "gw-user", Scopes: []string{"admin"}, Admin: true, Role: auth.RoleAdmin,
+				Workspace: "docs", WorkspaceRole: auth.RoleEditor,
+			},
+		},
+		{
+			name: "unknown role",
+			raw: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(jwt.MapClaims{
+				"workspace": "docs", "role": "owner",
+			})),
+			expected: editor,
+		},
+		{
+			// в пределах допуска на расхождение часов
+			name:     "expired within leeway",
+			raw:      sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(jwt.MapClaims{"exp": time.Now().Add(-30 * time.Second).Unix()})),
+			expected: editor,
+		},
+		{
+			name:    "expired",
+			raw:     sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+			invalid: true,
+		},
+		{
+			name:    "no expiration",
+			raw:     sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(jwt.MapClaims{"exp": nil})),
+			invalid: true,
+		},
+		{
+			name:    "another issuer",
+			raw:     sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(jwt.MapClaims{"iss": "https://evil.example"})),
+			invalid: true,
+		},
+		{
+			name:    "another audience",
+			raw:     sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(jwt.MapClaims{"aud": "billing"})),
+			invalid: true,
+		},
+		{
+			name:    "no subject",
+			raw:     sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", claims(jwt.MapClaims{"sub": nil})),
+			invalid: true,
+		},
+		{
+			name:    "unknown key",
+			raw:     sign(t, jwt.SigningMethodRS256, otherRSA, "rsa-1", claims(nil)),
+			invalid: true,
+		},
+		{
+			name:    "unknown kid",
+			raw:     sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-9", claims(nil)),
+			invalid: true,
+		},
+		{
+			name:    "wrong secret",
+			raw:     sign(t, jwt.SigningMethodHS256, []byte("guess"), "", claims(nil)),
+			invalid: true,
+		},
+		{
+			// публичный ключ в роли HMAC-секрета не проходит
+			name:    "public key as hmac secret",
+			raw:     sign(t, jwt.SigningMethodHS256, rsaPEM, "rsa-1", claims(nil)),
+			invalid: true,
+		},
+		{
+			name:    "none",
+			raw:     sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims(nil)),
+			invalid: true,
+		},
+		{
+			name:    "other algorithm",
+			raw:     sign(t, jwt.SigningMethodRS512, rsaKey, "rsa-1", claims(nil)),
+			invalid: true,
+		},
+		{
+			name:    "garbage",
+			raw:     "a.b.c",
+			invalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := verifier.Verify(tt.raw)
+			if tt.invalid {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, p)
+		})
+	}
+}
+
+func TestVerifyClaimNames(t *testing.T) {
+	verifier := NewVerifier(Secret("secret"), Options{Claims: Claims{
+		Name: "email", Scopes: "permissions", Workspace: "tenant", Role: "tenant_role",
+	}})
+
+	raw := sign(t, jwt.SigningMethodHS256, []byte("secret"), "", jwt.MapClaims{
+		"sub":         "42",
+		"email":       "dev@example.com",
+		"permissions": []any{"read", 7},
+		"tenant":      "docs",
+		"tenant_role": "viewer",
+		"exp":         time.Now().Add(time.Hour).Unix(),
+	})
+
+	p, err := verifier.Verify(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "dev@example.com", p.Name)
+	assert.Equal(t, auth.RoleViewer, p.Role)
+	assert.Equal(t, "docs", p.Workspace)
+	assert.Equal(t, auth.RoleViewer, p.WorkspaceRole)
+}
+
+func TestJWKSFileRotation(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, rsaJWK("k1", &first.PublicKey))
+
+	jwks, err := LoadJWKS(path)
+	require.NoError(t, err)
+
+	_, err = jwks.Key("k2", "RS256")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// новый ключ подхватывается без перезапуска
+	writeJWKS(t, path, rsaJWK("k1", &first.PublicKey), rsaJWK("k2", &second.PublicKey))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+
+	key, err := jwks.Key("k2", "RS256")
+	require.NoError(t, err)
+	assert.Equal(t, &second.PublicKey, key)
+
+	// без kid ключ выбирается, только если он один
+	_, err = jwks.Key("", "RS256")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = jwks.Key("k1", "ES256")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestParseJWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys, err := parseJWKS([]byte(`{"keys":[` +
+		`{"kty":"EC","crv":"P-256","kid":"ec","x":"` + b64(ecKey.X.FillBytes(make([]byte, 32))) + `","y":"` + b64(ecKey.Y.FillBytes(make([]byte, 32))) + `"},` +
+		`{"kty":"oct","kid":"hs","k":"` + b64([]byte("secret")) + `"},` +
+		`{"kty":"oct","kid":"enc","use":"enc","k":"c2VjcmV0"},` +
+		`{"kty":"EC","crv":"P-384","kid":"p384","x":"AA","y":"AA"},` +
+		`{"kty":"OKP","crv":"Ed25519","kid":"ed","x":"AA"}]}`))
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "ec", keys[0].kid)
+	assert.Equal(t, &ecKey.PublicKey, keys[0].key)
+	assert.Equal(t, []byte("secret"), keys[1].key)
+
+	_, err = parseJWKS([]byte(`{"keys":[{"kty":"EC","crv":"P-256","x":"` + b64(make([]byte, 32)) + `","y":"` + b64(make([]byte, 32)) + `"}]}`))
+	assert.Error(t, err, "the point is not on the curve")
+
+	_, err = parseJWKS([]byte(`{"keys":[]}`))
+	assert.Error(t, err)
+
+	_, err = parseJWKS([]byte(`not json`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,247 @@
+// Package backup keeps timestamped snapshots of the SQLite database
+// in a directory and restores the database from them.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/storage/sqlite"
+)
+
+const (
+	filePrefix = "backup-"
+	fileSuffix = ".db"
+	// timeLayout sorts lexically and is safe in file names.
+	timeLayout = "20060102T150405.000Z"
+)
+
+// ErrHotJournal is returned by Restore when the database has a journal
+// left by an open or crashed connection, i.e. the server may be running.
+var ErrHotJournal = errors.New("database has a journal, stop the server before restoring")
+
+// Snapshotter writes a consistent copy of the database to path.
+type Snapshotter interface {
+	Backup(ctx context.Context, path string) error
+}
+
+// Info describes a backup file.
+type Info struct {
+	Name      string
+	Path      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// Manager creates backups in a directory and keeps a number of the newest ones.
+type Manager struct {
+	mu   sync.Mutex
+	src  Snapshotter
+	dir  string
+	keep int
+	now  func() time.Time
+}
+
+func New(src Snapshotter, dir string, keep int) *Manager {
+	return &Manager{src: src, dir: dir, keep: keep, now: time.Now}
+}
+
+// Create snapshots the database, checks the integrity of the copy
+// and removes the backups beyond the retention limit. Concurrent
+// calls are serialized.
+func (m *Manager) Create(ctx context.Context) (Info, error) {
+	const op = "backup.Create"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the name keeps milliseconds, so does Info of a listed backup
+	createdAt := m.now().UTC().Truncate(time.Millisecond)
+	name := filePrefix + createdAt.Format(timeLayout) + fileSuffix
+	path := filepath.Join(m.dir, name)
+	// an interrupted backup must not look like a finished one
+	tmp := path + ".tmp"
+
+	if err := m.src.Backup(ctx, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := sqlite.CheckIntegrity(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := m.prune(); err != nil {
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return Info{Name: name, Path: path, Size: stat.Size(), CreatedAt: createdAt}, nil
+}
+
+// List returns the backups in Dir, newest first. Other files are ignored.
+func (m *Manager) List() ([]Info, error) {
+	const op = "backup.List"
+
+	entries, err := os.ReadDir(m.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Info{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	backups := make([]Info, 0, len(entries))
+
+	for _, entry := range entries {
+		createdAt, ok := parseName(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+
+		stat, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		backups = append(backups, Info{
+			Name:      entry.Name(),
+			Path:      filepath.Join(m.dir, entry.Name()),
+			Size:      stat.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+func (m *Manager) prune() error {
+	backups, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	for i := m.keep; i < len(backups); i++ {
+		if err := os.Remove(backups[i].Path); err != nil {
+			return fmt.Errorf("remove old backup: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func parseName(name string) (time.Time, bool) {
+	stamp, ok := strings.CutPrefix(name, filePrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	stamp, ok = strings.CutSuffix(stamp, fileSuffix)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	createdAt, err := time.Parse(timeLayout, stamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return createdAt, true
+}
+
+// Restore replaces the database at dbPath with the backup at backupPath.
+// The backup is checked and copied next to the database before the
+// files are swapped, so a failure leaves the database untouched.
+// The replaced database is kept as dbPath + ".before-restore".
+// The server must be stopped.
+func Restore(backupPath, dbPath string) error {
+	const op = "backup.Restore"
+
+	for _, suffix := range []string{"-journal", "-wal"} {
+		if _, err := os.Stat(dbPath + suffix); err == nil {
+			return fmt.Errorf("%s: %w", op, ErrHotJournal)
+		}
+	}
+
+	if err := sqlite.CheckIntegrity(backupPath); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tmp := dbPath + ".restore"
+
+	if err := copyFile(backupPath, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the copy is checked again, it is what the server will open
+	if err := sqlite.CheckIntegrity(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		if err := os.Rename(dbPath, dbPath+".before-restore"); err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	// the data must be on disk before the rename makes it the database
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,163 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStorage(t *testing.T, path string, aliases ...string) *sqlite.Storage {
+	t.Helper()
+
+	s, err := sqlite.New(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	for _, alias := range aliases {
+		_, err := s.SaveUrl(storage.DefaultWorkspace, "https://example.com/"+alias, alias, storage.SaveOptions{})
+		require.NoError(t, err)
+	}
+
+	return s
+}
+
+func TestCreateKeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+	src := newStorage(t, filepath.Join(dir, "storage.db"), "a")
+
+	backups := filepath.Join(dir, "backups")
+	require.NoError(t, os.MkdirAll(backups, 0o755))
+	// чужие файлы в каталоге не трогаются
+	require.NoError(t, os.WriteFile(filepath.Join(backups, "notes.txt"), []byte("keep me"), 0o644))
+
+	m := New(src, backups, 2)
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	var created []Info
+	for range 3 {
+		info, err := m.Create(context.Background())
+		require.NoError(t, err)
+		created = append(created, info)
+		now = now.Add(time.Hour)
+	}
+
+	assert.Equal(t, "backup-20250301T100000.000Z.db", created[0].Name)
+	assert.Positive(t, created[0].Size)
+
+	list, err := m.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, created[2].Name, list[0].Name)
+	assert.Equal(t, created[1].Name, list[1].Name)
+	assert.True(t, created[2].CreatedAt.Equal(list[0].CreatedAt))
+
+	assert.NoFileExists(t, created[0].Path)
+	assert.FileExists(t, filepath.Join(backups, "notes.txt"))
+
+	snapshot := newStorage(t, list[0].Path)
+	url, err := snapshot.GetUrl(storage.DefaultWorkspace, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", url)
+}
+
+func TestCreateFromFileWhileServing(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "storage.db")
+	src := newStorage(t, dbPath, "a", "b")
+
+	// сервер продолжает писать, пока команда делает копию
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 50 {
+			// запись может получить SQLITE_BUSY, это не ошибка бэкапа
+			_, _ = src.SaveUrl(storage.DefaultWorkspace, "https://example.com", fmt.Sprintf("live%d", i), storage.SaveOptions{})
+		}
+	}()
+
+	info, err := New(sqlite.File(dbPath), filepath.Join(dir, "backups"), 3).Create(context.Background())
+	<-done
+	require.NoError(t, err)
+
+	snapshot := newStorage(t, info.Path)
+	_, err = snapshot.GetUrl(storage.DefaultWorkspace, "b")
+	assert.NoError(t, err)
+}
+
+func TestCreateMissingDatabase(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := New(sqlite.File(filepath.Join(dir, "missing.db")), dir, 3).Create(context.Background())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.NoFileExists(t, filepath.Join(dir, "missing.db"))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "a failed backup leaves no files")
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "storage.db")
+
+	src := newStorage(t, dbPath, "old")
+	info, err := New(src, filepath.Join(dir, "backups"), 3).Create(context.Background())
+	require.NoError(t, err)
+
+	_, err = src.SaveUrl(storage.DefaultWorkspace, "https://example.com/new", "new", storage.SaveOptions{})
+	require.NoError(t, err)
+	require.NoError(t, src.Close())
+
+	require.NoError(t, Restore(info.Path, dbPath))
+
+	restored := newStorage(t, dbPath)
+	_, err = restored.GetUrl(storage.DefaultWorkspace, "old")
+	assert.NoError(t, err)
+	_, err = restored.GetUrl(storage.DefaultWorkspace, "new")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	previous := newStorage(t, dbPath+".before-restore")
+	_, err = previous.GetUrl(storage.DefaultWorkspace, "new")
+	assert.NoError(t, err, "the replaced database is kept")
+}
+
+func TestRestoreRejectsCorruptedBackup(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "storage.db")
+	newStorage(t, dbPath, "a").Close()
+
+	corrupted := filepath.Join(dir, "backup-20250301T100000.000Z.db")
+	require.NoError(t, os.WriteFile(corrupted, []byte("definitely not sqlite, but long enough to have a header page"), 0o644))
+
+	err := Restore(corrupted, dbPath)
+	assert.ErrorIs(t, err, sqlite.ErrCorrupted)
+
+	assert.NoFileExists(t, dbPath+".restore")
+	assert.NoFileExists(t, dbPath+".before-restore")
+
+	s := newStorage(t, dbPath)
+	_, err = s.GetUrl(storage.DefaultWorkspace, "a")
+	assert.NoError(t, err, "the database is untouched")
+}
+
+func TestRestoreRejectsHotJournal(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "storage.db")
+	newStorage(t, dbPath, "a").Close()
+
+	info, err := New(sqlite.File(dbPath), filepath.Join(dir, "backups"), 3).Create(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(dbPath+"-journal", []byte{}, 0o644))
+
+	assert.ErrorIs(t, Restore(info.Path, dbPath), ErrHotJournal)
+}
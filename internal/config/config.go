@@ -4,6 +4,7 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"log"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -11,12 +12,151 @@ type HttpServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"5s" `
 	IdleTimeout time.Duration `yaml:"iddle_timeout" env-default:"60s"`
+	// LegacyErrors replies to errors with {"status":"Error","error":"..."}
+	// and status 200, as older versions did, instead of application/problem+json.
+	LegacyErrors bool `yaml:"legacy_errors" env:"LEGACY_ERRORS" env-default:"false"`
 }
 
+type Reaper struct {
+	Interval time.Duration `yaml:"interval" env:"REAPER_INTERVAL" env-default:"1m"`
+	// Mode is "purge" to delete expired links or "archive" to move them to url_archive.
+	Mode string `yaml:"mode" env:"REAPER_MODE" env-default:"purge"`
+}
+
+type Analytics struct {
+	BufferSize    int           `yaml:"buffer_size" env:"ANALYTICS_BUFFER_SIZE" env-default:"10000"`
+	Workers       int           `yaml:"workers" env:"ANALYTICS_WORKERS" env-default:"2"`
+	BatchSize     int           `yaml:"batch_size" env:"ANALYTICS_BATCH_SIZE" env-default:"500"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"ANALYTICS_FLUSH_INTERVAL" env-default:"1s"`
+	// Policy is "drop" to drop clicks when the buffer is full or "block"
+	// to make the redirect wait up to BlockTimeout for free space.
+	Policy       string        `yaml:"policy" env:"ANALYTICS_POLICY" env-default:"drop"`
+	BlockTimeout time.Duration `yaml:"block_timeout" env:"ANALYTICS_BLOCK_TIMEOUT" env-default:"50ms"`
+}
+
+// Backup configures snapshots of the sqlite database.
+type Backup struct {
+	Dir string `yaml:"dir" env:"BACKUP_DIR" env-default:"./storage/backups"`
+	// Keep is the number of the newest backups kept, older ones are removed.
+	Keep int `yaml:"keep" env:"BACKUP_KEEP" env-default:"7"`
+}
+
+// Alias configures the aliases generated for links saved without one.
+type Alias struct {
+	// Generator is "random", "sequence", "hashids" or "words".
+	Generator string `yaml:"generator" env:"ALIAS_GENERATOR" env-default:"random"`
+	// Alphabet is ignored by the words generator.
+	Alphabet string `yaml:"alphabet" env:"ALIAS_ALPHABET" env-default:"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"`
+	Length   int    `yaml:"length" env:"ALIAS_LENGTH" env-default:"8"`
+	// Salt shuffles the alphabet of the hashids generator.
+	Salt        string `yaml:"salt" env:"ALIAS_SALT"`
+	MaxAttempts int    `yaml:"max_attempts" env:"ALIAS_MAX_ATTEMPTS" env-default:"5"`
+	// GrowAfter is the number of collisions of one link after which
+	// generated aliases get one character longer.
+	GrowAfter int `yaml:"grow_after" env:"ALIAS_GROW_AFTER" env-default:"2"`
+	// Policy applies to the aliases chosen by users.
+	Policy AliasPolicy `yaml:"policy"`
+}
+
+// AliasPolicy restricts the aliases chosen by users. The first segments
+// of the routes are always reserved.
+type AliasPolicy struct {
+	MinLength int      `yaml:"min_length" env:"ALIAS_MIN_LENGTH" env-default:"3"`
+	MaxLength int      `yaml:"max_length" env:"ALIAS_MAX_LENGTH" env-default:"64"`
+	Charset   string   `yaml:"charset" env:"ALIAS_CHARSET" env-default:"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"`
+	Reserved  []string `yaml:"reserved" env:"ALIAS_RESERVED"`
+	// Profanity replaces the built-in list when set.
+	Profanity []string `yaml:"profanity" env:"ALIAS_PROFANITY"`
+	// RejectAmbiguous rejects aliases mixing lookalikes like "0" and "O".
+	RejectAmbiguous bool `yaml:"reject_ambiguous" env:"ALIAS_REJECT_AMBIGUOUS" env-default:"false"`
+}
+
+// Auth protects the management API with API keys.
+type Auth struct {
+	// Enabled requires a key on every route except redirects and the docs.
+	Enabled bool `yaml:"enabled" env:"AUTH_ENABLED" env-default:"true"`
+	JWT     JWT  `yaml:"jwt"`
+}
+
+// JWT accepts tokens issued elsewhere, e.g. by a gateway, in place of API
+// keys. At least one of JWKSFile, PublicKeyFile and Secret is required.
+type JWT struct {
+	Enabled bool `yaml:"enabled" env:"JWT_ENABLED" env-default:"false"`
+	// JWKSFile is a JSON Web Key Set, it is read again when it changes.
+	JWKSFile string `yaml:"jwks_file" env:"JWT_JWKS_FILE"`
+	// PublicKeyFile is a PEM RSA or P-256 public key or certificate.
+	PublicKeyFile string `yaml:"public_key_file" env:"JWT_PUBLIC_KEY_FILE"`
+	// Secret checks HS256 tokens.
+	Secret   string        `yaml:"secret" env:"JWT_SECRET"`
+	Issuer   string        `yaml:"issuer" env:"JWT_ISSUER"`
+	Audience string        `yaml:"audience" env:"JWT_AUDIENCE"`
+	Leeway   time.Duration `yaml:"leeway" env:"JWT_LEEWAY" env-default:"30s"`
+	Claims   JWTClaims     `yaml:"claims"`
+}
+
+// JWTClaims names the claims with the owner of the links, the scopes and
+// a role in a workspace.
+type JWTClaims struct {
+	Name      string `yaml:"name" env:"JWT_CLAIM_NAME" env-default:"sub"`
+	Scopes    string `yaml:"scopes" env:"JWT_CLAIM_SCOPES" env-default:"scope"`
+	Workspace string `yaml:"workspace" env:"JWT_CLAIM_WORKSPACE" env-default:"workspace"`
+	Role      string `yaml:"role" env:"JWT_CLAIM_ROLE" env-default:"role"`
+}
+
+// RateLimit limits the requests of every client to a route group: an API
+// key, the user of a token or the IP of an anonymous request. A limit of
+// zero requests is off.
+type RateLimit struct {
+	// Read covers listing, searching, exporting and stats.
+	Read Limit `yaml:"read" env-prefix:"RATE_LIMIT_READ_"`
+	// Write covers creating and changing links and the members.
+	Write    Limit `yaml:"write" env-prefix:"RATE_LIMIT_WRITE_"`
+	Redirect Limit `yaml:"redirect" env-prefix:"RATE_LIMIT_REDIRECT_"`
+	Admin    Limit `yaml:"admin" env-prefix:"RATE_LIMIT_ADMIN_"`
+}
+
+// Limit allows Requests requests per Per on average and up to Burst at
+// once, Burst is Requests when not set.
+type Limit struct {
+	Requests int           `yaml:"requests" env:"REQUESTS"`
+	Per      time.Duration `yaml:"per" env:"PER" env-default:"1m"`
+	Burst    int           `yaml:"burst" env:"BURST"`
+}
+
+// Workspaces configures how requests find their workspace. Paths under
+// /w/{workspace} name it, hosts can be bound to one.
+type Workspaces struct {
+	// Hosts maps hosts to workspaces, e.g. docs.example.com: docs.
+	// Other hosts serve the default workspace.
+	Hosts map[string]string `yaml:"hosts" env:"WORKSPACE_HOSTS"`
+}
+
+const (
+	StorageSqlite   = "sqlite"
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+
+	// memoryScheme in storage_path selects the in-memory storage
+	// without changing storage_type.
+	memoryScheme = "memory://"
+)
+
 type Config struct {
 	Env         string `yaml:"env" env:"ENV"  env-required:"true"`
+	StorageType string `yaml:"storage_type" env:"STORAGE_TYPE" env-default:"sqlite"`
 	StoragePath string `yaml:"storage_path" env:"STORAGE_PATH" envDefault:"./storage"`
+	StorageDSN  string `yaml:"storage_dsn" env:"STORAGE_DSN"`
 	HttpServer  `yaml:"http_server"`
+	Reaper      Reaper     `yaml:"reaper"`
+	Analytics   Analytics  `yaml:"analytics"`
+	Backup      Backup     `yaml:"backup"`
+	Alias       Alias      `yaml:"alias"`
+	Auth        Auth       `yaml:"auth"`
+	Workspaces  Workspaces `yaml:"workspaces"`
+	RateLimit   RateLimit  `yaml:"rate_limit"`
+	// Dedup makes POST /url return the generated alias of an earlier
+	// link to the same url, requests can override it.
+	Dedup bool `yaml:"dedup" env:"DEDUP" env-default:"false"`
 }
 
 func MustLoad() *Config {
@@ -35,5 +175,75 @@ func MustLoad() *Config {
 		log.Fatal(err)
 	}
 
+	if cfg.StorageType == StorageSqlite && strings.HasPrefix(cfg.StoragePath, memoryScheme) {
+		cfg.StorageType = StorageMemory
+	}
+
+	switch cfg.StorageType {
+	case StorageSqlite, StorageMemory:
+	case StoragePostgres:
+		if cfg.StorageDSN == "" {
+			log.Fatal("storage_dsn is required for postgres storage")
+		}
+	default:
+		log.Fatalf("unknown storage type %q", cfg.StorageType)
+	}
+
+	if cfg.Reaper.Mode != "purge" && cfg.Reaper.Mode != "archive" {
+		log.Fatalf("unknown reaper mode %q", cfg.Reaper.Mode)
+	}
+
+	if cfg.Reaper.Interval <= 0 {
+		log.Fatal("reaper interval must be positive")
+	}
+
+	if cfg.Analytics.Policy != "drop" && cfg.Analytics.Policy != "block" {
+		log.Fatalf("unknown analytics policy %q", cfg.Analytics.Policy)
+	}
+
+	if cfg.Analytics.BufferSize <= 0 || cfg.Analytics.Workers <= 0 || cfg.Analytics.BatchSize <= 0 {
+		log.Fatal("analytics buffer_size, workers and batch_size must be positive")
+	}
+
+	if cfg.Analytics.FlushInterval <= 0 {
+		log.Fatal("analytics flush interval must be positive")
+	}
+
+	if cfg.Backup.Keep <= 0 {
+		log.Fatal("backup keep must be positive")
+	}
+
+	switch cfg.Alias.Generator {
+	case "random", "sequence", "hashids", "words":
+	default:
+		log.Fatalf("unknown alias generator %q", cfg.Alias.Generator)
+	}
+
+	if cfg.Alias.Length < 1 || cfg.Alias.Length > 32 {
+		log.Fatal("alias length must be from 1 to 32")
+	}
+
+	if cfg.Alias.MaxAttempts <= 0 || cfg.Alias.GrowAfter <= 0 {
+		log.Fatal("alias max_attempts and grow_after must be positive")
+	}
+
+	if cfg.Alias.Policy.MinLength < 1 || cfg.Alias.Policy.MaxLength < cfg.Alias.Policy.MinLength {
+		log.Fatal("alias policy needs 1 <= min_length <= max_length")
+	}
+
+	if cfg.Alias.Policy.Charset == "" || strings.ContainsAny(cfg.Alias.Policy.Charset, "/?#%.") {
+		log.Fatal(`alias policy charset must be set and must not contain "/", "?", "#", "%" or "."`)
+	}
+
+	for _, limit := range []Limit{cfg.RateLimit.Read, cfg.RateLimit.Write, cfg.RateLimit.Redirect, cfg.RateLimit.Admin} {
+		if limit.Requests < 0 || limit.Burst < 0 || limit.Per <= 0 {
+			log.Fatal("rate limit requests and burst must not be negative, per must be positive")
+		}
+	}
+
+	if jwt := cfg.Auth.JWT; jwt.Enabled && jwt.JWKSFile == "" && jwt.PublicKeyFile == "" && jwt.Secret == "" {
+		log.Fatal("jwt needs jwks_file, public_key_file or secret")
+	}
+
 	return &cfg
 }
@@ -0,0 +1,68 @@
+package createBackup
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/backup"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+)
+
+type BackupCreator interface {
+	Create(ctx context.Context) (backup.Info, error)
+}
+
+// Response represents a created backup
+// @Description Name and size of the backup file
+// swagger:model
+type Response struct {
+	resp.Response
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// New
+// @Summary Back up the database
+// @Description Snapshots the SQLite database while the server keeps serving and removes
+// @Description the backups beyond the retention limit. Only available with sqlite storage.
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} Response
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no admin scope"
+// @Failure 500 {object} resp.Problem
+// @Security ApiKeyAuth
+// @Router /admin/backup [post]
+func New(log *slog.Logger, creator BackupCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.createBackup.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		info, err := creator.Create(r.Context())
+		if err != nil {
+			log.Error("failed to create backup", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to create backup")
+
+			return
+		}
+
+		log.Info("success create backup", slog.String("name", info.Name), slog.Int64("size", info.Size))
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			Name:      info.Name,
+			Size:      info.Size,
+			CreatedAt: info.CreatedAt,
+		})
+	}
+}
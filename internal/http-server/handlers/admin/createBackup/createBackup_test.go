@@ -0,0 +1,76 @@
+package createBackup
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockBackupCreator struct {
+	mock.Mock
+}
+
+func (m *MockBackupCreator) Create(ctx context.Context) (backup.Info, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(backup.Info), args.Error(1)
+}
+
+func TestCreateBackupHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		info           backup.Info
+		err            error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			info: backup.Info{
+				Name:      "backup-20250301T100000.000Z.db",
+				Path:      "/app/storage/backups/backup-20250301T100000.000Z.db",
+				Size:      24576,
+				CreatedAt: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","name":"backup-20250301T100000.000Z.db","size":24576,"created_at":"2025-03-01T10:00:00Z"}`,
+		},
+		{
+			name:           "failure",
+			err:            errors.New("disk full"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to create backup","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			creator := new(MockBackupCreator)
+			creator.On("Create", mock.Anything).Return(tt.info, tt.err)
+
+			req, err := http.NewRequest("POST", "/admin/backup", nil)
+			require.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			middleware.RequestID(New(slog.Default(), creator)).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			creator.AssertExpectations(t)
+		})
+	}
+}
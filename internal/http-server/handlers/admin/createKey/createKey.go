@@ -0,0 +1,111 @@
+package createKey
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type KeyCreator interface {
+	Create(name string, scopes []string) (string, storage.APIKey, error)
+}
+
+// Request represents API key creation request
+// @Description Name of the key and its scopes: read, write or admin.
+// @Description write includes read, admin includes both.
+type Request struct {
+	Name   string   `json:"name" validate:"required,max=100" example:"ci"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=read write admin" example:"read,write"`
+}
+
+// Response represents a created API key
+// @Description The key is shown only once, only its hash is stored.
+// swagger:model
+type Response struct {
+	resp.Response
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key" example:"us_1a2b3c4d_9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Prefix    string    `json:"prefix" example:"1a2b3c4d"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// New
+// @Summary Create an API key
+// @Description Issues a key for the management API. Save the key from the response,
+// @Description it cannot be shown again.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param request body Request true "Key name and scopes"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Invalid request"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no admin scope"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /admin/keys [post]
+func New(log *slog.Logger, keyCreator KeyCreator) http.HandlerFunc {
+	validate := resp.NewValidator()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.createKey.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request")
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			var validatorErr validator.ValidationErrors
+			errors.As(err, &validatorErr)
+
+			log.Info("failed to validate request", slog.String("error", err.Error()))
+
+			resp.RenderValidationError(w, r, validatorErr)
+
+			return
+		}
+
+		plain, key, err := keyCreator.Create(req.Name, req.Scopes)
+		if err != nil {
+			log.Error("failed to create api key", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to create api key")
+
+			return
+		}
+
+		// the key is a secret, only its id goes to the log
+		log.Info("success create api key", slog.Int64("id", key.ID), slog.String("name", key.Name))
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			ID:        key.ID,
+			Name:      key.Name,
+			Key:       plain,
+			Prefix:    key.Prefix,
+			Scopes:    key.Scopes,
+			CreatedAt: key.CreatedAt,
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package createKey
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockKeyCreator struct {
+	mock.Mock
+}
+
+func (m *MockKeyCreator) Create(name string, scopes []string) (string, storage.APIKey, error) {
+	args := m.Called(name, scopes)
+	return args.String(0), args.Get(1).(storage.APIKey), args.Error(2)
+}
+
+func TestCreateKeyHandler(t *testing.T) {
+	log := slog.Default()
+
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		body           string
+		setupMock      func(*MockKeyCreator)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			body: `{"name":"ci","scopes":["read","write"]}`,
+			setupMock: func(m *MockKeyCreator) {
+				m.On("Create", "ci", []string{"read", "write"}).Return("us_1a2b3c4d_ff", storage.APIKey{
+					ID: 3, Name: "ci", Prefix: "1a2b3c4d", Hash: "secret", Scopes: []string{"read", "write"}, CreatedAt: created,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","id":3,"name":"ci","key":"us_1a2b3c4d_ff","prefix":"1a2b3c4d","scopes":["read","write"],"created_at":"2026-10-01T12:00:00Z"}`,
+		},
+		{
+			name:           "invalid json",
+			body:           `{"name":`,
+			setupMock:      func(m *MockKeyCreator) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"failed to decode request","code":"invalid_body","request_id":"test-request"}`,
+		},
+		{
+			name:           "no scopes",
+			body:           `{"name":"ci","scopes":[]}`,
+			setupMock:      func(m *MockKeyCreator) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Scopes is not valid","code":"validation_failed","request_id":"test-request",` +
+				`"errors":[{"field":"scopes","rule":"min","message":"must have at least 1 item","param":"1"}]}`,
+		},
+		{
+			name:           "unknown scope",
+			body:           `{"name":"ci","scopes":["root"]}`,
+			setupMock:      func(m *MockKeyCreator) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Scopes[0] is not valid","code":"validation_failed","request_id":"test-request",` +
+				`"errors":[{"field":"scopes[0]","rule":"oneof","message":"must be one of: read, write, admin","param":"read write admin"}]}`,
+		},
+		{
+			name: "storage error",
+			body: `{"name":"ci","scopes":["admin"]}`,
+			setupMock: func(m *MockKeyCreator) {
+				m.On("Create", "ci", []string{"admin"}).Return("", storage.APIKey{}, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to create api key","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockCreator := new(MockKeyCreator)
+			tt.setupMock(mockCreator)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Post("/admin/keys", New(log, mockCreator))
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/keys", bytes.NewBufferString(tt.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockCreator.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package createWorkspace
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type WorkspaceCreator interface {
+	CreateWorkspace(ws storage.Workspace) error
+}
+
+// Request represents workspace creation request
+// @Description The name goes to paths and hosts: lowercase letters, digits and hyphens.
+type Request struct {
+	Name string `json:"name" validate:"required,max=63,lowercase,hostname_rfc1123,excludes=." example:"docs"`
+}
+
+// Response represents a created workspace
+// @Description Links of the workspace are managed under /w/{name}
+// swagger:model
+type Response struct {
+	resp.Response
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// New
+// @Summary Create a workspace
+// @Description Creates a workspace with its own aliases, links and members.
+// @Description Only admin keys work in it until members are added.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param request body Request true "Workspace name"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Invalid request"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no admin scope"
+// @Failure 409 {object} resp.Problem "Workspace already exists"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /admin/workspaces [post]
+func New(log *slog.Logger, workspaceCreator WorkspaceCreator) http.HandlerFunc {
+	validate := resp.NewValidator()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.createWorkspace.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request")
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			var validatorErr validator.ValidationErrors
+			errors.As(err, &validatorErr)
+
+			log.Info("failed to validate request", slog.String("error", err.Error()))
+
+			resp.RenderValidationError(w, r, validatorErr)
+
+			return
+		}
+
+		ws := storage.Workspace{Name: req.Name, CreatedAt: time.Now().UTC()}
+
+		err := workspaceCreator.CreateWorkspace(ws)
+		if errors.Is(err, storage.ErrWorkspaceExists) {
+			log.Info("workspace already exists", slog.String("name", req.Name))
+
+			resp.RenderError(w, r, http.StatusConflict, resp.CodeWorkspaceExists, "workspace already exists")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to create workspace", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to create workspace")
+
+			return
+		}
+
+		log.Info("success create workspace", slog.String("name", ws.Name))
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			Name:      ws.Name,
+			CreatedAt: ws.CreatedAt,
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package createWorkspace
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWorkspaceCreator struct {
+	mock.Mock
+}
+
+func (m *MockWorkspaceCreator) CreateWorkspace(ws storage.Workspace) error {
+	args := m.Called(ws)
+	return args.Error(0)
+}
+
+func named(name string) interface{} {
+	return mock.MatchedBy(func(ws storage.Workspace) bool {
+		return ws.Name == name && !ws.CreatedAt.IsZero()
+	})
+}
+
+func TestCreateWorkspaceHandler(t *testing.T) {
+	log := slog.Default()
+
+	invalidName := `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Name is not valid","code":"validation_failed","request_id":"test-request",` +
+		`"errors":[{"field":"name","rule":"%s","message":"is not valid"}]}`
+
+	tests := []struct {
+		name           string
+		body           string
+		setupMock      func(*MockWorkspaceCreator)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			body: `{"name":"docs-2"}`,
+			setupMock: func(m *MockWorkspaceCreator) {
+				m.On("CreateWorkspace", named("docs-2")).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "already exists",
+			body: `{"name":"default"}`,
+			setupMock: func(m *MockWorkspaceCreator) {
+				m.On("CreateWorkspace", named("default")).Return(storage.ErrWorkspaceExists)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"about:blank","title":"Conflict","status":409,"detail":"workspace already exists","code":"workspace_exists","request_id":"test-request"}`,
+		},
+		{
+			name:           "no name",
+			body:           `{}`,
+			setupMock:      func(m *MockWorkspaceCreator) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Name is a required field","code":"validation_failed","request_id":"test-request",` +
+				`"errors":[{"field":"name","rule":"required","message":"is required"}]}`,
+		},
+		{
+			name:           "uppercase",
+			body:           `{"name":"Docs"}`,
+			setupMock:      func(m *MockWorkspaceCreator) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   fmt.Sprintf(invalidName, "lowercase"),
+		},
+		{
+			name:           "slash",
+			body:           `{"name":"docs/blog"}`,
+			setupMock:      func(m *MockWorkspaceCreator) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   fmt.Sprintf(invalidName, "hostname_rfc1123"),
+		},
+		{
+			name:           "dot",
+			body:           `{"name":"docs.blog"}`,
+			setupMock:      func(m *MockWorkspaceCreator) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Name is not valid","code":"validation_failed","request_id":"test-request",` +
+				`"errors":[{"field":"name","rule":"excludes","message":"is not valid","param":"."}]}`,
+		},
+		{
+			name: "storage error",
+			body: `{"name":"docs"}`,
+			setupMock: func(m *MockWorkspaceCreator) {
+				m.On("CreateWorkspace", named("docs")).Return(errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to create workspace","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockCreator := new(MockWorkspaceCreator)
+			tt.setupMock(mockCreator)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Post("/admin/workspaces", New(log, mockCreator))
+
+			req, err := http.NewRequest(http.MethodPost, "/admin/workspaces", bytes.NewBufferString(tt.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+			} else {
+				assert.Contains(t, rr.Body.String(), `"name":"docs-2"`)
+			}
+
+			mockCreator.AssertExpectations(t)
+		})
+	}
+}
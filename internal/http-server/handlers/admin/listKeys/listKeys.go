@@ -0,0 +1,81 @@
+package listKeys
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type KeyLister interface {
+	List() ([]storage.APIKey, error)
+}
+
+// Key represents a stored API key
+// @Description The key itself is not stored, the prefix tells keys apart
+type Key struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix" example:"1a2b3c4d"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Response represents API key list response
+// @Description All keys, revoked ones included
+// swagger:model
+type Response struct {
+	resp.Response
+	Keys []Key `json:"keys"`
+}
+
+// New
+// @Summary List API keys
+// @Description Lists the keys of the management API in creation order, revoked ones included.
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} Response
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no admin scope"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /admin/keys [get]
+func New(log *slog.Logger, keyLister KeyLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.listKeys.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		keys, err := keyLister.List()
+		if err != nil {
+			log.Error("failed to list api keys", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to list api keys")
+
+			return
+		}
+
+		response := Response{Response: resp.OK(), Keys: make([]Key, 0, len(keys))}
+		for _, key := range keys {
+			response.Keys = append(response.Keys, Key{
+				ID:        key.ID,
+				Name:      key.Name,
+				Prefix:    key.Prefix,
+				Scopes:    key.Scopes,
+				CreatedAt: key.CreatedAt,
+				RevokedAt: key.RevokedAt,
+			})
+		}
+
+		render.JSON(w, r, response)
+	}
+}
@@ -0,0 +1,96 @@
+package listKeys
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockKeyLister struct {
+	mock.Mock
+}
+
+func (m *MockKeyLister) List() ([]storage.APIKey, error) {
+	args := m.Called()
+	keys, _ := args.Get(0).([]storage.APIKey)
+	return keys, args.Error(1)
+}
+
+func TestListKeysHandler(t *testing.T) {
+	log := slog.Default()
+
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	revoked := created.Add(time.Hour)
+
+	tests := []struct {
+		name           string
+		setupMock      func(*MockKeyLister)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			setupMock: func(m *MockKeyLister) {
+				m.On("List").Return([]storage.APIKey{
+					{ID: 1, Name: "ops", Prefix: "1a2b3c4d", Hash: "secret", Scopes: []string{"admin"}, CreatedAt: created},
+					{ID: 2, Name: "ci", Prefix: "5e6f7a8b", Hash: "secret", Scopes: []string{"write"}, CreatedAt: created, RevokedAt: &revoked},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			// хеш ключа не отдается
+			expectedBody: `{"status":"OK","keys":[` +
+				`{"id":1,"name":"ops","prefix":"1a2b3c4d","scopes":["admin"],"created_at":"2026-10-01T12:00:00Z"},` +
+				`{"id":2,"name":"ci","prefix":"5e6f7a8b","scopes":["write"],"created_at":"2026-10-01T12:00:00Z","revoked_at":"2026-10-01T13:00:00Z"}]}`,
+		},
+		{
+			name: "no keys",
+			setupMock: func(m *MockKeyLister) {
+				m.On("List").Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","keys":[]}`,
+		},
+		{
+			name: "storage error",
+			setupMock: func(m *MockKeyLister) {
+				m.On("List").Return(nil, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to list api keys","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockLister := new(MockKeyLister)
+			tt.setupMock(mockLister)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Get("/admin/keys", New(log, mockLister))
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/keys", nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockLister.AssertExpectations(t)
+		})
+	}
+}
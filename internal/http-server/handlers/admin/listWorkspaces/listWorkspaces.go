@@ -0,0 +1,73 @@
+package listWorkspaces
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type WorkspaceLister interface {
+	ListWorkspaces() ([]storage.Workspace, error)
+}
+
+// Workspace represents a workspace in the listing
+// @Description Workspace with its own aliases, links and members
+type Workspace struct {
+	Name      string    `json:"name" example:"docs"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Response represents workspace list response
+// @Description All workspaces, the default one included
+// swagger:model
+type Response struct {
+	resp.Response
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// New
+// @Summary List workspaces
+// @Description Lists the workspaces by name, the default one included.
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} Response
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no admin scope"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /admin/workspaces [get]
+func New(log *slog.Logger, workspaceLister WorkspaceLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.listWorkspaces.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		workspaces, err := workspaceLister.ListWorkspaces()
+		if err != nil {
+			log.Error("failed to list workspaces", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to list workspaces")
+
+			return
+		}
+
+		response := Response{Response: resp.OK(), Workspaces: make([]Workspace, 0, len(workspaces))}
+		for _, ws := range workspaces {
+			response.Workspaces = append(response.Workspaces, Workspace{
+				Name:      ws.Name,
+				CreatedAt: ws.CreatedAt,
+			})
+		}
+
+		render.JSON(w, r, response)
+	}
+}
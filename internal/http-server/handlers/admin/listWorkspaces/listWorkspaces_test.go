@@ -0,0 +1,86 @@
+package listWorkspaces
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWorkspaceLister struct {
+	mock.Mock
+}
+
+func (m *MockWorkspaceLister) ListWorkspaces() ([]storage.Workspace, error) {
+	args := m.Called()
+	workspaces, _ := args.Get(0).([]storage.Workspace)
+	return workspaces, args.Error(1)
+}
+
+func TestListWorkspacesHandler(t *testing.T) {
+	log := slog.Default()
+
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		setupMock      func(*MockWorkspaceLister)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			setupMock: func(m *MockWorkspaceLister) {
+				m.On("ListWorkspaces").Return([]storage.Workspace{
+					{Name: "default", CreatedAt: created},
+					{Name: "docs", CreatedAt: created.Add(time.Hour)},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","workspaces":[` +
+				`{"name":"default","created_at":"2026-10-01T12:00:00Z"},` +
+				`{"name":"docs","created_at":"2026-10-01T13:00:00Z"}]}`,
+		},
+		{
+			name: "storage error",
+			setupMock: func(m *MockWorkspaceLister) {
+				m.On("ListWorkspaces").Return(nil, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to list workspaces","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockLister := new(MockWorkspaceLister)
+			tt.setupMock(mockLister)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Get("/admin/workspaces", New(log, mockLister))
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/workspaces", nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockLister.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package revokeKey
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type KeyRevoker interface {
+	Revoke(id int64) error
+}
+
+// Response represents API key revocation response
+// @Description Success response for API key revocation
+// swagger:model
+type Response struct {
+	resp.Response
+}
+
+// New
+// @Summary Revoke an API key
+// @Description Revokes a key at once, requests with it are rejected from then on.
+// @Description Revoking a revoked key is not an error.
+// @Tags admin
+// @Produce  json
+// @Param id path int true "Id of the key"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Id is not a number"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no admin scope"
+// @Failure 404 {object} resp.Problem "API key not found"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /admin/keys/{id} [delete]
+func New(log *slog.Logger, keyRevoker KeyRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.revokeKey.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Info("invalid id", slog.String("id", chi.URLParam(r, "id")))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "id must be a number")
+
+			return
+		}
+
+		err = keyRevoker.Revoke(id)
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Info("api key not found", slog.Int64("id", id))
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeAPIKeyNotFound, "api key not found")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to revoke api key", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to revoke api key")
+
+			return
+		}
+
+		log.Info("success revoke api key", slog.Int64("id", id))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package revokeKey
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockKeyRevoker struct {
+	mock.Mock
+}
+
+func (m *MockKeyRevoker) Revoke(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func TestRevokeKeyHandler(t *testing.T) {
+	log := slog.Default()
+
+	tests := []struct {
+		name           string
+		id             string
+		setupMock      func(*MockKeyRevoker)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			id:   "2",
+			setupMock: func(m *MockKeyRevoker) {
+				m.On("Revoke", int64(2)).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK"}`,
+		},
+		{
+			name:           "id is not a number",
+			id:             "two",
+			setupMock:      func(m *MockKeyRevoker) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"id must be a number","code":"validation_failed","request_id":"test-request"}`,
+		},
+		{
+			name: "key not found",
+			id:   "7",
+			setupMock: func(m *MockKeyRevoker) {
+				m.On("Revoke", int64(7)).Return(storage.ErrAPIKeyNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"api key not found","code":"api_key_not_found","request_id":"test-request"}`,
+		},
+		{
+			name: "storage error",
+			id:   "3",
+			setupMock: func(m *MockKeyRevoker) {
+				m.On("Revoke", int64(3)).Return(errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to revoke api key","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockRevoker := new(MockKeyRevoker)
+			tt.setupMock(mockRevoker)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Delete("/admin/keys/{id}", New(log, mockRevoker))
+
+			req, err := http.NewRequest(http.MethodDelete, "/admin/keys/"+tt.id, nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockRevoker.AssertExpectations(t)
+		})
+	}
+}
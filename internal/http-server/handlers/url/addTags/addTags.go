@@ -0,0 +1,157 @@
+package addTags
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+
+	"github.com/popvaleks/url-shortener/internal/auth"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type TagAdder interface {
+	GetOwner(workspace, alias string) (string, error)
+	AddTags(workspace, alias string, tags []string) ([]string, error)
+}
+
+// Request represents tags adding request
+// @Description Tags to add to the link. Tags are case-insensitive
+// @Description and must not contain "/", "?", "#" or "%".
+type Request struct {
+	Tags []string `json:"tags" validate:"required,min=1,max=20,dive,min=1,max=50,excludesall=/?#%" example:"spring,team-a"`
+}
+
+// Response represents tags adding response
+// @Description Success response with all tags of the link
+// swagger:model
+type Response struct {
+	resp.Response
+	Alias string   `json:"alias"`
+	Tags  []string `json:"tags"`
+}
+
+// New
+// @Summary Add tags to URL
+// @Description Labels the link with tags, tags it already has are ignored.
+// @Description Only the owner of the link or an admin can tag it.
+// @Tags url
+// @Accept  json
+// @Produce  json
+// @Param alias path string true "Alias of the URL"
+// @Param input body Request true "Tags to add"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Invalid tags"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no write scope or the URL belongs to another owner"
+// @Failure 404 {object} resp.Problem "URL not found"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /url/{alias}/tags [post]
+func New(
+	log *slog.Logger,
+	tagAdder TagAdder,
+	principal func(*http.Request) auth.Principal,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
+	validate := resp.NewValidator()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.addTags.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias not allowed")
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "alias not allowed")
+
+			return
+		}
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request")
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			var validatorErr validator.ValidationErrors
+			errors.As(err, &validatorErr)
+
+			log.Info("failed to validate request", slog.String("error", err.Error()))
+
+			resp.RenderValidationError(w, r, validatorErr)
+
+			return
+		}
+
+		owner, err := tagAdder.GetOwner(ws, alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found")
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeUrlNotFound, "url not found")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get owner", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+			return
+		}
+
+		if caller := principal(r); !caller.CanManage(owner) {
+			log.Info("url belongs to another owner", slog.String("owner", owner), slog.String("caller", caller.Name))
+
+			resp.RenderError(w, r, http.StatusForbidden, resp.CodeNotOwner, "url belongs to another owner")
+
+			return
+		}
+
+		tags, err := tagAdder.AddTags(ws, alias, req.Tags)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found")
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeUrlNotFound, "url not found")
+
+			return
+		}
+
+		if err != nil {
+			log.Error("internal server error", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+			return
+		}
+
+		log.Info("success add tags", slog.Any("tags", tags))
+
+		if tags == nil {
+			tags = []string{}
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Alias:    alias,
+			Tags:     tags,
+		})
+	}
+}
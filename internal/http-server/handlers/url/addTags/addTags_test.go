@@ -0,0 +1,143 @@
+package addTags
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTagAdder struct {
+	mock.Mock
+}
+
+func (m *MockTagAdder) GetOwner(workspace, alias string) (string, error) {
+	args := m.Called(workspace, alias)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTagAdder) AddTags(workspace, alias string, tags []string) ([]string, error) {
+	args := m.Called(workspace, alias, tags)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
+func TestAddTagsHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		alias          string
+		requestBody    string
+		caller         auth.Principal
+		setupMock      func(*MockTagAdder)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "success",
+			alias:       "example",
+			requestBody: `{"tags": ["Spring", "team-a"]}`,
+			caller:      auth.Principal{Name: "ops"},
+			setupMock: func(m *MockTagAdder) {
+				m.On("GetOwner", storage.DefaultWorkspace, "example").Return("ops", nil)
+				m.On("AddTags", storage.DefaultWorkspace, "example", []string{"Spring", "team-a"}).
+					Return([]string{"promo", "spring", "team-a"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","alias":"example","tags":["promo","spring","team-a"]}`,
+		},
+		{
+			name:        "url not found",
+			alias:       "notfound",
+			requestBody: `{"tags": ["spring"]}`,
+			setupMock: func(m *MockTagAdder) {
+				m.On("GetOwner", storage.DefaultWorkspace, "notfound").Return("", storage.ErrUrlNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"url not found","code":"url_not_found","request_id":"test-request"}`,
+		},
+		{
+			name:        "another owner",
+			alias:       "example",
+			requestBody: `{"tags": ["spring"]}`,
+			caller:      auth.Principal{Name: "dev"},
+			setupMock: func(m *MockTagAdder) {
+				m.On("GetOwner", storage.DefaultWorkspace, "example").Return("ops", nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"url belongs to another owner","code":"not_owner","request_id":"test-request"}`,
+		},
+		{
+			name:           "no tags",
+			alias:          "example",
+			requestBody:    `{"tags": []}`,
+			setupMock:      func(m *MockTagAdder) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Tags is not valid","code":"validation_failed","request_id":"test-request","errors":[{"field":"tags","rule":"min","message":"must have at least 1 item","param":"1"}]}`,
+		},
+		{
+			name:           "invalid tag",
+			alias:          "example",
+			requestBody:    `{"tags": ["ok", "50%"]}`,
+			setupMock:      func(m *MockTagAdder) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Tags[1] is not valid","code":"validation_failed","request_id":"test-request","errors":[{"field":"tags[1]","rule":"excludesall","message":"must not contain any of the characters /?#%","param":"/?#%"}]}`,
+		},
+		{
+			name:           "invalid body",
+			alias:          "example",
+			requestBody:    `{"tags": "spring"}`,
+			setupMock:      func(m *MockTagAdder) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"failed to decode request","code":"invalid_body","request_id":"test-request"}`,
+		},
+		{
+			name:        "internal server error",
+			alias:       "error",
+			requestBody: `{"tags": ["spring"]}`,
+			caller:      auth.Unrestricted,
+			setupMock: func(m *MockTagAdder) {
+				m.On("GetOwner", storage.DefaultWorkspace, "error").Return("", nil)
+				m.On("AddTags", storage.DefaultWorkspace, "error", []string{"spring"}).Return([]string(nil), errors.New("internal error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockAdder := new(MockTagAdder)
+			tt.setupMock(mockAdder)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Post("/url/{alias}/tags", New(slog.Default(), mockAdder, func(*http.Request) auth.Principal { return tt.caller }, defaultWorkspace))
+
+			req, err := http.NewRequest("POST", "/url/"+tt.alias+"/tags", bytes.NewBufferString(tt.requestBody))
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockAdder.AssertExpectations(t)
+		})
+	}
+}
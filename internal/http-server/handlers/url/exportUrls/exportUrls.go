@@ -0,0 +1,91 @@
+package exportUrls
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/lib/transfer"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type UrlExporter interface {
+	ExportUrls(workspace, owner string, fn func(storage.Link) error) error
+}
+
+// New
+// @Summary Export URLs
+// @Description Streams every link of the workspace with its limits, clicks and tags as CSV or NDJSON,
+// @Description the format accepted by POST /url/import.
+// @Tags url
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Param format query string false "File format" Enums(csv, ndjson) default(ndjson)
+// @Param owner query string false "Only links of the owner"
+// @Success 200 {file} file
+// @Failure 400 {object} resp.Problem "Unknown format"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no read scope"
+// @Security ApiKeyAuth
+// @Router /url/export [get]
+func New(
+	log *slog.Logger,
+	urlExporter UrlExporter,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.exportUrls.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = transfer.FormatNDJSON
+		}
+
+		if format != transfer.FormatCSV && format != transfer.FormatNDJSON {
+			log.Info("unknown format", slog.String("format", format))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidQuery, transfer.ErrUnknownFormat.Error())
+
+			return
+		}
+
+		w.Header().Set("Content-Type", transfer.ContentType(format))
+		w.Header().Set("Content-Disposition", `attachment; filename="links.`+format+`"`)
+
+		tw, err := transfer.NewWriter(w, format)
+		if err != nil {
+			log.Error("failed to start export", slog.String("error", err.Error()))
+
+			return
+		}
+
+		count := 0
+
+		err = urlExporter.ExportUrls(ws, r.URL.Query().Get("owner"), func(link storage.Link) error {
+			count++
+
+			return tw.Write(transfer.RecordOf(link))
+		})
+		if err == nil {
+			err = tw.Flush()
+		}
+
+		// the status is already sent, a failure can only cut the file short
+		if err != nil {
+			log.Error("export interrupted", slog.String("error", err.Error()), slog.Int("count", count))
+
+			return
+		}
+
+		log.Info("success export urls", slog.Int("count", count))
+	}
+}
@@ -0,0 +1,141 @@
+package exportUrls
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockUrlExporter struct {
+	mock.Mock
+	links []storage.Link
+}
+
+func (m *MockUrlExporter) ExportUrls(workspace, owner string, fn func(storage.Link) error) error {
+	args := m.Called(workspace, owner)
+
+	for _, link := range m.links {
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+
+	return args.Error(0)
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
+func TestExportHandler(t *testing.T) {
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	maxClicks := int64(10)
+
+	links := []storage.Link{
+		{ID: 1, Alias: "a", Url: "https://example.com", CreatedAt: createdAt, Tags: []string{"promo", "spring"}},
+		{ID: 2, Alias: "b", Url: "https://example.org", CreatedAt: createdAt, MaxClicks: &maxClicks, Clicks: 3},
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		links       []storage.Link
+		err         error
+		contentType string
+		body        string
+	}{
+		{
+			name:        "ndjson by default",
+			links:       links,
+			contentType: "application/x-ndjson",
+			body: `{"alias":"a","url":"https://example.com","created_at":"2025-03-01T10:00:00Z","tags":["promo","spring"]}
+{"alias":"b","url":"https://example.org","created_at":"2025-03-01T10:00:00Z","max_clicks":10,"clicks":3}
+`,
+		},
+		{
+			name:        "csv",
+			query:       "?format=csv",
+			links:       links,
+			contentType: "text/csv; charset=utf-8",
+			body: "alias,url,created_at,expires_at,max_clicks,clicks,tags,owner\n" +
+				"a,https://example.com,2025-03-01T10:00:00Z,,,0,promo;spring,\n" +
+				"b,https://example.org,2025-03-01T10:00:00Z,,10,3,,\n",
+		},
+		{
+			name:        "empty csv has a header",
+			query:       "?format=csv",
+			contentType: "text/csv; charset=utf-8",
+			body:        "alias,url,created_at,expires_at,max_clicks,clicks,tags,owner\n",
+		},
+		{
+			// статус уже отправлен, файл просто обрывается
+			name:        "storage error",
+			links:       links[:1],
+			err:         errors.New("database error"),
+			contentType: "application/x-ndjson",
+			body:        "",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			exporter := &MockUrlExporter{links: tt.links}
+			exporter.On("ExportUrls", storage.DefaultWorkspace, "").Return(tt.err)
+
+			req, err := http.NewRequest("GET", "/url/export"+tt.query, nil)
+			require.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			middleware.RequestID(New(slog.Default(), exporter, defaultWorkspace)).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.contentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, tt.body, rr.Body.String())
+
+			exporter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExportHandlerOwner(t *testing.T) {
+	exporter := &MockUrlExporter{links: []storage.Link{
+		{ID: 2, Alias: "b", Url: "https://example.org", CreatedAt: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC), Owner: "dev"},
+	}}
+	exporter.On("ExportUrls", storage.DefaultWorkspace, "dev").Return(nil)
+
+	req, err := http.NewRequest("GET", "/url/export?format=csv&owner=dev", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	middleware.RequestID(New(slog.Default(), exporter, defaultWorkspace)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "alias,url,created_at,expires_at,max_clicks,clicks,tags,owner\n"+
+		"b,https://example.org,2025-03-01T10:00:00Z,,,0,,dev\n", rr.Body.String())
+	exporter.AssertExpectations(t)
+}
+
+func TestExportHandlerUnknownFormat(t *testing.T) {
+	exporter := new(MockUrlExporter)
+
+	req, err := http.NewRequest("GET", "/url/export?format=xml", nil)
+	require.NoError(t, err)
+	req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+	rr := httptest.NewRecorder()
+	middleware.RequestID(New(slog.Default(), exporter, defaultWorkspace)).ServeHTTP(rr, req)
+
+	assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"format must be csv or ndjson","code":"invalid_query","request_id":"test-request"}`, rr.Body.String())
+	exporter.AssertNotCalled(t, "ExportUrls")
+}
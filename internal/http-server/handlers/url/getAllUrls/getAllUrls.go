@@ -1,57 +1,231 @@
 package getAllUrls
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
 )
 
-type AllUrlGetter interface {
-	GetAllUrls() (map[string]string, error)
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+const (
+	orderAsc  = "asc"
+	orderDesc = "desc"
+)
+
+type UrlLister interface {
+	ListUrls(params storage.ListParams) (storage.Page, error)
+}
+
+// Link represents a short link in the listing
+// @Description Short link with its target and limits
+type Link struct {
+	Alias     string     `json:"alias"`
+	Url       string     `json:"url"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxClicks *int64     `json:"max_clicks,omitempty"`
+	// Clicks is only counted for click-limited links
+	Clicks *int64   `json:"clicks,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Owner  string   `json:"owner,omitempty"`
 }
 
-// Response represents response with all URLs
-// @Description Success response containing all URL mappings
+// Response represents a page of URLs
+// @Description Success response containing an ordered page of links
+// @Description and the cursor of the next page
 // swagger:model
 type Response struct {
 	resp.Response
-	Result map[string]string `json:"result"`
+	Result     []Link `json:"result"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // New
-// @Summary Get all URLs
-// @Description Returns all existing short URL mappings
+// @Summary List URLs
+// @Description Returns a page of short URL mappings. Pass next_cursor of the response
+// @Description as cursor to get the next page with the same sort and order.
+// @Description Every member of the workspace sees all of its links.
 // @Tags url
 // @Produce  json
+// @Param limit query int false "Page size, 100 by default, at most 1000"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "Sort key" Enums(created, alias) default(created)
+// @Param order query string false "Sort order" Enums(asc, desc) default(asc)
+// @Param alias_prefix query string false "Only aliases starting with the prefix"
+// @Param domain query string false "Only links to the domain and its subdomains"
+// @Param tag query string false "Only links with the tag"
+// @Param owner query string false "Only links of the owner"
 // @Success 200 {object} Response
-// @Failure 500 {object} resp.Response
+// @Failure 400 {object} resp.Problem "Invalid query parameters"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no read scope"
+// @Failure 500 {object} resp.Problem
+// @Security ApiKeyAuth
 // @Router /url [get]
-func New(log *slog.Logger, allUrlGetter AllUrlGetter) http.HandlerFunc {
+func New(
+	log *slog.Logger,
+	urlLister UrlLister,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.getAllUrls.New"
 
-		log = log.With(
+		ws := workspace(r)
+
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
 		)
 
-		rUrlMap, err := allUrlGetter.GetAllUrls()
+		params, err := listParams(r.URL.Query())
+		if err != nil {
+			log.Info("invalid query", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidQuery, err.Error())
+
+			return
+		}
+
+		params.Workspace = ws
+
+		page, err := urlLister.ListUrls(params)
 		if err != nil {
-			log.Error("internal server error")
+			log.Error("internal server error", slog.String("error", err.Error()))
 
-			render.JSON(w, r, resp.Error("internal server error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
 
 			return
 		}
 
-		log.Info("success get all urls")
+		log.Info("success list urls", slog.Int("count", len(page.Links)))
+
+		links := make([]Link, 0, len(page.Links))
+		for _, link := range page.Links {
+			item := Link{
+				Alias:     link.Alias,
+				Url:       link.Url,
+				CreatedAt: link.CreatedAt,
+				ExpiresAt: link.ExpiresAt,
+				MaxClicks: link.MaxClicks,
+				Tags:      link.Tags,
+				Owner:     link.Owner,
+			}
+			if link.MaxClicks != nil {
+				item.Clicks = &link.Clicks
+			}
 
-		render.JSON(w, r, Response{
+			links = append(links, item)
+		}
+
+		response := Response{
 			Response: resp.OK(),
-			Result:   rUrlMap,
-		})
+			Result:   links,
+		}
+		if page.Next != nil {
+			response.NextCursor = encodeCursor(params, page.Next)
+		}
+
+		render.JSON(w, r, response)
+	}
+}
+
+func listParams(query url.Values) (storage.ListParams, error) {
+	params := storage.ListParams{
+		Sort:        storage.SortCreated,
+		Limit:       defaultLimit,
+		AliasPrefix: query.Get("alias_prefix"),
+		Domain:      strings.ToLower(strings.TrimPrefix(query.Get("domain"), ".")),
+		Tag:         storage.NormalizeTag(query.Get("tag")),
+		Owner:       query.Get("owner"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxLimit {
+			return storage.ListParams{}, errors.New("limit must be a number from 1 to 1000")
+		}
+		params.Limit = n
+	}
+
+	switch sort := query.Get("sort"); sort {
+	case "", storage.SortCreated:
+	case storage.SortAlias:
+		params.Sort = storage.SortAlias
+	default:
+		return storage.ListParams{}, errors.New("sort must be created or alias")
+	}
+
+	switch order := query.Get("order"); order {
+	case "", orderAsc:
+	case orderDesc:
+		params.Desc = true
+	default:
+		return storage.ListParams{}, errors.New("order must be asc or desc")
+	}
+
+	if c := query.Get("cursor"); c != "" {
+		after, err := decodeCursor(params, c)
+		if err != nil {
+			return storage.ListParams{}, err
+		}
+		params.After = after
+	}
+
+	return params, nil
+}
+
+// cursor is the opaque next_cursor. It remembers the sort it was made
+// for, because a position in one order means nothing in another.
+type cursor struct {
+	Sort      string    `json:"s"`
+	Desc      bool      `json:"d,omitempty"`
+	CreatedAt time.Time `json:"c"`
+	Alias     string    `json:"a"`
+	ID        int64     `json:"i"`
+}
+
+func encodeCursor(params storage.ListParams, next *storage.Cursor) string {
+	data, _ := json.Marshal(cursor{
+		Sort:      params.Sort,
+		Desc:      params.Desc,
+		CreatedAt: next.CreatedAt,
+		Alias:     next.Alias,
+		ID:        next.ID,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(params storage.ListParams, s string) (*storage.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, errors.New("invalid cursor")
 	}
+
+	if c.Sort != params.Sort || c.Desc != params.Desc {
+		return nil, errors.New("cursor was made for another sort or order")
+	}
+
+	return &storage.Cursor{CreatedAt: c.CreatedAt, Alias: c.Alias, ID: c.ID}, nil
 }
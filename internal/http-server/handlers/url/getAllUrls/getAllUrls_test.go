@@ -1,60 +1,126 @@
 package getAllUrls
 
 import (
+	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/http-server/middleware/workspace"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-type MockUrlGetter struct {
+type MockUrlLister struct {
 	mock.Mock
 }
 
-func (m *MockUrlGetter) GetAllUrls() (map[string]string, error) {
-	args := m.Called()
-	return args.Get(0).(map[string]string), args.Error(1)
+func (m *MockUrlLister) ListUrls(params storage.ListParams) (storage.Page, error) {
+	args := m.Called(params)
+	return args.Get(0).(storage.Page), args.Error(1)
 }
 
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
 func TestGetAllUrlsHandler(t *testing.T) {
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	maxClicks := int64(10)
+
+	defaultParams := storage.ListParams{Workspace: storage.DefaultWorkspace, Sort: storage.SortCreated, Limit: defaultLimit}
+
 	tests := []struct {
 		name           string
-		setupMock      func(*MockUrlGetter)
+		query          string
+		setupMock      func(*MockUrlLister)
 		expectedStatus int
 		expectedBody   string
 	}{
 		{
 			name: "success with urls",
-			setupMock: func(m *MockUrlGetter) {
-				m.On("GetAllUrls").Return(map[string]string{
-					"abc": "https://example.com",
-					"def": "https://google.com",
-				}, nil)
+			setupMock: func(m *MockUrlLister) {
+				m.On("ListUrls", defaultParams).Return(storage.Page{Links: []storage.Link{
+					{ID: 1, Alias: "abc", Url: "https://example.com", CreatedAt: createdAt, Owner: "ops"},
+					{ID: 2, Alias: "def", Url: "https://google.com", CreatedAt: createdAt,
+						ExpiresAt: &expiresAt, MaxClicks: &maxClicks, Clicks: 3},
+				}}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"result": {"abc":"https://example.com", "def":"https://google.com"},"status":"OK"}`,
+			expectedBody: `{"status":"OK","result":[
+				{"alias":"abc","url":"https://example.com","created_at":"2025-03-01T10:00:00Z","owner":"ops"},
+				{"alias":"def","url":"https://google.com","created_at":"2025-03-01T10:00:00Z",
+					"expires_at":"2030-01-02T03:04:05Z","max_clicks":10,"clicks":3}]}`,
 		},
 		{
-			name: "empty result",
-			setupMock: func(m *MockUrlGetter) {
-				m.On("GetAllUrls").Return(map[string]string{}, nil)
+			name:  "params are passed to the storage",
+			query: "?limit=2&sort=alias&order=desc&alias_prefix=pro&domain=.Example.COM",
+			setupMock: func(m *MockUrlLister) {
+				m.On("ListUrls", storage.ListParams{
+					Workspace:   storage.DefaultWorkspace,
+					Sort:        storage.SortAlias,
+					Desc:        true,
+					Limit:       2,
+					AliasPrefix: "pro",
+					Domain:      "example.com",
+				}).Return(storage.Page{Links: []storage.Link{}}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"status":"OK","result":{}}`,
+			expectedBody:   `{"status":"OK","result":[]}`,
 		},
 		{
-			name: "internal server error",
-			setupMock: func(m *MockUrlGetter) {
-				m.On("GetAllUrls").Return(map[string]string{}, errors.New("database error"))
+			name:  "owner filter",
+			query: "?owner=dev",
+			setupMock: func(m *MockUrlLister) {
+				m.On("ListUrls", storage.ListParams{Workspace: storage.DefaultWorkspace, Sort: storage.SortCreated, Limit: defaultLimit, Owner: "dev"}).
+					Return(storage.Page{Links: []storage.Link{}}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"error":"internal server error", "status":"Error"}`,
+			expectedBody:   `{"status":"OK","result":[]}`,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=1001",
+			setupMock:      func(m *MockUrlLister) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"limit must be a number from 1 to 1000","code":"invalid_query","request_id":"test-request"}`,
+		},
+		{
+			name:           "invalid sort",
+			query:          "?sort=url",
+			setupMock:      func(m *MockUrlLister) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"sort must be created or alias","code":"invalid_query","request_id":"test-request"}`,
+		},
+		{
+			name:           "invalid order",
+			query:          "?order=up",
+			setupMock:      func(m *MockUrlLister) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"order must be asc or desc","code":"invalid_query","request_id":"test-request"}`,
+		},
+		{
+			name:           "invalid cursor",
+			query:          "?cursor=bm90LWpzb24",
+			setupMock:      func(m *MockUrlLister) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid cursor","code":"invalid_query","request_id":"test-request"}`,
+		},
+		{
+			name: "internal server error",
+			setupMock: func(m *MockUrlLister) {
+				m.On("ListUrls", defaultParams).Return(storage.Page{}, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error","request_id":"test-request"}`,
 		},
 	}
 
@@ -64,25 +130,96 @@ func TestGetAllUrlsHandler(t *testing.T) {
 			t.Parallel()
 
 			// Создаем новый мок для каждого теста
-			mockGetter := new(MockUrlGetter)
-			tt.setupMock(mockGetter)
+			mockLister := new(MockUrlLister)
+			tt.setupMock(mockLister)
 
-			log := slog.Default()
-
-			r := chi.NewRouter()
-			r.Use(middleware.RequestID)
-			r.Get("/", New(log, mockGetter))
-
-			req, err := http.NewRequest("GET", "/", nil)
-			assert.NoError(t, err)
-
-			rr := httptest.NewRecorder()
-			r.ServeHTTP(rr, req)
+			rr := serve(t, mockLister, tt.query)
 
 			assert.Equal(t, tt.expectedStatus, rr.Code)
 			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
 
-			mockGetter.AssertExpectations(t)
+			mockLister.AssertExpectations(t)
 		})
 	}
 }
+
+func TestGetAllUrlsHandlerCursor(t *testing.T) {
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 123456000, time.UTC)
+	next := &storage.Cursor{CreatedAt: createdAt, Alias: "abc", ID: 7}
+
+	mockLister := new(MockUrlLister)
+	mockLister.On("ListUrls", storage.ListParams{Workspace: storage.DefaultWorkspace, Sort: storage.SortCreated, Desc: true, Limit: 1}).
+		Return(storage.Page{
+			Links: []storage.Link{{ID: 7, Alias: "abc", Url: "https://example.com", CreatedAt: createdAt}},
+			Next:  next,
+		}, nil)
+	mockLister.On("ListUrls", storage.ListParams{Workspace: storage.DefaultWorkspace, Sort: storage.SortCreated, Desc: true, Limit: 1, After: next}).
+		Return(storage.Page{Links: []storage.Link{}}, nil)
+
+	rr := serve(t, mockLister, "?limit=1&order=desc")
+
+	var response Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.NotEmpty(t, response.NextCursor)
+
+	// курсор переносит позицию на следующую страницу
+	rr = serve(t, mockLister, "?limit=1&order=desc&cursor="+response.NextCursor)
+	assert.JSONEq(t, `{"status":"OK","result":[]}`, rr.Body.String())
+
+	// курсор другой сортировки отклоняется
+	rr = serve(t, mockLister, "?limit=1&cursor="+response.NextCursor)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"cursor was made for another sort or order","code":"invalid_query","request_id":"test-request"}`, rr.Body.String())
+
+	mockLister.AssertExpectations(t)
+}
+
+func TestGetAllUrlsHandlerViewer(t *testing.T) {
+	repo := memory.New()
+	require.NoError(t, repo.CreateWorkspace(storage.Workspace{Name: "docs", CreatedAt: time.Now()}))
+	require.NoError(t, repo.SetMember(storage.Member{Workspace: "docs", Name: "writer", Role: auth.RoleEditor}))
+	require.NoError(t, repo.SetMember(storage.Member{Workspace: "docs", Name: "reader", Role: auth.RoleViewer}))
+
+	_, err := repo.SaveUrl("docs", "https://example.com", "abc", storage.SaveOptions{Owner: "writer"})
+	require.NoError(t, err)
+
+	// участник с ролью viewer видит ссылки всего пространства
+	principal := func(*http.Request) auth.Principal { return auth.Principal{Name: "reader", Role: auth.RoleViewer} }
+	resolver := workspace.NewResolver(nil)
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Route("/w/{workspace}", func(r chi.Router) {
+		r.With(workspace.New(slog.Default(), repo, resolver, principal, auth.RoleViewer)).Get("/url", New(slog.Default(), repo, resolver.Of))
+	})
+
+	req, err := http.NewRequest("GET", "/w/docs/url", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var response Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Len(t, response.Result, 1)
+	assert.Equal(t, "abc", response.Result[0].Alias)
+	assert.Equal(t, "writer", response.Result[0].Owner)
+}
+
+func serve(t *testing.T, lister UrlLister, query string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Get("/", New(slog.Default(), lister, defaultWorkspace))
+
+	req, err := http.NewRequest("GET", "/"+query, nil)
+	require.NoError(t, err)
+	req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	return rr
+}
@@ -0,0 +1,228 @@
+package importUrls
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/popvaleks/url-shortener/internal/auth"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/lib/transfer"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+// maxBodySize limits an import file. The links are imported in one
+// transaction, so the whole file is decoded before anything is saved.
+const maxBodySize = 64 << 20
+
+type UrlImporter interface {
+	ImportUrls(workspace string, links []storage.Link, onConflict string, dryRun bool) (storage.ImportReport, error)
+}
+
+// Response represents import result
+// @Description Number of created, overwritten and skipped links
+// @Description and the aliases that already existed
+// swagger:model
+type Response struct {
+	resp.Response
+	DryRun    bool     `json:"dry_run,omitempty"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Skipped   int      `json:"skipped"`
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+type params struct {
+	format     string
+	onConflict string
+	dryRun     bool
+}
+
+// New
+// @Summary Import URLs
+// @Description Imports links from a file made by GET /url/export in one transaction.
+// @Description Only alias and url are required. on_conflict decides what happens to aliases
+// @Description that already exist: fail (the default) imports nothing, skip keeps the existing
+// @Description links, overwrite replaces them. With dry_run nothing is saved and the response
+// @Description shows what would change.
+// @Description Imported links belong to the caller. Only admins keep the owners of the file
+// @Description and can overwrite links.
+// @Tags url
+// @Accept  text/csv
+// @Accept  application/x-ndjson
+// @Produce  json
+// @Param format query string false "File format, taken from Content-Type if omitted" Enums(csv, ndjson)
+// @Param on_conflict query string false "Conflict policy" Enums(fail, skip, overwrite) default(fail)
+// @Param dry_run query bool false "Report changes without saving them"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Invalid file or parameters"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no write scope or overwrite without admin scope"
+// @Failure 409 {object} Response "Aliases already exist"
+// @Failure 413 {object} resp.Problem "File larger than 64 MB"
+// @Failure 500 {object} resp.Problem
+// @Security ApiKeyAuth
+// @Router /url/import [post]
+func New(
+	log *slog.Logger,
+	urlImporter UrlImporter,
+	principal func(*http.Request) auth.Principal,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.importUrls.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		p, err := parseParams(r)
+		if err != nil {
+			log.Info("invalid query", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidQuery, err.Error())
+
+			return
+		}
+
+		// overwriting could take links of other owners
+		caller := principal(r)
+		if !caller.Admin && p.onConflict == storage.ConflictOverwrite {
+			log.Info("overwrite without admin scope")
+
+			resp.RenderError(w, r, http.StatusForbidden, resp.CodeNotOwner, "only admins can overwrite links on import")
+
+			return
+		}
+
+		validate := resp.NewValidator()
+
+		var links []storage.Link
+
+		body := http.MaxBytesReader(w, r.Body, maxBodySize)
+
+		err = transfer.Read(body, p.format, func(line int, rec transfer.Record) error {
+			if err := validate.Struct(rec); err != nil {
+				var validatorErr validator.ValidationErrors
+				errors.As(err, &validatorErr)
+
+				return &transfer.LineError{Line: line, Err: errors.New(resp.ValidationError(r, validatorErr).Error)}
+			}
+
+			link := rec.Link()
+			if !caller.Admin {
+				link.Owner = caller.Name
+			}
+			links = append(links, link)
+
+			return nil
+		})
+
+		var (
+			lineErr *transfer.LineError
+			sizeErr *http.MaxBytesError
+		)
+
+		switch {
+		case errors.As(err, &lineErr):
+			log.Info("invalid file", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidFile, err.Error())
+
+			return
+		case errors.As(err, &sizeErr):
+			log.Info("file too large")
+
+			resp.RenderError(w, r, http.StatusRequestEntityTooLarge, resp.CodeFileTooLarge, "file must be at most 64 MB")
+
+			return
+		case err != nil:
+			log.Error("failed to read file", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidFile, "failed to read file")
+
+			return
+		}
+
+		report, err := urlImporter.ImportUrls(ws, links, p.onConflict, p.dryRun)
+		if errors.Is(err, storage.ErrUrlExists) {
+			log.Info("aliases already exist", slog.Int("conflicts", len(report.Conflicts)))
+
+			// the conflicts do not fit a problem, the response lists them
+			resp.ErrorStatus(r, http.StatusConflict)
+			render.JSON(w, r, Response{
+				Response:  resp.Error(resp.T(r, "aliases already exist")),
+				DryRun:    p.dryRun,
+				Conflicts: report.Conflicts,
+			})
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to import urls", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to import urls")
+
+			return
+		}
+
+		log.Info("success import urls",
+			slog.Bool("dry_run", p.dryRun),
+			slog.Int("created", report.Created),
+			slog.Int("updated", report.Updated),
+			slog.Int("skipped", report.Skipped),
+		)
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			DryRun:    p.dryRun,
+			Created:   report.Created,
+			Updated:   report.Updated,
+			Skipped:   report.Skipped,
+			Conflicts: report.Conflicts,
+		})
+	}
+}
+
+func parseParams(r *http.Request) (params, error) {
+	query := r.URL.Query()
+
+	p := params{
+		format:     query.Get("format"),
+		onConflict: query.Get("on_conflict"),
+	}
+
+	if p.format == "" {
+		p.format = transfer.FormatOf(r.Header.Get("Content-Type"))
+	}
+
+	if p.format != transfer.FormatCSV && p.format != transfer.FormatNDJSON {
+		return params{}, transfer.ErrUnknownFormat
+	}
+
+	switch p.onConflict {
+	case "":
+		p.onConflict = storage.ConflictFail
+	case storage.ConflictFail, storage.ConflictSkip, storage.ConflictOverwrite:
+	default:
+		return params{}, errors.New("on_conflict must be fail, skip or overwrite")
+	}
+
+	if dryRun := query.Get("dry_run"); dryRun != "" {
+		v, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			return params{}, errors.New("dry_run must be true or false")
+		}
+		p.dryRun = v
+	}
+
+	return p, nil
+}
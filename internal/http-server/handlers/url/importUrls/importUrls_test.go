@@ -0,0 +1,248 @@
+package importUrls
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockUrlImporter struct {
+	mock.Mock
+}
+
+func (m *MockUrlImporter) ImportUrls(workspace string, links []storage.Link, onConflict string, dryRun bool) (storage.ImportReport, error) {
+	args := m.Called(workspace, links, onConflict, dryRun)
+	return args.Get(0).(storage.ImportReport), args.Error(1)
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
+func TestImportHandler(t *testing.T) {
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	csvBody := "alias,url,created_at,tags\n" +
+		"a,https://example.com,2025-03-01T10:00:00Z,Promo\n" +
+		"b,https://example.org,,\n"
+	links := []storage.Link{
+		{Alias: "a", Url: "https://example.com", CreatedAt: createdAt, Tags: []string{"promo"}},
+		{Alias: "b", Url: "https://example.org", Tags: []string{}},
+	}
+
+	admin := auth.Principal{Name: "root", Admin: true}
+	ops := auth.Principal{Name: "ops"}
+
+	tests := []struct {
+		name           string
+		caller         auth.Principal
+		query          string
+		contentType    string
+		body           string
+		setupMock      func(*MockUrlImporter)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "csv",
+			caller: admin,
+			query:  "?format=csv",
+			body:   csvBody,
+			setupMock: func(m *MockUrlImporter) {
+				m.On("ImportUrls", storage.DefaultWorkspace, links, storage.ConflictFail, false).Return(storage.ImportReport{Created: 2}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","created":2,"updated":0,"skipped":0}`,
+		},
+		{
+			name:        "ndjson by content type",
+			caller:      admin,
+			query:       "?on_conflict=skip",
+			contentType: "application/x-ndjson",
+			body: `{"alias":"a","url":"https://example.com","created_at":"2025-03-01T10:00:00Z","tags":["Promo"]}
+
+{"alias":"b","url":"https://example.org"}`,
+			setupMock: func(m *MockUrlImporter) {
+				m.On("ImportUrls", storage.DefaultWorkspace, links, storage.ConflictSkip, false).Return(
+					storage.ImportReport{Created: 1, Skipped: 1, Conflicts: []string{"b"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","created":1,"updated":0,"skipped":1,"conflicts":["b"]}`,
+		},
+		{
+			name:   "dry run",
+			caller: admin,
+			query:  "?format=csv&on_conflict=overwrite&dry_run=true",
+			body:   csvBody,
+			setupMock: func(m *MockUrlImporter) {
+				m.On("ImportUrls", storage.DefaultWorkspace, links, storage.ConflictOverwrite, true).Return(
+					storage.ImportReport{Created: 1, Updated: 1, Conflicts: []string{"a"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","dry_run":true,"created":1,"updated":1,"skipped":0,"conflicts":["a"]}`,
+		},
+		{
+			name:   "conflicts",
+			caller: admin,
+			query:  "?format=csv",
+			body:   csvBody,
+			setupMock: func(m *MockUrlImporter) {
+				m.On("ImportUrls", storage.DefaultWorkspace, links, storage.ConflictFail, false).Return(
+					storage.ImportReport{Conflicts: []string{"a"}}, storage.ErrUrlExists)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"status":"Error","error":"aliases already exist","created":0,"updated":0,"skipped":0,"conflicts":["a"]}`,
+		},
+		{
+			name:           "invalid url",
+			caller:         admin,
+			query:          "?format=csv",
+			body:           "alias,url\na,https://example.com\nb,not a url\n",
+			setupMock:      func(m *MockUrlImporter) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"line 3: field Url is not a valid URL","code":"invalid_file","request_id":"test-request"}`,
+		},
+		{
+			name:           "missing alias",
+			caller:         admin,
+			query:          "?format=ndjson",
+			body:           `{"url":"https://example.com"}`,
+			setupMock:      func(m *MockUrlImporter) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"line 1: field Alias is a required field","code":"invalid_file","request_id":"test-request"}`,
+		},
+		{
+			name:           "malformed file",
+			caller:         admin,
+			query:          "?format=ndjson",
+			body:           `{"alias":`,
+			setupMock:      func(m *MockUrlImporter) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"line 1: invalid json","code":"invalid_file","request_id":"test-request"}`,
+		},
+		{
+			name:           "unknown format",
+			caller:         admin,
+			contentType:    "application/json",
+			body:           `[]`,
+			setupMock:      func(m *MockUrlImporter) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"format must be csv or ndjson","code":"invalid_query","request_id":"test-request"}`,
+		},
+		{
+			name:           "unknown conflict policy",
+			caller:         admin,
+			query:          "?format=csv&on_conflict=merge",
+			body:           csvBody,
+			setupMock:      func(m *MockUrlImporter) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"on_conflict must be fail, skip or overwrite","code":"invalid_query","request_id":"test-request"}`,
+		},
+		{
+			// ссылки из файла достаются вызывающему
+			name:   "links of the caller",
+			caller: ops,
+			query:  "?format=ndjson",
+			body:   `{"alias":"a","url":"https://example.com","owner":"dev"}`,
+			setupMock: func(m *MockUrlImporter) {
+				m.On("ImportUrls", storage.DefaultWorkspace, []storage.Link{{Alias: "a", Url: "https://example.com", Tags: []string{}, Owner: "ops"}}, storage.ConflictFail, false).
+					Return(storage.ImportReport{Created: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","created":1,"updated":0,"skipped":0}`,
+		},
+		{
+			name:           "overwrite without admin scope",
+			caller:         ops,
+			query:          "?format=csv&on_conflict=overwrite",
+			body:           csvBody,
+			setupMock:      func(m *MockUrlImporter) {},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"only admins can overwrite links on import","code":"not_owner","request_id":"test-request"}`,
+		},
+		{
+			name:   "storage error",
+			caller: admin,
+			query:  "?format=csv",
+			body:   csvBody,
+			setupMock: func(m *MockUrlImporter) {
+				m.On("ImportUrls", storage.DefaultWorkspace, links, storage.ConflictFail, false).Return(storage.ImportReport{}, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to import urls","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockImporter := new(MockUrlImporter)
+			tt.setupMock(mockImporter)
+
+			req, err := http.NewRequest("POST", "/url/import"+tt.query, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+			req.Header.Set("Content-Type", tt.contentType)
+
+			rr := httptest.NewRecorder()
+			middleware.RequestID(New(slog.Default(), mockImporter, func(*http.Request) auth.Principal { return tt.caller }, defaultWorkspace)).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockImporter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestImportHandlerWithMemoryStorage(t *testing.T) {
+	repo := memory.New()
+	_, err := repo.SaveUrl(storage.DefaultWorkspace, "https://old.example.com", "a", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	handler := middleware.RequestID(New(slog.Default(), repo, func(*http.Request) auth.Principal { return auth.Unrestricted }, defaultWorkspace))
+
+	post := func(query, body string) string {
+		req, err := http.NewRequest("POST", "/url/import"+query, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr.Body.String()
+	}
+
+	body := "alias,url\na,https://example.com\nb,https://example.org\n"
+
+	assert.JSONEq(t,
+		`{"status":"OK","dry_run":true,"created":1,"updated":1,"skipped":0,"conflicts":["a"]}`,
+		post("?format=csv&on_conflict=overwrite&dry_run=1", body))
+
+	url, err := repo.GetUrl(storage.DefaultWorkspace, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "https://old.example.com", url)
+
+	assert.JSONEq(t,
+		`{"status":"OK","created":1,"updated":1,"skipped":0,"conflicts":["a"]}`,
+		post("?format=csv&on_conflict=overwrite", body))
+
+	url, err = repo.GetUrl(storage.DefaultWorkspace, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+
+	_, err = repo.GetUrl(storage.DefaultWorkspace, "b")
+	assert.NoError(t, err)
+}
@@ -4,51 +4,83 @@ import (
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/render"
 	"log/slog"
+	"net"
 	"net/http"
+	"time"
 
 	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
 	"github.com/popvaleks/url-shortener/internal/storage"
 )
 
-type UrlGetter interface {
-	GetUrl(alias string) (string, error)
+type UrlHitter interface {
+	HitUrl(workspace, alias string) (string, error)
+}
+
+type ClickRecorder interface {
+	// RecordClick must not block: it is called before the redirect is sent.
+	RecordClick(click storage.Click)
 }
 
 // New
 // @Summary Redirect by alias
-// @Description Redirects to the original URL associated with the provided alias
+// @Description Redirects to the original URL associated with the provided alias.
+// @Description Links of other workspaces are served under /w/{workspace}/{alias} or from their hosts.
 // @Tags url
 // @Param alias path string true "Alias for the URL to redirect"
 // @Success 302 "Redirects to the original URL"
-// @Failure 400 {object} resp.Response "Alias is missing"
-// @Failure 404 {object} resp.Response "URL not found for the provided alias"
-// @Failure 500 {object} resp.Response "Internal server error"
+// @Failure 400 {object} resp.Problem "Alias is missing"
+// @Failure 404 {object} resp.Problem "URL not found for the provided alias"
+// @Failure 410 {object} resp.Problem "Link has expired or used up its clicks"
+// @Failure 500 {object} resp.Problem "Internal server error"
 // @Router /{alias} [get]
-func New(log *slog.Logger, urlGetter UrlGetter) http.HandlerFunc {
+func New(
+	log *slog.Logger,
+	urlHitter UrlHitter,
+	clickRecorder ClickRecorder,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 
-		log = log.With(
+		ws := workspace(r)
+
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
 		)
 
 		alias := chi.URLParam(r, "alias")
 		if alias == "" {
 			log.Info("alias not allowed")
 
-			render.JSON(w, r, resp.Error("alias not allowed"))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "alias not allowed")
 
 			return
 		}
 
-		rUrl, err := urlGetter.GetUrl(alias)
+		rUrl, err := urlHitter.HitUrl(ws, alias)
 		if errors.Is(err, storage.ErrUrlNotFound) {
 			log.Info("url not found")
 
-			render.JSON(w, r, resp.Error("url not found"))
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeUrlNotFound, "url not found")
+
+			return
+		}
+
+		if errors.Is(err, storage.ErrUrlExpired) {
+			log.Info("url expired")
+
+			resp.RenderError(w, r, http.StatusGone, resp.CodeUrlExpired, "url expired")
+
+			return
+		}
+
+		if errors.Is(err, storage.ErrClickLimit) {
+			log.Info("click limit reached")
+
+			resp.RenderError(w, r, http.StatusGone, resp.CodeClickLimitReached, "click limit reached")
 
 			return
 		}
@@ -56,13 +88,33 @@ func New(log *slog.Logger, urlGetter UrlGetter) http.HandlerFunc {
 		if err != nil {
 			log.Error("internal server error")
 
-			render.JSON(w, r, resp.Error("internal server error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
 
 			return
 		}
 
 		log.Info("success get url", slog.String("res_url", rUrl))
 
+		clickRecorder.RecordClick(storage.Click{
+			Workspace: ws,
+			Alias:     alias,
+			At:        time.Now(),
+			Referrer:  r.Referer(),
+			UserAgent: r.UserAgent(),
+			IP:        clientIP(r),
+		})
+
 		http.Redirect(w, r, rUrl, http.StatusFound)
 	}
 }
+
+// clientIP returns the address set by middleware.RealIP, without the port
+// that RemoteAddr carries when no proxy headers were present.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -5,39 +5,54 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/http-server/middleware/workspace"
 	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-type MockUrlGetter struct {
+type MockUrlHitter struct {
 	mock.Mock
 }
 
-func (m *MockUrlGetter) GetUrl(alias string) (string, error) {
-	args := m.Called(alias)
+func (m *MockUrlHitter) HitUrl(workspace, alias string) (string, error) {
+	args := m.Called(workspace, alias)
 	return args.String(0), args.Error(1)
 }
 
+type MockClickRecorder struct {
+	mock.Mock
+}
+
+func (m *MockClickRecorder) RecordClick(click storage.Click) {
+	m.Called(click)
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
 func TestRedirectHandler(t *testing.T) {
 	log := slog.Default()
 
 	tests := []struct {
 		name           string
 		alias          string
-		setupMock      func(*MockUrlGetter) // Принимает конкретный мок
+		setupMock      func(*MockUrlHitter) // Принимает конкретный мок
 		expectedStatus int
 		expectedURL    string
 	}{
 		{
 			name:  "success",
 			alias: "example",
-			setupMock: func(m *MockUrlGetter) {
-				m.On("GetUrl", "example").Return("http://example.com", nil)
+			setupMock: func(m *MockUrlHitter) {
+				m.On("HitUrl", storage.DefaultWorkspace, "example").Return("http://example.com", nil)
 			},
 			expectedStatus: http.StatusFound,
 			expectedURL:    "http://example.com",
@@ -45,19 +60,37 @@ func TestRedirectHandler(t *testing.T) {
 		{
 			name:  "url not found",
 			alias: "notfound",
-			setupMock: func(m *MockUrlGetter) {
-				m.On("GetUrl", "notfound").Return("", storage.ErrUrlNotFound)
+			setupMock: func(m *MockUrlHitter) {
+				m.On("HitUrl", storage.DefaultWorkspace, "notfound").Return("", storage.ErrUrlNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedURL:    "",
+		},
+		{
+			name:  "url expired",
+			alias: "expired",
+			setupMock: func(m *MockUrlHitter) {
+				m.On("HitUrl", storage.DefaultWorkspace, "expired").Return("", storage.ErrUrlExpired)
+			},
+			expectedStatus: http.StatusGone,
+			expectedURL:    "",
+		},
+		{
+			name:  "click limit reached",
+			alias: "used",
+			setupMock: func(m *MockUrlHitter) {
+				m.On("HitUrl", storage.DefaultWorkspace, "used").Return("", storage.ErrClickLimit)
 			},
-			expectedStatus: http.StatusOK,
+			expectedStatus: http.StatusGone,
 			expectedURL:    "",
 		},
 		{
 			name:  "internal server error",
 			alias: "error",
-			setupMock: func(m *MockUrlGetter) {
-				m.On("GetUrl", "error").Return("", errors.New("internal error"))
+			setupMock: func(m *MockUrlHitter) {
+				m.On("HitUrl", storage.DefaultWorkspace, "error").Return("", errors.New("internal error"))
 			},
-			expectedStatus: http.StatusOK,
+			expectedStatus: http.StatusInternalServerError,
 			expectedURL:    "",
 		},
 	}
@@ -67,12 +100,19 @@ func TestRedirectHandler(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 			// Создаем новый мок для каждого теста
-			mockGetter := new(MockUrlGetter)
-			tt.setupMock(mockGetter) // Передаем конкретный мок
+			mockHitter := new(MockUrlHitter)
+			tt.setupMock(mockHitter) // Передаем конкретный мок
+
+			mockRecorder := new(MockClickRecorder)
+			if tt.expectedStatus == http.StatusFound {
+				mockRecorder.On("RecordClick", mock.MatchedBy(func(c storage.Click) bool {
+					return c.Alias == tt.alias
+				}))
+			}
 
 			r := chi.NewRouter()
 			r.Use(middleware.RequestID)
-			r.Get("/{alias}", New(log, mockGetter))
+			r.Get("/{alias}", New(log, mockHitter, mockRecorder, defaultWorkspace))
 
 			req, err := http.NewRequest("GET", "/"+tt.alias, nil)
 			assert.NoError(t, err)
@@ -89,7 +129,132 @@ func TestRedirectHandler(t *testing.T) {
 			}
 
 			// Проверяем ожидания только для текущего мока
-			mockGetter.AssertExpectations(t)
+			mockHitter.AssertExpectations(t)
+			mockRecorder.AssertExpectations(t)
 		})
 	}
 }
+
+func TestRedirectHandlerRecordsClick(t *testing.T) {
+	mockHitter := new(MockUrlHitter)
+	mockHitter.On("HitUrl", storage.DefaultWorkspace, "example").Return("http://example.com", nil)
+
+	mockRecorder := new(MockClickRecorder)
+	mockRecorder.On("RecordClick", mock.MatchedBy(func(c storage.Click) bool {
+		return c.Workspace == storage.DefaultWorkspace &&
+			c.Alias == "example" &&
+			c.IP == "203.0.113.7" &&
+			c.Referrer == "https://news.example/post" &&
+			c.UserAgent == "test-agent" &&
+			time.Since(c.At) < time.Minute
+	}))
+
+	r := chi.NewRouter()
+	r.Use(middleware.RealIP)
+	r.Get("/{alias}", New(slog.Default(), mockHitter, mockRecorder, defaultWorkspace))
+
+	req := httptest.NewRequest("GET", "/example", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	req.Header.Set("Referer", "https://news.example/post")
+	req.Header.Set("User-Agent", "test-agent")
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	mockRecorder.AssertExpectations(t)
+}
+
+func TestRedirectHandlerClickLimit(t *testing.T) {
+	const (
+		maxClicks = 5
+		requests  = 40
+	)
+
+	repo := memory.New()
+	limit := int64(maxClicks)
+
+	_, err := repo.SaveUrl(storage.DefaultWorkspace, "http://example.com", "invite", storage.SaveOptions{MaxClicks: &limit})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	mockRecorder := new(MockClickRecorder)
+	mockRecorder.On("RecordClick", mock.Anything)
+
+	r.Get("/{alias}", New(slog.Default(), repo, mockRecorder, defaultWorkspace))
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = make(map[int]int)
+	)
+
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest("GET", "/invite", nil))
+
+			mu.Lock()
+			codes[rr.Code]++
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, map[int]int{
+		http.StatusFound: maxClicks,
+		http.StatusGone:  requests - maxClicks,
+	}, codes)
+	mockRecorder.AssertNumberOfCalls(t, "RecordClick", maxClicks)
+}
+
+func TestRedirectHandlerWorkspaces(t *testing.T) {
+	repo := memory.New()
+	require.NoError(t, repo.CreateWorkspace(storage.Workspace{Name: "docs", CreatedAt: time.Now()}))
+
+	_, err := repo.SaveUrl(storage.DefaultWorkspace, "http://example.com", "x", storage.SaveOptions{})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl("docs", "http://docs.example.com", "x", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	mockRecorder := new(MockClickRecorder)
+	mockRecorder.On("RecordClick", mock.Anything)
+
+	resolver := workspace.NewResolver(map[string]string{"docs.example.com": "docs"})
+	handler := New(slog.Default(), repo, mockRecorder, resolver.Of)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", handler)
+	r.Get("/w/{workspace}/{alias}", handler)
+
+	tests := []struct {
+		host, path  string
+		expectedURL string
+	}{
+		{"short.example.com", "/x", "http://example.com"},
+		{"short.example.com", "/w/docs/x", "http://docs.example.com"},
+		{"docs.example.com", "/x", "http://docs.example.com"},
+		{"short.example.com", "/w/default/x", "http://example.com"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusFound, rr.Code, "%s%s", tt.host, tt.path)
+		assert.Equal(t, tt.expectedURL, rr.Header().Get("Location"), "%s%s", tt.host, tt.path)
+	}
+
+	// в чужом пространстве такой ссылки нет
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/w/blog/x", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
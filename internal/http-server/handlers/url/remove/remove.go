@@ -8,12 +8,14 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/popvaleks/url-shortener/internal/auth"
 	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
 	"github.com/popvaleks/url-shortener/internal/storage"
 )
 
 type UrlRemover interface {
-	DeleteUrl(alias string) error
+	GetOwner(workspace, alias string) (string, error)
+	DeleteUrl(workspace, alias string) error
 }
 
 // Request represents URL deletion request
@@ -31,37 +33,72 @@ type Response struct {
 
 // New
 // @Summary Delete URL by alias
-// @Description Deletes a short URL by its alias
+// @Description Deletes a short URL by its alias. Only the owner of the link or an admin can delete it.
 // @Tags url
 // @Param alias path string true "Alias of the URL to delete"
 // @Success 200 {object} Response
-// @Failure 400 {object} resp.Response "Alias is missing"
-// @Failure 404 {object} resp.Response "URL not found"
-// @Failure 500 {object} resp.Response "Internal server error"
+// @Failure 400 {object} resp.Problem "Alias is missing"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no write scope or the URL belongs to another owner"
+// @Failure 404 {object} resp.Problem "URL not found"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
 // @Router /{alias} [delete]
-func New(log *slog.Logger, urlRemover UrlRemover) http.HandlerFunc {
+func New(
+	log *slog.Logger,
+	urlRemover UrlRemover,
+	principal func(*http.Request) auth.Principal,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.remove.New"
 
-		log = log.With(
+		ws := workspace(r)
+
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
 		)
 
 		alias := chi.URLParam(r, "alias")
 		if alias == "" {
 			log.Info("alias not allowed")
 
-			render.JSON(w, r, resp.Error("alias not allowed"))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "alias not allowed")
+
+			return
+		}
+
+		owner, err := urlRemover.GetOwner(ws, alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found")
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeUrlNotFound, "url not found")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get owner", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+			return
+		}
+
+		if caller := principal(r); !caller.CanManage(owner) {
+			log.Info("url belongs to another owner", slog.String("owner", owner), slog.String("caller", caller.Name))
+
+			resp.RenderError(w, r, http.StatusForbidden, resp.CodeNotOwner, "url belongs to another owner")
 
 			return
 		}
 
-		err := urlRemover.DeleteUrl(alias)
+		err = urlRemover.DeleteUrl(ws, alias)
 		if errors.Is(err, storage.ErrUrlNotFound) {
 			log.Info("url not found")
 
-			render.JSON(w, r, resp.Error("url not found"))
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeUrlNotFound, "url not found")
 
 			return
 		}
@@ -69,7 +106,7 @@ func New(log *slog.Logger, urlRemover UrlRemover) http.HandlerFunc {
 		if err != nil {
 			log.Error("internal server error")
 
-			render.JSON(w, r, resp.Error("internal server error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
 
 			return
 		}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
 	"github.com/popvaleks/url-shortener/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -18,26 +19,68 @@ type MockUrlRemover struct {
 	mock.Mock
 }
 
-func (m *MockUrlRemover) DeleteUrl(alias string) error {
-	args := m.Called(alias)
+func (m *MockUrlRemover) GetOwner(workspace, alias string) (string, error) {
+	args := m.Called(workspace, alias)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUrlRemover) DeleteUrl(workspace, alias string) error {
+	args := m.Called(workspace, alias)
 	return args.Error(0)
 }
 
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
 func TestRemoveHandler(t *testing.T) {
 	log := slog.Default()
 
 	tests := []struct {
 		name           string
 		alias          string
+		caller         auth.Principal
 		setupMock      func(*MockUrlRemover) // Принимает конкретный мок
 		expectedStatus int
 		expectedBody   string
 	}{
 		{
-			name:  "success",
-			alias: "example",
+			name:   "success",
+			alias:  "example",
+			caller: auth.Principal{Name: "ops"},
 			setupMock: func(m *MockUrlRemover) {
-				m.On("DeleteUrl", "example").Return(nil)
+				m.On("GetOwner", storage.DefaultWorkspace, "example").Return("ops", nil)
+				m.On("DeleteUrl", storage.DefaultWorkspace, "example").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK"}`,
+		},
+		{
+			name:   "another owner",
+			alias:  "example",
+			caller: auth.Principal{Name: "dev"},
+			setupMock: func(m *MockUrlRemover) {
+				m.On("GetOwner", storage.DefaultWorkspace, "example").Return("ops", nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"url belongs to another owner","code":"not_owner","request_id":"test-request"}`,
+		},
+		{
+			// ссылки без владельца удаляет только админ
+			name:   "no owner",
+			alias:  "example",
+			caller: auth.Principal{Name: "dev"},
+			setupMock: func(m *MockUrlRemover) {
+				m.On("GetOwner", storage.DefaultWorkspace, "example").Return("", nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"url belongs to another owner","code":"not_owner","request_id":"test-request"}`,
+		},
+		{
+			name:   "admin",
+			alias:  "example",
+			caller: auth.Principal{Name: "root", Admin: true},
+			setupMock: func(m *MockUrlRemover) {
+				m.On("GetOwner", storage.DefaultWorkspace, "example").Return("ops", nil)
+				m.On("DeleteUrl", storage.DefaultWorkspace, "example").Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"status":"OK"}`,
@@ -46,19 +89,21 @@ func TestRemoveHandler(t *testing.T) {
 			name:  "url not found",
 			alias: "notfound",
 			setupMock: func(m *MockUrlRemover) {
-				m.On("DeleteUrl", "notfound").Return(storage.ErrUrlNotFound)
+				m.On("GetOwner", storage.DefaultWorkspace, "notfound").Return("", storage.ErrUrlNotFound)
 			},
-			expectedStatus: http.StatusOK,
-			expectedBody:   `{"status":"Error","error":"url not found"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"url not found","code":"url_not_found","request_id":"test-request"}`,
 		},
 		{
-			name:  "internal server error",
-			alias: "error",
+			name:   "internal server error",
+			alias:  "error",
+			caller: auth.Unrestricted,
 			setupMock: func(m *MockUrlRemover) {
-				m.On("DeleteUrl", "error").Return(errors.New("internal error"))
+				m.On("GetOwner", storage.DefaultWorkspace, "error").Return("", nil)
+				m.On("DeleteUrl", storage.DefaultWorkspace, "error").Return(errors.New("internal error"))
 			},
-			expectedStatus: http.StatusOK,
-			expectedBody:   `{"status":"Error","error":"internal server error"}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error","request_id":"test-request"}`,
 		},
 	}
 
@@ -72,10 +117,11 @@ func TestRemoveHandler(t *testing.T) {
 
 			r := chi.NewRouter()
 			r.Use(middleware.RequestID)
-			r.Delete("/{alias}", New(log, mockRemover))
+			r.Delete("/{alias}", New(log, mockRemover, func(*http.Request) auth.Principal { return tt.caller }, defaultWorkspace))
 
 			req, err := http.NewRequest("DELETE", "/"+tt.alias, nil)
 			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
 
 			rr := httptest.NewRecorder()
 			r.ServeHTTP(rr, req)
@@ -0,0 +1,76 @@
+package removeByTag
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+)
+
+type TagDeleter interface {
+	DeleteByTag(workspace, tag string) (int64, error)
+}
+
+// Response represents bulk deletion response
+// @Description Success response with the number of deleted links
+// swagger:model
+type Response struct {
+	resp.Response
+	Deleted int64 `json:"deleted"`
+}
+
+// New
+// @Summary Delete URLs by tag
+// @Description Deletes every link of the workspace labelled with the tag
+// @Tags url
+// @Produce  json
+// @Param tag query string true "Tag of the links to delete"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Tag is missing"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no write scope or no admin role in the workspace"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /url [delete]
+func New(log *slog.Logger, tagDeleter TagDeleter, workspace func(*http.Request) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.removeByTag.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		// a bulk delete without a tag is always a mistake, never "every link"
+		tag := strings.TrimSpace(r.URL.Query().Get("tag"))
+		if tag == "" {
+			log.Info("tag is required")
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidQuery, "tag is required")
+
+			return
+		}
+
+		deleted, err := tagDeleter.DeleteByTag(ws, tag)
+		if err != nil {
+			log.Error("internal server error", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+			return
+		}
+
+		log.Info("success delete by tag", slog.String("tag", tag), slog.Int64("deleted", deleted))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Deleted:  deleted,
+		})
+	}
+}
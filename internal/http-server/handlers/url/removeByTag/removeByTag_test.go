@@ -0,0 +1,98 @@
+package removeByTag
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTagDeleter struct {
+	mock.Mock
+}
+
+func (m *MockTagDeleter) DeleteByTag(workspace, tag string) (int64, error) {
+	args := m.Called(workspace, tag)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
+func TestRemoveByTagHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(*MockTagDeleter)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "success",
+			query: "?tag=spring",
+			setupMock: func(m *MockTagDeleter) {
+				m.On("DeleteByTag", storage.DefaultWorkspace, "spring").Return(int64(3), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","deleted":3}`,
+		},
+		{
+			name:  "nothing to delete",
+			query: "?tag=winter",
+			setupMock: func(m *MockTagDeleter) {
+				m.On("DeleteByTag", storage.DefaultWorkspace, "winter").Return(int64(0), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","deleted":0}`,
+		},
+		{
+			// без тега ничего не удаляется
+			name:           "tag is missing",
+			query:          "?tags=spring",
+			setupMock:      func(m *MockTagDeleter) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"tag is required","code":"invalid_query","request_id":"test-request"}`,
+		},
+		{
+			name:  "internal server error",
+			query: "?tag=spring",
+			setupMock: func(m *MockTagDeleter) {
+				m.On("DeleteByTag", storage.DefaultWorkspace, "spring").Return(int64(0), errors.New("internal error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockDeleter := new(MockTagDeleter)
+			tt.setupMock(mockDeleter)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Delete("/url", New(slog.Default(), mockDeleter, defaultWorkspace))
+
+			req, err := http.NewRequest("DELETE", "/url"+tt.query, nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockDeleter.AssertExpectations(t)
+		})
+	}
+}
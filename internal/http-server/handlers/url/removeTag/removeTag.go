@@ -0,0 +1,125 @@
+package removeTag
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+
+	"github.com/popvaleks/url-shortener/internal/auth"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type TagRemover interface {
+	GetOwner(workspace, alias string) (string, error)
+	RemoveTag(workspace, alias, tag string) error
+}
+
+// Response represents tag removal response
+// @Description Success response for tag removal
+// swagger:model
+type Response struct {
+	resp.Response
+}
+
+// New
+// @Summary Remove tag from URL
+// @Description Removes a tag from the link. Only the owner of the link or an admin can do it.
+// @Tags url
+// @Produce  json
+// @Param alias path string true "Alias of the URL"
+// @Param tag path string true "Tag to remove"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Alias or tag is missing"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no write scope or the URL belongs to another owner"
+// @Failure 404 {object} resp.Problem "URL or tag not found"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /url/{alias}/tags/{tag} [delete]
+func New(
+	log *slog.Logger,
+	tagRemover TagRemover,
+	principal func(*http.Request) auth.Principal,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.removeTag.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		tag := chi.URLParam(r, "tag")
+		if alias == "" || tag == "" {
+			log.Info("alias or tag not allowed")
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "alias or tag not allowed")
+
+			return
+		}
+
+		owner, err := tagRemover.GetOwner(ws, alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found")
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeUrlNotFound, "url not found")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get owner", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+			return
+		}
+
+		if caller := principal(r); !caller.CanManage(owner) {
+			log.Info("url belongs to another owner", slog.String("owner", owner), slog.String("caller", caller.Name))
+
+			resp.RenderError(w, r, http.StatusForbidden, resp.CodeNotOwner, "url belongs to another owner")
+
+			return
+		}
+
+		err = tagRemover.RemoveTag(ws, alias, tag)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found")
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeUrlNotFound, "url not found")
+
+			return
+		}
+
+		if errors.Is(err, storage.ErrTagNotFound) {
+			log.Info("tag not found", slog.String("tag", tag))
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeTagNotFound, "tag not found")
+
+			return
+		}
+
+		if err != nil {
+			log.Error("internal server error", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+			return
+		}
+
+		log.Info("success remove tag", slog.String("tag", tag))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package removeTag
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTagRemover struct {
+	mock.Mock
+}
+
+func (m *MockTagRemover) GetOwner(workspace, alias string) (string, error) {
+	args := m.Called(workspace, alias)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTagRemover) RemoveTag(workspace, alias, tag string) error {
+	args := m.Called(workspace, alias, tag)
+	return args.Error(0)
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
+func TestRemoveTagHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		alias          string
+		tag            string
+		caller         auth.Principal
+		setupMock      func(*MockTagRemover)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "success",
+			alias:  "example",
+			tag:    "spring",
+			caller: auth.Principal{Name: "ops"},
+			setupMock: func(m *MockTagRemover) {
+				m.On("GetOwner", storage.DefaultWorkspace, "example").Return("ops", nil)
+				m.On("RemoveTag", storage.DefaultWorkspace, "example", "spring").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK"}`,
+		},
+		{
+			name:  "url not found",
+			alias: "notfound",
+			tag:   "spring",
+			setupMock: func(m *MockTagRemover) {
+				m.On("GetOwner", storage.DefaultWorkspace, "notfound").Return("", storage.ErrUrlNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"url not found","code":"url_not_found","request_id":"test-request"}`,
+		},
+		{
+			name:   "another owner",
+			alias:  "example",
+			tag:    "spring",
+			caller: auth.Principal{Name: "dev"},
+			setupMock: func(m *MockTagRemover) {
+				m.On("GetOwner", storage.DefaultWorkspace, "example").Return("ops", nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"url belongs to another owner","code":"not_owner","request_id":"test-request"}`,
+		},
+		{
+			name:   "tag not found",
+			alias:  "example",
+			tag:    "winter",
+			caller: auth.Principal{Name: "root", Admin: true},
+			setupMock: func(m *MockTagRemover) {
+				m.On("GetOwner", storage.DefaultWorkspace, "example").Return("ops", nil)
+				m.On("RemoveTag", storage.DefaultWorkspace, "example", "winter").Return(storage.ErrTagNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"tag not found","code":"tag_not_found","request_id":"test-request"}`,
+		},
+		{
+			name:   "internal server error",
+			alias:  "error",
+			tag:    "spring",
+			caller: auth.Unrestricted,
+			setupMock: func(m *MockTagRemover) {
+				m.On("GetOwner", storage.DefaultWorkspace, "error").Return("", nil)
+				m.On("RemoveTag", storage.DefaultWorkspace, "error", "spring").Return(errors.New("internal error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockRemover := new(MockTagRemover)
+			tt.setupMock(mockRemover)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Delete("/url/{alias}/tags/{tag}", New(slog.Default(), mockRemover, func(*http.Request) auth.Principal { return tt.caller }, defaultWorkspace))
+
+			req, err := http.NewRequest("DELETE", "/url/"+tt.alias+"/tags/"+tt.tag, nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockRemover.AssertExpectations(t)
+		})
+	}
+}
@@ -6,22 +6,48 @@ import (
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/popvaleks/url-shortener/internal/auth"
+	aliasgen "github.com/popvaleks/url-shortener/internal/lib/alias"
 	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
-	rand "github.com/popvaleks/url-shortener/internal/lib/utils/random"
 	"github.com/popvaleks/url-shortener/internal/storage"
 )
 
 type UrlSaver interface {
-	SaveUrl(inputUrl string, alias string) (int64, error)
+	SaveUrl(workspace, inputUrl string, alias string, opts storage.SaveOptions) (int64, error)
+	FindAlias(workspace, target, owner string) (string, error)
+	AddTags(workspace, alias string, tags []string) ([]string, error)
+}
+
+// AliasGenerator makes aliases for requests without one.
+type AliasGenerator interface {
+	// Save calls save with new aliases until one is not taken.
+	Save(save func(alias string) error) (string, error)
 }
 
 // Request represents URL save request
-// @Description Request to create a short URL
+// @Description Request to create a short URL.
+// @Description Use either expires_at (RFC 3339) or ttl (e.g. "90m", "168h", "7d") for a temporary link
+// @Description and max_clicks for a link that stops resolving after that many redirects.
+// @Description An alias is made up unless given. A given alias must fit the alias policy of the server:
+// @Description length bounds, allowed characters, no reserved or profane words.
+// @Description Tags are case-insensitive and must not contain "/", "?", "#" or "%".
+// @Description With dedup a request without alias, expiration and click limit returns
+// @Description the generated alias of an earlier link to the same url, dedup overrides
+// @Description the server default.
 type Request struct {
-	Url   string `json:"url" validate:"required,url"`
-	Alias string `json:"alias,omitempty"`
+	Url       string     `json:"url" validate:"required,url"`
+	Alias     string     `json:"alias,omitempty" validate:"omitempty,alias"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" validate:"omitempty,excluded_with=TTL"`
+	TTL       string     `json:"ttl,omitempty" example:"168h"`
+	MaxClicks *int64     `json:"max_clicks,omitempty" validate:"omitempty,min=1"`
+	Tags      []string   `json:"tags,omitempty" validate:"omitempty,max=20,dive,min=1,max=50,excludesall=/?#%"`
+	Dedup     *bool      `json:"dedup,omitempty"`
 }
 
 // Response represents URL save response
@@ -29,28 +55,55 @@ type Request struct {
 // swagger:model
 type Response struct {
 	resp.Response
-	Alias string `json:"alias,omitempty"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxClicks *int64     `json:"max_clicks,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	// Existing is set when the alias of an earlier link is returned
+	Existing bool `json:"existing,omitempty"`
 }
 
-const aliasLength = 8
+var (
+	errInvalidTTL  = errors.New("ttl must be a positive duration like 90m, 168h or 7d")
+	errExpiresPast = errors.New("expires_at must be in the future")
+)
 
 // New
 // @Summary Save URL
-// @Description Creates a short alias for the provided URL
+// @Description Creates a short alias for the provided URL or, with deduplication,
+// @Description returns the generated alias of an earlier link of the caller to it.
+// @Description The link belongs to the caller.
 // @Tags url
 // @Accept  json
 // @Produce  json
 // @Param input body Request true "URL shortening request data"
 // @Success 200 {object} Response
-// @Failure 400 {object} resp.Response
+// @Failure 400 {object} resp.Problem "Invalid request or validation error"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no write scope"
+// @Failure 409 {object} resp.Problem "Alias already exists"
+// @Failure 500 {object} resp.Problem
+// @Failure 503 {object} resp.Problem "No free alias found, retry later"
+// @Security ApiKeyAuth
 // @Router /url [post]
-func New(log *slog.Logger, urlSaver UrlSaver) http.HandlerFunc {
+func New(
+	log *slog.Logger,
+	urlSaver UrlSaver,
+	validate *validator.Validate,
+	aliases AliasGenerator,
+	dedup bool,
+	principal func(*http.Request) auth.Principal,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handler.url.save.New"
 
-		log = log.With(
+		ws := workspace(r)
+
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
 		)
 
 		var req Request
@@ -59,41 +112,95 @@ func New(log *slog.Logger, urlSaver UrlSaver) http.HandlerFunc {
 		if err != nil {
 			log.Error("failed to decode request", slog.String("error", err.Error()))
 
-			render.JSON(w, r, resp.Error("failed to decode request"))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request")
 
 			return
 		}
 
 		log.Info("success decode req", slog.Any("request", req)) // can remove, debug only
 
-		if err := validator.New().Struct(req); err != nil {
+		if err := validate.Struct(req); err != nil {
 			var validatorErr validator.ValidationErrors
 			errors.As(err, &validatorErr)
 
 			log.Error("failed to validate request", slog.String("error", err.Error()))
 
-			render.JSON(w, r, resp.ValidationError(validatorErr))
+			resp.RenderValidationError(w, r, validatorErr)
+
+			return
+		}
+
+		opts, err := Options(req, time.Now())
+		if err != nil {
+			log.Info("invalid expiration", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, err.Error())
 
 			return
 		}
+		opts.Owner = principal(r).Name
 
 		alias := req.Alias
+
 		if alias == "" {
-			alias = rand.NewRandomString(aliasLength)
+			opts.Generated = true
+
+			reuseExisting := dedup
+			if req.Dedup != nil {
+				reuseExisting = *req.Dedup
+			}
+
+			if reuseExisting && opts.ExpiresAt == nil && opts.MaxClicks == nil {
+				response, err := reuse(urlSaver, ws, req.Url, opts)
+				if err == nil {
+					log.Info("success reuse url", slog.String("alias", response.Alias))
+
+					render.JSON(w, r, response)
+
+					return
+				}
+
+				if !errors.Is(err, storage.ErrUrlNotFound) {
+					log.Error("failed to find url", slog.String("error", err.Error()))
+
+					resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to save url")
+
+					return
+				}
+			}
+		}
+
+		var id int64
+
+		if opts.Generated {
+			// a generated alias taken by another link is replaced by a new one
+			alias, err = aliases.Save(func(candidate string) error {
+				var saveErr error
+				id, saveErr = urlSaver.SaveUrl(ws, req.Url, candidate, opts)
+				return saveErr
+			})
+		} else {
+			id, err = urlSaver.SaveUrl(ws, req.Url, alias, opts)
 		}
 
-		id, err := urlSaver.SaveUrl(req.Url, alias)
+		if errors.Is(err, aliasgen.ErrExhausted) {
+			log.Error("no free alias", slog.String("url", req.Url))
+
+			resp.RenderError(w, r, http.StatusServiceUnavailable, resp.CodeAliasExhausted, "failed to generate alias")
+
+			return
+		}
 		if errors.Is(err, storage.ErrUrlExists) {
 			log.Info("url already exists", slog.String("url", req.Url))
 
-			render.JSON(w, r, resp.Error("url already exists"))
+			resp.RenderError(w, r, http.StatusConflict, resp.CodeAliasExists, "url already exists")
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to save url", slog.String("error", err.Error()))
 
-			render.JSON(w, r, resp.Error("failed to save url"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to save url")
 
 			return
 		}
@@ -101,8 +208,82 @@ func New(log *slog.Logger, urlSaver UrlSaver) http.HandlerFunc {
 		log.Info("success save url", slog.Int64("id", id))
 
 		render.JSON(w, r, Response{
-			Response: resp.OK(),
-			Alias:    alias,
+			Response:  resp.OK(),
+			Alias:     alias,
+			ExpiresAt: opts.ExpiresAt,
+			MaxClicks: opts.MaxClicks,
+			Tags:      opts.Tags,
 		})
 	}
 }
+
+// reuse returns the earlier link of the owner to target labelled with the requested
+// tags. Two concurrent requests may still both create a link.
+func reuse(urlSaver UrlSaver, ws, target string, opts storage.SaveOptions) (Response, error) {
+	alias, err := urlSaver.FindAlias(ws, target, opts.Owner)
+	if err != nil {
+		return Response{}, err
+	}
+
+	response := Response{Response: resp.OK(), Alias: alias, Existing: true}
+
+	if len(opts.Tags) > 0 {
+		// the link may be deleted in between, then a new one is made
+		if response.Tags, err = urlSaver.AddTags(ws, alias, opts.Tags); err != nil {
+			return Response{}, err
+		}
+	}
+
+	return response, nil
+}
+
+// Options resolves the expiration of req relative to now.
+func Options(req Request, now time.Time) (storage.SaveOptions, error) {
+	opts := storage.SaveOptions{
+		MaxClicks: req.MaxClicks,
+	}
+
+	if len(req.Tags) > 0 {
+		opts.Tags = storage.NormalizeTags(req.Tags)
+	}
+
+	if req.TTL != "" {
+		ttl, err := parseTTL(req.TTL)
+		if err != nil || ttl <= 0 {
+			return opts, errInvalidTTL
+		}
+
+		expiresAt := now.Add(ttl)
+		opts.ExpiresAt = &expiresAt
+	}
+
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return opts, errExpiresPast
+		}
+
+		opts.ExpiresAt = req.ExpiresAt
+	}
+
+	return opts, nil
+}
+
+// maxTTLDays is the longest ttl in days a time.Duration can hold.
+const maxTTLDays = math.MaxInt64 / int64(24*time.Hour)
+
+// parseTTL is time.ParseDuration that also accepts whole days, e.g. "7d".
+func parseTTL(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseInt(days, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if n > maxTTLDays {
+			return 0, errors.New("ttl is too long")
+		}
+
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(s)
+}
@@ -1,27 +1,69 @@
 package save
 
 import (
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	aliasgen "github.com/popvaleks/url-shortener/internal/lib/alias"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
 	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockUrlSaver struct {
 	mock.Mock
 }
 
-func (m *MockUrlSaver) SaveUrl(inputUrl string, alias string) (int64, error) {
-	args := m.Called(inputUrl, alias)
+func (m *MockUrlSaver) SaveUrl(workspace, inputUrl string, alias string, opts storage.SaveOptions) (int64, error) {
+	args := m.Called(workspace, inputUrl, alias, opts)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockUrlSaver) FindAlias(workspace, target, owner string) (string, error) {
+	args := m.Called(workspace, target, owner)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUrlSaver) AddTags(workspace, alias string, tags []string) ([]string, error) {
+	args := m.Called(workspace, alias, tags)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func testValidator() *validator.Validate {
+	policy := aliasgen.NewPolicy(aliasgen.PolicyOptions{MinLength: 3, MaxLength: 32, Charset: aliasgen.DefaultCharset})
+	policy.Reserve("url")
+
+	v := resp.NewValidator()
+	if err := policy.Register(v); err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+func testAliases() *aliasgen.Source {
+	gen, err := aliasgen.NewRandom(aliasgen.DefaultAlphabet)
+	if err != nil {
+		panic(err)
+	}
+
+	return aliasgen.NewSource(gen, aliasgen.Options{Length: 8, MaxAttempts: 3, GrowAfter: 2})
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
 func TestSaveHandler(t *testing.T) {
 	log := slog.Default()
 
@@ -36,7 +78,7 @@ func TestSaveHandler(t *testing.T) {
 			name:        "success with alias",
 			requestBody: `{"url": "http://example.com", "alias": "example"}`,
 			setupMock: func(m *MockUrlSaver) {
-				m.On("SaveUrl", "http://example.com", "example").Return(int64(1), nil)
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", "example", storage.SaveOptions{}).Return(int64(1), nil)
 			},
 			expectedCode: http.StatusOK,
 			expectedBody: `{"status":"OK","alias":"example"}`,
@@ -45,7 +87,9 @@ func TestSaveHandler(t *testing.T) {
 			name:        "success without alias",
 			requestBody: `{"url": "http://example.com"}`,
 			setupMock: func(m *MockUrlSaver) {
-				m.On("SaveUrl", "http://example.com", mock.AnythingOfType("string")).Return(int64(1), nil)
+				// сгенерированные алиасы хранят нормализованный URL
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", mock.AnythingOfType("string"), storage.SaveOptions{Generated: true}).
+					Return(int64(1), nil)
 			},
 			expectedCode: http.StatusOK,
 			expectedBody: `{"status":"OK","alias":`,
@@ -54,17 +98,191 @@ func TestSaveHandler(t *testing.T) {
 			name:        "url already exists",
 			requestBody: `{"url": "http://example.com", "alias": "example"}`,
 			setupMock: func(m *MockUrlSaver) {
-				m.On("SaveUrl", "http://example.com", "example").Return(int64(0), storage.ErrUrlExists)
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", "example", storage.SaveOptions{}).Return(int64(0), storage.ErrUrlExists)
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"type":"about:blank","title":"Conflict","status":409,"detail":"url already exists","code":"alias_exists","request_id":"test-request"}`,
+		},
+		{
+			name:        "success with ttl",
+			requestBody: `{"url": "http://example.com", "alias": "example", "ttl": "7d"}`,
+			setupMock: func(m *MockUrlSaver) {
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", "example", mock.MatchedBy(func(opts storage.SaveOptions) bool {
+					return opts.ExpiresAt != nil && time.Until(*opts.ExpiresAt) > 167*time.Hour
+				})).Return(int64(1), nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":"example","expires_at":`,
+		},
+		{
+			name:        "success with expires_at",
+			requestBody: `{"url": "http://example.com", "alias": "example", "expires_at": "2100-01-01T00:00:00Z"}`,
+			setupMock: func(m *MockUrlSaver) {
+				expiresAt := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", "example", storage.SaveOptions{ExpiresAt: &expiresAt}).
+					Return(int64(1), nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":"example","expires_at":"2100-01-01T00:00:00Z"}`,
+		},
+		{
+			name:        "success with max_clicks",
+			requestBody: `{"url": "http://example.com", "alias": "invite", "max_clicks": 1}`,
+			setupMock: func(m *MockUrlSaver) {
+				maxClicks := int64(1)
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", "invite", storage.SaveOptions{MaxClicks: &maxClicks}).
+					Return(int64(1), nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":"invite","max_clicks":1}`,
+		},
+		{
+			name:        "success with tags",
+			requestBody: `{"url": "http://example.com", "alias": "example", "tags": ["Spring", "team-a", "spring"]}`,
+			setupMock: func(m *MockUrlSaver) {
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", "example", storage.SaveOptions{Tags: []string{"spring", "team-a"}}).
+					Return(int64(1), nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":"example","tags":["spring","team-a"]}`,
+		},
+		{
+			name:        "generated alias collision is retried",
+			requestBody: `{"url": "http://example.com"}`,
+			setupMock: func(m *MockUrlSaver) {
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", mock.AnythingOfType("string"), storage.SaveOptions{Generated: true}).
+					Return(int64(0), storage.ErrUrlExists).Once()
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", mock.AnythingOfType("string"), storage.SaveOptions{Generated: true}).
+					Return(int64(2), nil).Once()
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":`,
+		},
+		{
+			name:        "no free alias",
+			requestBody: `{"url": "http://example.com"}`,
+			setupMock: func(m *MockUrlSaver) {
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", mock.AnythingOfType("string"), storage.SaveOptions{Generated: true}).
+					Return(int64(0), storage.ErrUrlExists).Times(3)
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"failed to generate alias","code":"alias_exhausted","request_id":"test-request"}`,
+		},
+		{
+			name:        "dedup returns existing alias",
+			requestBody: `{"url": "HTTP://Example.com:80", "dedup": true}`,
+			setupMock: func(m *MockUrlSaver) {
+				// url сравнивается хранилищем в нормализованном виде
+				m.On("FindAlias", storage.DefaultWorkspace, "HTTP://Example.com:80", "").Return("a1b2c3d4", nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":"a1b2c3d4","existing":true}`,
+		},
+		{
+			name:        "dedup labels existing link",
+			requestBody: `{"url": "http://example.com", "dedup": true, "tags": ["Promo"]}`,
+			setupMock: func(m *MockUrlSaver) {
+				m.On("FindAlias", storage.DefaultWorkspace, "http://example.com", "").Return("a1b2c3d4", nil)
+				m.On("AddTags", storage.DefaultWorkspace, "a1b2c3d4", []string{"promo"}).Return([]string{"old", "promo"}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":"a1b2c3d4","tags":["old","promo"],"existing":true}`,
+		},
+		{
+			name:        "dedup without earlier link",
+			requestBody: `{"url": "http://example.com", "dedup": true}`,
+			setupMock: func(m *MockUrlSaver) {
+				m.On("FindAlias", storage.DefaultWorkspace, "http://example.com", "").Return("", storage.ErrUrlNotFound)
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", mock.AnythingOfType("string"), storage.SaveOptions{Generated: true}).
+					Return(int64(1), nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":`,
+		},
+		{
+			name:        "dedup skips temporary links",
+			requestBody: `{"url": "http://example.com", "dedup": true, "max_clicks": 1}`,
+			setupMock: func(m *MockUrlSaver) {
+				maxClicks := int64(1)
+				m.On("SaveUrl", storage.DefaultWorkspace, "http://example.com", mock.AnythingOfType("string"),
+					storage.SaveOptions{MaxClicks: &maxClicks, Generated: true}).Return(int64(1), nil)
 			},
 			expectedCode: http.StatusOK,
-			expectedBody: `{"status":"Error","error":"url already exists"}`,
+			expectedBody: `{"status":"OK","alias":`,
+		},
+		{
+			name:        "dedup lookup error",
+			requestBody: `{"url": "http://example.com", "dedup": true}`,
+			setupMock: func(m *MockUrlSaver) {
+				m.On("FindAlias", storage.DefaultWorkspace, "http://example.com", "").Return("", errors.New("database error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to save url","code":"internal_error","request_id":"test-request"}`,
+		},
+		{
+			name:         "invalid tag",
+			requestBody:  `{"url": "http://example.com", "tags": ["spring/2025"]}`,
+			setupMock:    func(m *MockUrlSaver) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Tags[0] is not valid","code":"validation_failed","request_id":"test-request","errors":[{"field":"tags[0]","rule":"excludesall","message":"must not contain any of the characters /?#%","param":"/?#%"}]}`,
+		},
+		{
+			name:         "invalid max_clicks",
+			requestBody:  `{"url": "http://example.com", "max_clicks": 0}`,
+			setupMock:    func(m *MockUrlSaver) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field MaxClicks is not valid","code":"validation_failed","request_id":"test-request","errors":[{"field":"max_clicks","rule":"min","message":"must be at least 1","param":"1"}]}`,
+		},
+		{
+			name:         "expires_at in the past",
+			requestBody:  `{"url": "http://example.com", "expires_at": "2000-01-01T00:00:00Z"}`,
+			setupMock:    func(m *MockUrlSaver) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"expires_at must be in the future","code":"validation_failed","request_id":"test-request"}`,
+		},
+		{
+			name:         "invalid ttl",
+			requestBody:  `{"url": "http://example.com", "ttl": "-5m"}`,
+			setupMock:    func(m *MockUrlSaver) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"ttl must be a positive duration like 90m, 168h or 7d","code":"validation_failed","request_id":"test-request"}`,
+		},
+		{
+			// без проверки 213505 дней переполняют time.Duration и превращаются в сутки
+			name:         "ttl in days overflows",
+			requestBody:  `{"url": "http://example.com", "ttl": "213505d"}`,
+			setupMock:    func(m *MockUrlSaver) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"ttl must be a positive duration like 90m, 168h or 7d","code":"validation_failed","request_id":"test-request"}`,
+		},
+		{
+			name:         "both ttl and expires_at",
+			requestBody:  `{"url": "http://example.com", "ttl": "1h", "expires_at": "2100-01-01T00:00:00Z"}`,
+			setupMock:    func(m *MockUrlSaver) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field ExpiresAt is not valid","code":"validation_failed","request_id":"test-request","errors":[{"field":"expires_at","rule":"excluded_with","message":"must not be set together with ttl","param":"TTL"}]}`,
 		},
 		{
 			name:         "invalid url",
 			requestBody:  `{"url": "invalid-url"}`,
 			setupMock:    func(m *MockUrlSaver) {},
-			expectedCode: http.StatusOK,
-			expectedBody: `{"status":"Error","error":"field Url is not a valid URL"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Url is not a valid URL","code":"validation_failed","request_id":"test-request","errors":[{"field":"url","rule":"url","message":"must be a valid URL"}]}`,
+		},
+		{
+			// алиас совпадает с маршрутом /url
+			name:         "reserved alias",
+			requestBody:  `{"url": "http://example.com", "alias": "Url"}`,
+			setupMock:    func(m *MockUrlSaver) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Alias is not an allowed alias","code":"validation_failed","request_id":"test-request","errors":[{"field":"alias","rule":"alias_reserved","message":"is a reserved alias"}]}`,
+		},
+		{
+			name:         "alias with slash",
+			requestBody:  `{"url": "http://example.com", "alias": "a/b/c"}`,
+			setupMock:    func(m *MockUrlSaver) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Alias is not an allowed alias","code":"validation_failed","request_id":"test-request","errors":[{"field":"alias","rule":"alias_charset","message":"has characters that are not allowed in an alias"}]}`,
 		},
 	}
 
@@ -78,8 +296,9 @@ func TestSaveHandler(t *testing.T) {
 
 			req, err := http.NewRequest("POST", "/url", strings.NewReader(tt.requestBody))
 			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
 
-			handler := middleware.RequestID(http.HandlerFunc(New(log, mockSaver)))
+			handler := middleware.RequestID(http.HandlerFunc(New(log, mockSaver, testValidator(), testAliases(), false, unrestricted, defaultWorkspace)))
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
@@ -93,3 +312,85 @@ func TestSaveHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveHandlerWithMemoryStorage(t *testing.T) {
+	repo := memory.New()
+	handler := middleware.RequestID(New(slog.Default(), repo, testValidator(), testAliases(), false, unrestricted, defaultWorkspace))
+
+	post := func(body string) string {
+		req, err := http.NewRequest("POST", "/url", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr.Body.String()
+	}
+
+	assert.JSONEq(t, `{"status":"OK","alias":"example"}`, post(`{"url": "http://example.com", "alias": "example"}`))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Conflict","status":409,"detail":"url already exists","code":"alias_exists","request_id":"test-request"}`, post(`{"url": "http://google.com", "alias": "example"}`))
+
+	url, err := repo.GetUrl(storage.DefaultWorkspace, "example")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com", url)
+}
+
+func TestSaveHandlerDedup(t *testing.T) {
+	repo := memory.New()
+	// дедупликация включена глобально
+	handler := middleware.RequestID(New(slog.Default(), repo, testValidator(), testAliases(), true, ownerHeader, defaultWorkspace))
+
+	postAs := func(owner, body string) Response {
+		req, err := http.NewRequest("POST", "/url", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set(middleware.RequestIDHeader, "test-request")
+		req.Header.Set("X-Test-Owner", owner)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var response Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Equal(t, "OK", response.Status, rr.Body.String())
+
+		return response
+	}
+	post := func(body string) Response { return postAs("ops", body) }
+
+	first := post(`{"url": "https://Example.com"}`)
+	assert.False(t, first.Existing)
+
+	again := post(`{"url": "https://example.com/"}`)
+	assert.True(t, again.Existing)
+	assert.Equal(t, first.Alias, again.Alias)
+
+	optedOut := post(`{"url": "https://example.com", "dedup": false}`)
+	assert.NotEqual(t, first.Alias, optedOut.Alias)
+
+	// ссылка с выбранным алиасом не переиспользуется
+	post(`{"url": "https://example.org", "alias": "chosen"}`)
+	assert.NotEqual(t, "chosen", post(`{"url": "https://example.org"}`).Alias)
+
+	url, err := repo.GetUrl(storage.DefaultWorkspace, first.Alias)
+	require.NoError(t, err)
+	assert.Equal(t, "https://Example.com", url, "the url is stored as it was sent")
+
+	owner, err := repo.GetOwner(storage.DefaultWorkspace, first.Alias)
+	require.NoError(t, err)
+	assert.Equal(t, "ops", owner)
+
+	// ссылки другого владельца не переиспользуются
+	other := postAs("dev", `{"url": "https://example.com/"}`)
+	assert.False(t, other.Existing)
+	assert.NotEqual(t, first.Alias, other.Alias)
+}
+
+func unrestricted(*http.Request) auth.Principal {
+	return auth.Unrestricted
+}
+
+// ownerHeader lets a test act for the owner named in X-Test-Owner.
+func ownerHeader(r *http.Request) auth.Principal {
+	return auth.Principal{Name: r.Header.Get("X-Test-Owner")}
+}
@@ -0,0 +1,303 @@
+package saveBatch
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/http-server/handlers/url/save"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+const (
+	ModeAtomic     = "atomic"
+	ModeBestEffort = "best_effort"
+)
+
+type UrlBatchSaver interface {
+	SaveUrls(workspace string, links []storage.NewLink, atomic bool) ([]storage.SaveResult, error)
+}
+
+type AliasSource interface {
+	Next(attempt int) (string, error)
+	MaxAttempts() int
+}
+
+// Request represents a batch of URL save requests
+// @Description Up to 1000 links, each item is validated like a POST /url request.
+// @Description In atomic mode (the default) nothing is saved unless every item can be,
+// @Description in best_effort mode valid items are saved and the rest are reported.
+type Request struct {
+	Mode  string         `json:"mode,omitempty" validate:"omitempty,oneof=atomic best_effort" example:"atomic"`
+	Items []save.Request `json:"items" validate:"required,min=1,max=1000"`
+}
+
+// Result is the outcome of one item, in the order of the request
+// @Description Either the alias of the saved link or the reason it was not saved
+type Result struct {
+	Alias string `json:"alias,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Errors are the failed fields of an invalid item
+	Errors []resp.FieldError `json:"errors,omitempty"`
+}
+
+// Response represents batch save response
+// @Description Number of saved links and a result per item
+// swagger:model
+type Response struct {
+	resp.Response
+	Saved   int      `json:"saved"`
+	Results []Result `json:"results"`
+}
+
+const errNotSaved = "not saved because another item failed"
+
+// New
+// @Summary Save URLs in batch
+// @Description Creates short aliases for many URLs in one transaction, the links belong to the caller
+// @Tags url
+// @Accept  json
+// @Produce  json
+// @Param input body Request true "Batch of URL shortening requests"
+// @Success 200 {object} Response
+// @Failure 400 {object} Response "Atomic batch with invalid items"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no write scope"
+// @Failure 409 {object} Response "Atomic batch with taken aliases"
+// @Failure 500 {object} resp.Problem
+// @Security ApiKeyAuth
+// @Router /url/batch [post]
+func New(
+	log *slog.Logger,
+	urlSaver UrlBatchSaver,
+	validate *validator.Validate,
+	aliases AliasSource,
+	principal func(*http.Request) auth.Principal,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.url.saveBatch.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request")
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			var validatorErr validator.ValidationErrors
+			errors.As(err, &validatorErr)
+
+			log.Info("invalid batch", slog.String("error", err.Error()))
+
+			resp.RenderValidationError(w, r, validatorErr)
+
+			return
+		}
+
+		atomic := req.Mode != ModeBestEffort
+		now := time.Now()
+		owner := principal(r).Name
+
+		results := make([]Result, len(req.Items))
+		// indexes[j] is the position of links[j] in the request
+		links := make([]storage.NewLink, 0, len(req.Items))
+		indexes := make([]int, 0, len(req.Items))
+		failed := false
+
+		for i, item := range req.Items {
+			if err := validate.Struct(item); err != nil {
+				var validatorErr validator.ValidationErrors
+				errors.As(err, &validatorErr)
+
+				errResp := resp.ValidationError(r, validatorErr)
+				results[i].Error = errResp.Error
+				results[i].Errors = errResp.Errors
+				failed = true
+
+				continue
+			}
+
+			opts, err := save.Options(item, now)
+			if err != nil {
+				results[i].Error = resp.T(r, err.Error())
+				failed = true
+
+				continue
+			}
+			opts.Owner = owner
+
+			target, alias := item.Url, item.Alias
+			if alias == "" {
+				// stored like save.New does, so that deduplication finds the link later
+				target = storage.NormalizeURL(item.Url)
+				alias, err = aliases.Next(0)
+				if err != nil {
+					log.Error("failed to generate alias", slog.String("error", err.Error()))
+
+					resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to generate alias")
+
+					return
+				}
+				opts.Generated = true
+			}
+
+			links = append(links, storage.NewLink{Url: target, Alias: alias, Opts: opts})
+			indexes = append(indexes, i)
+		}
+
+		if atomic && failed {
+			log.Info("invalid items in atomic batch")
+
+			resp.ErrorStatus(r, http.StatusBadRequest)
+			render.JSON(w, r, rejected(r, results))
+
+			return
+		}
+
+		saved, err := saveLinks(urlSaver, aliases, ws, links, atomic)
+		if err != nil {
+			log.Error("failed to save urls", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to save urls")
+
+			return
+		}
+
+		for j, res := range saved {
+			i := indexes[j]
+
+			switch {
+			case errors.Is(res.Err, storage.ErrUrlExists) && links[j].Opts.Generated:
+				results[i].Error = resp.T(r, "failed to generate alias")
+				failed = true
+			case errors.Is(res.Err, storage.ErrUrlExists):
+				results[i].Error = resp.T(r, "url already exists")
+				failed = true
+			case res.Err != nil:
+				results[i].Error = resp.T(r, "failed to save url")
+				failed = true
+			default:
+				results[i].Alias = links[j].Alias
+			}
+		}
+
+		if atomic && failed {
+			log.Info("atomic batch not saved")
+
+			resp.ErrorStatus(r, http.StatusConflict)
+			render.JSON(w, r, rejected(r, results))
+
+			return
+		}
+
+		response := Response{Response: resp.OK(), Results: results}
+		for _, res := range results {
+			if res.Error == "" {
+				response.Saved++
+			}
+		}
+
+		log.Info("success save urls", slog.Int("saved", response.Saved), slog.Int("total", len(results)))
+
+		render.JSON(w, r, response)
+	}
+}
+
+// rejected reports an atomic batch that was not saved. Items without
+// an error of their own are marked as not saved too.
+func rejected(r *http.Request, results []Result) Response {
+	for i := range results {
+		results[i].Alias = ""
+		if results[i].Error == "" {
+			results[i].Error = resp.T(r, errNotSaved)
+		}
+	}
+
+	return Response{
+		Response: resp.Error(resp.T(r, "batch not saved")),
+		Results:  results,
+	}
+}
+
+// saveLinks saves links and replaces the generated aliases that are
+// taken until they fit or run out of attempts. A best effort batch
+// retries only the collided links. An atomic batch is retried as a
+// whole, and only when collisions are all that kept it from saving.
+// The aliases in links are updated to the ones saved.
+func saveLinks(
+	urlSaver UrlBatchSaver,
+	aliases AliasSource,
+	ws string,
+	links []storage.NewLink,
+	atomic bool,
+) ([]storage.SaveResult, error) {
+	results := make([]storage.SaveResult, len(links))
+
+	pending := make([]int, len(links))
+	for j := range pending {
+		pending[j] = j
+	}
+
+	for attempt := 1; ; attempt++ {
+		batch := make([]storage.NewLink, len(pending))
+		for k, j := range pending {
+			batch[k] = links[j]
+		}
+
+		saved, err := urlSaver.SaveUrls(ws, batch, atomic)
+		if err != nil {
+			return nil, err
+		}
+
+		var collided []int
+		retry := true
+
+		for k, res := range saved {
+			j := pending[k]
+			results[j] = res
+
+			switch {
+			case errors.Is(res.Err, storage.ErrUrlExists) && links[j].Opts.Generated:
+				collided = append(collided, j)
+			case res.Err != nil:
+				retry = false
+			}
+		}
+
+		if len(collided) == 0 || attempt >= aliases.MaxAttempts() || (atomic && !retry) {
+			return results, nil
+		}
+
+		for _, j := range collided {
+			alias, err := aliases.Next(attempt)
+			if err != nil {
+				return nil, err
+			}
+			links[j].Alias = alias
+		}
+
+		if !atomic {
+			pending = collided
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package saveBatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	aliasgen "github.com/popvaleks/url-shortener/internal/lib/alias"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockUrlBatchSaver struct {
+	mock.Mock
+}
+
+func (m *MockUrlBatchSaver) SaveUrls(workspace string, links []storage.NewLink, atomic bool) ([]storage.SaveResult, error) {
+	args := m.Called(workspace, links, atomic)
+	return args.Get(0).([]storage.SaveResult), args.Error(1)
+}
+
+// stubAliases hands out the given aliases in order.
+type stubAliases struct {
+	aliases []string
+	next    int
+}
+
+func (s *stubAliases) Next(attempt int) (string, error) {
+	alias := s.aliases[s.next]
+	s.next++
+
+	return alias, nil
+}
+
+func (s *stubAliases) MaxAttempts() int {
+	return 3
+}
+
+func testValidator() *validator.Validate {
+	policy := aliasgen.NewPolicy(aliasgen.PolicyOptions{MinLength: 3, MaxLength: 32, Charset: aliasgen.DefaultCharset})
+	policy.Reserve("url")
+
+	v := resp.NewValidator()
+	if err := policy.Register(v); err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+func testAliases() *aliasgen.Source {
+	gen, err := aliasgen.NewRandom(aliasgen.DefaultAlphabet)
+	if err != nil {
+		panic(err)
+	}
+
+	return aliasgen.NewSource(gen, aliasgen.Options{Length: 8, MaxAttempts: 3, GrowAfter: 2})
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
+func TestSaveBatchHandler(t *testing.T) {
+	links := []storage.NewLink{
+		{Url: "http://a.example.com", Alias: "aaa"},
+		{Url: "http://b.example.com", Alias: "bbb", Opts: storage.SaveOptions{Tags: []string{"promo"}}},
+	}
+	body := `{"items":[
+		{"url":"http://a.example.com","alias":"aaa"},
+		{"url":"http://b.example.com","alias":"bbb","tags":["Promo"]}]}`
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*MockUrlBatchSaver)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "success atomic",
+			requestBody: body,
+			setupMock: func(m *MockUrlBatchSaver) {
+				m.On("SaveUrls", storage.DefaultWorkspace, links, true).Return([]storage.SaveResult{{ID: 1}, {ID: 2}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","saved":2,"results":[{"alias":"aaa"},{"alias":"bbb"}]}`,
+		},
+		{
+			name:        "atomic with taken alias",
+			requestBody: body,
+			setupMock: func(m *MockUrlBatchSaver) {
+				m.On("SaveUrls", storage.DefaultWorkspace, links, true).Return([]storage.SaveResult{{}, {Err: storage.ErrUrlExists}}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody: `{"status":"Error","error":"batch not saved","saved":0,"results":[
+				{"error":"not saved because another item failed"},
+				{"error":"url already exists"}]}`,
+		},
+		{
+			name: "atomic with invalid item",
+			requestBody: `{"mode":"atomic","items":[
+				{"url":"http://a.example.com","alias":"aaa"},
+				{"url":"not a url"},
+				{"url":"http://c.example.com","ttl":"soon"}]}`,
+			// ни один элемент не передается в хранилище
+			setupMock:      func(m *MockUrlBatchSaver) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"status":"Error","error":"batch not saved","saved":0,"results":[
+				{"error":"not saved because another item failed"},
+				{"error":"field Url is not a valid URL","errors":[{"field":"url","rule":"url","message":"must be a valid URL"}]},
+				{"error":"ttl must be a positive duration like 90m, 168h or 7d"}]}`,
+		},
+		{
+			name: "best effort",
+			requestBody: `{"mode":"best_effort","items":[
+				{"url":"http://a.example.com","alias":"aaa"},
+				{"url":"not a url"},
+				{"url":"http://b.example.com","alias":"bbb","tags":["promo"]}]}`,
+			setupMock: func(m *MockUrlBatchSaver) {
+				m.On("SaveUrls", storage.DefaultWorkspace, links, false).Return([]storage.SaveResult{{Err: storage.ErrUrlExists}, {ID: 2}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","saved":1,"results":[
+				{"error":"url already exists"},
+				{"error":"field Url is not a valid URL","errors":[{"field":"url","rule":"url","message":"must be a valid URL"}]},
+				{"alias":"bbb"}]}`,
+		},
+		{
+			name:           "empty batch",
+			requestBody:    `{"items":[]}`,
+			setupMock:      func(m *MockUrlBatchSaver) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Items is not valid","code":"validation_failed","request_id":"test-request","errors":[{"field":"items","rule":"min","message":"must have at least 1 item","param":"1"}]}`,
+		},
+		{
+			name:           "unknown mode",
+			requestBody:    `{"mode":"some","items":[{"url":"http://a.example.com"}]}`,
+			setupMock:      func(m *MockUrlBatchSaver) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Mode is not valid","code":"validation_failed","request_id":"test-request","errors":[{"field":"mode","rule":"oneof","message":"must be one of: atomic, best_effort","param":"atomic best_effort"}]}`,
+		},
+		{
+			name:           "invalid json",
+			requestBody:    `{"items":`,
+			setupMock:      func(m *MockUrlBatchSaver) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"failed to decode request","code":"invalid_body","request_id":"test-request"}`,
+		},
+		{
+			name:        "storage error",
+			requestBody: body,
+			setupMock: func(m *MockUrlBatchSaver) {
+				m.On("SaveUrls", storage.DefaultWorkspace, links, true).Return([]storage.SaveResult(nil), errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to save urls","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockSaver := new(MockUrlBatchSaver)
+			tt.setupMock(mockSaver)
+
+			req, err := http.NewRequest("POST", "/url/batch", strings.NewReader(tt.requestBody))
+			require.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			handler := middleware.RequestID(New(slog.Default(), mockSaver, testValidator(), testAliases(), unrestricted, defaultWorkspace))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockSaver.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSaveBatchHandlerWithMemoryStorage(t *testing.T) {
+	repo := memory.New()
+	ops := func(*http.Request) auth.Principal { return auth.Principal{Name: "ops"} }
+	handler := middleware.RequestID(New(slog.Default(), repo, testValidator(), testAliases(), ops, defaultWorkspace))
+
+	items := make([]string, 0, 300)
+	for i := range 300 {
+		items = append(items, fmt.Sprintf(`{"url":"http://example.com/%d"}`, i))
+	}
+
+	req, err := http.NewRequest("POST", "/url/batch", strings.NewReader(`{"items":[`+strings.Join(items, ",")+`]}`))
+	require.NoError(t, err)
+	req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var response Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, "OK", response.Status)
+	assert.Equal(t, 300, response.Saved)
+
+	for i, res := range response.Results {
+		url, err := repo.GetUrl(storage.DefaultWorkspace, res.Alias)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("http://example.com/%d", i), url, "results keep the order of the request")
+	}
+
+	owner, err := repo.GetOwner(storage.DefaultWorkspace, response.Results[0].Alias)
+	require.NoError(t, err)
+	assert.Equal(t, "ops", owner)
+}
+
+func TestSaveBatchHandlerRetriesCollisions(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         string
+		aliases      []string
+		expectedBody string
+	}{
+		{
+			name:         "best effort retries collided items",
+			mode:         ModeBestEffort,
+			aliases:      []string{"taken", "free1", "free2"},
+			expectedBody: `{"status":"OK","saved":2,"results":[{"alias":"free2"},{"alias":"free1"}]}`,
+		},
+		{
+			name: "atomic retries the whole batch",
+			mode: ModeAtomic,
+			// одинаковые сгенерированные алиасы в одном пакете тоже считаются коллизией
+			aliases:      []string{"free1", "free1", "free2"},
+			expectedBody: `{"status":"OK","saved":2,"results":[{"alias":"free1"},{"alias":"free2"}]}`,
+		},
+		{
+			name:    "attempts run out",
+			mode:    ModeBestEffort,
+			aliases: []string{"taken", "free1", "taken", "taken"},
+			expectedBody: `{"status":"OK","saved":1,"results":[
+				{"error":"failed to generate alias"},
+				{"alias":"free1"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := memory.New()
+			_, err := repo.SaveUrl(storage.DefaultWorkspace, "http://example.com/", "taken", storage.SaveOptions{})
+			require.NoError(t, err)
+
+			handler := middleware.RequestID(New(slog.Default(), repo, testValidator(), &stubAliases{aliases: tt.aliases}, unrestricted, defaultWorkspace))
+
+			body := `{"mode":"` + tt.mode + `","items":[{"url":"http://a.example.com"},{"url":"http://b.example.com"}]}`
+			req, err := http.NewRequest("POST", "/url/batch", strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestSaveBatchHandlerTranslated(t *testing.T) {
+	handler := middleware.RequestID(New(slog.Default(), new(MockUrlBatchSaver), testValidator(), testAliases(), unrestricted, defaultWorkspace))
+
+	body := `{"items":[{"url":"http://a.example.com"},{"url":"not a url"}]}`
+	req, err := http.NewRequest("POST", "/url/batch", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"status":"Error","error":"пакет не сохранён","saved":0,"results":[
+		{"error":"не сохранено из-за ошибки в другом элементе"},
+		{"error":"поле Url не является корректным URL","errors":[{"field":"url","rule":"url","message":"должно быть корректным URL"}]}]}`,
+		rr.Body.String())
+}
+
+func unrestricted(*http.Request) auth.Principal {
+	return auth.Unrestricted
+}
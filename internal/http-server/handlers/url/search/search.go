@@ -0,0 +1,156 @@
+package search
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type UrlSearcher interface {
+	SearchUrls(workspace, owner, query string, limit, offset int) ([]storage.Link, error)
+}
+
+// Link represents a found short link
+// @Description Short link matching the query
+type Link struct {
+	Alias     string     `json:"alias"`
+	Url       string     `json:"url"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Response represents search results
+// @Description Success response with links ordered by relevance
+// swagger:model
+type Response struct {
+	resp.Response
+	Result []Link `json:"result"`
+	// NextOffset is set when there are more results
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+type params struct {
+	query  string
+	owner  string
+	limit  int
+	offset int
+}
+
+// New
+// @Summary Search URLs
+// @Description Finds links whose alias or target URL contain every word of the query
+// @Description as a word prefix. Alias matches rank higher.
+// @Tags url
+// @Produce  json
+// @Param q query string true "Search query" example(pricing)
+// @Param owner query string false "Only links of the owner"
+// @Param limit query int false "Page size, 20 by default, at most 100"
+// @Param offset query int false "next_offset of the previous page"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Invalid query parameters"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no read scope"
+// @Failure 500 {object} resp.Problem
+// @Security ApiKeyAuth
+// @Router /url/search [get]
+func New(
+	log *slog.Logger,
+	urlSearcher UrlSearcher,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.search.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		p, err := parseParams(r.URL.Query())
+		if err != nil {
+			log.Info("invalid query", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidQuery, err.Error())
+
+			return
+		}
+
+		// one extra link tells whether there is a next page
+		links, err := urlSearcher.SearchUrls(ws, p.owner, p.query, p.limit+1, p.offset)
+		if err != nil {
+			log.Error("internal server error", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+			return
+		}
+
+		log.Info("success search urls", slog.Int("count", len(links)))
+
+		response := Response{Response: resp.OK(), Result: make([]Link, 0, len(links))}
+
+		if len(links) > p.limit {
+			links = links[:p.limit]
+			next := p.offset + p.limit
+			response.NextOffset = &next
+		}
+
+		for _, link := range links {
+			response.Result = append(response.Result, Link{
+				Alias:     link.Alias,
+				Url:       link.Url,
+				CreatedAt: link.CreatedAt,
+				ExpiresAt: link.ExpiresAt,
+			})
+		}
+
+		render.JSON(w, r, response)
+	}
+}
+
+func parseParams(query url.Values) (params, error) {
+	p := params{
+		query: strings.TrimSpace(query.Get("q")),
+		owner: query.Get("owner"),
+		limit: defaultLimit,
+	}
+
+	if p.query == "" {
+		return params{}, errors.New("q is required")
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxLimit {
+			return params{}, errors.New("limit must be a number from 1 to 100")
+		}
+		p.limit = n
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return params{}, errors.New("offset must be a non-negative number")
+		}
+		p.offset = n
+	}
+
+	return p, nil
+}
@@ -0,0 +1,144 @@
+package search
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUrlSearcher struct {
+	mock.Mock
+}
+
+func (m *MockUrlSearcher) SearchUrls(workspace, owner, query string, limit, offset int) ([]storage.Link, error) {
+	args := m.Called(workspace, owner, query, limit, offset)
+	return args.Get(0).([]storage.Link), args.Error(1)
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
+func TestSearchHandler(t *testing.T) {
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	links := []storage.Link{
+		{ID: 3, Alias: "pricing", Url: "https://example.org/plans", CreatedAt: createdAt},
+		{ID: 1, Alias: "p1", Url: "https://example.com/pricing", CreatedAt: createdAt},
+		{ID: 2, Alias: "faq", Url: "https://example.com/docs/pricing-faq", CreatedAt: createdAt},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(*MockUrlSearcher)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "success",
+			query: "?q=pricing",
+			setupMock: func(m *MockUrlSearcher) {
+				// запрашивается на одну ссылку больше, чтобы узнать о следующей странице
+				m.On("SearchUrls", storage.DefaultWorkspace, "", "pricing", defaultLimit+1, 0).Return(links[:2], nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","result":[
+				{"alias":"pricing","url":"https://example.org/plans","created_at":"2025-03-01T10:00:00Z"},
+				{"alias":"p1","url":"https://example.com/pricing","created_at":"2025-03-01T10:00:00Z"}]}`,
+		},
+		{
+			name:  "next page",
+			query: "?q=pricing&limit=2&offset=2",
+			setupMock: func(m *MockUrlSearcher) {
+				m.On("SearchUrls", storage.DefaultWorkspace, "", "pricing", 3, 2).Return(links, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","next_offset":4,"result":[
+				{"alias":"pricing","url":"https://example.org/plans","created_at":"2025-03-01T10:00:00Z"},
+				{"alias":"p1","url":"https://example.com/pricing","created_at":"2025-03-01T10:00:00Z"}]}`,
+		},
+		{
+			name:  "owner filter",
+			query: "?q=pricing&owner=dev",
+			setupMock: func(m *MockUrlSearcher) {
+				m.On("SearchUrls", storage.DefaultWorkspace, "dev", "pricing", defaultLimit+1, 0).Return(links[2:], nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","result":[
+				{"alias":"faq","url":"https://example.com/docs/pricing-faq","created_at":"2025-03-01T10:00:00Z"}]}`,
+		},
+		{
+			name:  "nothing found",
+			query: "?q=missing",
+			setupMock: func(m *MockUrlSearcher) {
+				m.On("SearchUrls", storage.DefaultWorkspace, "", "missing", defaultLimit+1, 0).Return([]storage.Link{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","result":[]}`,
+		},
+		{
+			name:           "empty query",
+			query:          "?q=+",
+			setupMock:      func(m *MockUrlSearcher) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"q is required","code":"invalid_query","request_id":"test-request"}`,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?q=pricing&limit=0",
+			setupMock:      func(m *MockUrlSearcher) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"limit must be a number from 1 to 100","code":"invalid_query","request_id":"test-request"}`,
+		},
+		{
+			name:           "invalid offset",
+			query:          "?q=pricing&offset=-1",
+			setupMock:      func(m *MockUrlSearcher) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"offset must be a non-negative number","code":"invalid_query","request_id":"test-request"}`,
+		},
+		{
+			name:  "internal server error",
+			query: "?q=pricing",
+			setupMock: func(m *MockUrlSearcher) {
+				m.On("SearchUrls", storage.DefaultWorkspace, "", "pricing", defaultLimit+1, 0).Return([]storage.Link(nil), errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockSearcher := new(MockUrlSearcher)
+			tt.setupMock(mockSearcher)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Get("/url/search", New(slog.Default(), mockSearcher, defaultWorkspace))
+
+			req, err := http.NewRequest("GET", "/url/search"+tt.query, nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockSearcher.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package stats
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type StatsGetter interface {
+	GetStats(workspace, alias string) (storage.Stats, error)
+}
+
+// DayCount represents clicks during one day
+// @Description Number of clicks during one UTC day
+type DayCount struct {
+	Date  string `json:"date" example:"2025-03-01"`
+	Count int64  `json:"count"`
+}
+
+// Result represents click statistics of an alias
+// @Description Total and per-day click counts
+type Result struct {
+	Alias  string     `json:"alias"`
+	Total  int64      `json:"total"`
+	PerDay []DayCount `json:"per_day"`
+}
+
+// Response represents URL statistics response
+// @Description Success response with click statistics
+// swagger:model
+type Response struct {
+	resp.Response
+	Result Result `json:"result"`
+}
+
+// New
+// @Summary Get URL click statistics
+// @Description Returns the total and per-day number of redirects of the alias
+// @Tags url
+// @Produce  json
+// @Param alias path string true "Alias to get statistics for"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Alias is missing"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no read scope"
+// @Failure 404 {object} resp.Problem "URL not found"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /url/{alias}/stats [get]
+func New(log *slog.Logger, statsGetter StatsGetter, workspace func(*http.Request) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias not allowed")
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "alias not allowed")
+
+			return
+		}
+
+		stats, err := statsGetter.GetStats(ws, alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found")
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeUrlNotFound, "url not found")
+
+			return
+		}
+
+		if err != nil {
+			log.Error("internal server error", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+			return
+		}
+
+		perDay := make([]DayCount, 0, len(stats.PerDay))
+		for _, day := range stats.PerDay {
+			perDay = append(perDay, DayCount{Date: day.Date, Count: day.Count})
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Result: Result{
+				Alias:  alias,
+				Total:  stats.Total,
+				PerDay: perDay,
+			},
+		})
+	}
+}
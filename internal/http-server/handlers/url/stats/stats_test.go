@@ -0,0 +1,106 @@
+package stats
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStatsGetter struct {
+	mock.Mock
+}
+
+func (m *MockStatsGetter) GetStats(workspace, alias string) (storage.Stats, error) {
+	args := m.Called(workspace, alias)
+	return args.Get(0).(storage.Stats), args.Error(1)
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
+func TestStatsHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		alias          string
+		setupMock      func(*MockStatsGetter)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "success",
+			alias: "example",
+			setupMock: func(m *MockStatsGetter) {
+				m.On("GetStats", storage.DefaultWorkspace, "example").Return(storage.Stats{
+					Total: 3,
+					PerDay: []storage.DayCount{
+						{Date: "2025-03-01", Count: 1},
+						{Date: "2025-03-02", Count: 2},
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","result":{"alias":"example","total":3,"per_day":[
+				{"date":"2025-03-01","count":1},{"date":"2025-03-02","count":2}]}}`,
+		},
+		{
+			name:  "no clicks",
+			alias: "example",
+			setupMock: func(m *MockStatsGetter) {
+				m.On("GetStats", storage.DefaultWorkspace, "example").Return(storage.Stats{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","result":{"alias":"example","total":0,"per_day":[]}}`,
+		},
+		{
+			name:  "url not found",
+			alias: "notfound",
+			setupMock: func(m *MockStatsGetter) {
+				m.On("GetStats", storage.DefaultWorkspace, "notfound").Return(storage.Stats{}, storage.ErrUrlNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"url not found","code":"url_not_found","request_id":"test-request"}`,
+		},
+		{
+			name:  "internal server error",
+			alias: "error",
+			setupMock: func(m *MockStatsGetter) {
+				m.On("GetStats", storage.DefaultWorkspace, "error").Return(storage.Stats{}, errors.New("internal error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockGetter := new(MockStatsGetter)
+			tt.setupMock(mockGetter)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Get("/url/{alias}/stats", New(slog.Default(), mockGetter, defaultWorkspace))
+
+			req, err := http.NewRequest("GET", "/url/"+tt.alias+"/stats", nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockGetter.AssertExpectations(t)
+		})
+	}
+}
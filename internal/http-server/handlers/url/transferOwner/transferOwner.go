@@ -0,0 +1,152 @@
+package transferOwner
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+
+	"github.com/popvaleks/url-shortener/internal/auth"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type OwnerChanger interface {
+	GetOwner(workspace, alias string) (string, error)
+	SetOwner(workspace, alias, owner string) error
+}
+
+// Request represents ownership transfer request
+// @Description The new owner is the name of API keys, see POST /admin/keys.
+type Request struct {
+	Owner string `json:"owner" validate:"required,max=100" example:"marketing"`
+}
+
+// Response represents ownership transfer response
+// @Description Success response with the new owner of the link
+// swagger:model
+type Response struct {
+	resp.Response
+	Alias string `json:"alias"`
+	Owner string `json:"owner"`
+}
+
+// New
+// @Summary Transfer URL ownership
+// @Description Gives the link to another owner. Only the owner of the link or an admin
+// @Description can transfer it, links without an owner are transferred by admins.
+// @Tags url
+// @Accept  json
+// @Produce  json
+// @Param alias path string true "Alias of the URL"
+// @Param input body Request true "New owner"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Invalid request"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no write scope or the URL belongs to another owner"
+// @Failure 404 {object} resp.Problem "URL not found"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /url/{alias}/owner [put]
+func New(
+	log *slog.Logger,
+	ownerChanger OwnerChanger,
+	principal func(*http.Request) auth.Principal,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
+	validate := resp.NewValidator()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.transferOwner.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias not allowed")
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "alias not allowed")
+
+			return
+		}
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request")
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			var validatorErr validator.ValidationErrors
+			errors.As(err, &validatorErr)
+
+			log.Info("failed to validate request", slog.String("error", err.Error()))
+
+			resp.RenderValidationError(w, r, validatorErr)
+
+			return
+		}
+
+		owner, err := ownerChanger.GetOwner(ws, alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found")
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeUrlNotFound, "url not found")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get owner", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to transfer url")
+
+			return
+		}
+
+		if caller := principal(r); !caller.CanManage(owner) {
+			log.Info("url belongs to another owner", slog.String("owner", owner), slog.String("caller", caller.Name))
+
+			resp.RenderError(w, r, http.StatusForbidden, resp.CodeNotOwner, "url belongs to another owner")
+
+			return
+		}
+
+		// the link may be deleted in between
+		err = ownerChanger.SetOwner(ws, alias, req.Owner)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found")
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeUrlNotFound, "url not found")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to set owner", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to transfer url")
+
+			return
+		}
+
+		log.Info("success transfer url", slog.String("from", owner), slog.String("to", req.Owner))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Alias:    alias,
+			Owner:    req.Owner,
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package transferOwner
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOwnerChanger struct {
+	mock.Mock
+}
+
+func (m *MockOwnerChanger) GetOwner(workspace, alias string) (string, error) {
+	args := m.Called(workspace, alias)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockOwnerChanger) SetOwner(workspace, alias, owner string) error {
+	args := m.Called(workspace, alias, owner)
+	return args.Error(0)
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
+func TestTransferOwnerHandler(t *testing.T) {
+	ops := auth.Principal{Name: "ops"}
+	admin := auth.Principal{Name: "root", Admin: true}
+
+	tests := []struct {
+		name           string
+		caller         auth.Principal
+		body           string
+		setupMock      func(*MockOwnerChanger)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "owner transfers",
+			caller: ops,
+			body:   `{"owner":"marketing"}`,
+			setupMock: func(m *MockOwnerChanger) {
+				m.On("GetOwner", storage.DefaultWorkspace, "promo").Return("ops", nil)
+				m.On("SetOwner", storage.DefaultWorkspace, "promo", "marketing").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","alias":"promo","owner":"marketing"}`,
+		},
+		{
+			// ссылку без владельца передает только админ
+			name:   "admin transfers a link without owner",
+			caller: admin,
+			body:   `{"owner":"ops"}`,
+			setupMock: func(m *MockOwnerChanger) {
+				m.On("GetOwner", storage.DefaultWorkspace, "promo").Return("", nil)
+				m.On("SetOwner", storage.DefaultWorkspace, "promo", "ops").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","alias":"promo","owner":"ops"}`,
+		},
+		{
+			name:   "another owner",
+			caller: ops,
+			body:   `{"owner":"ops"}`,
+			setupMock: func(m *MockOwnerChanger) {
+				m.On("GetOwner", storage.DefaultWorkspace, "promo").Return("dev", nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"url belongs to another owner","code":"not_owner","request_id":"test-request"}`,
+		},
+		{
+			name:           "no owner",
+			caller:         ops,
+			body:           `{}`,
+			setupMock:      func(m *MockOwnerChanger) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Owner is a required field","code":"validation_failed","request_id":"test-request",` +
+				`"errors":[{"field":"owner","rule":"required","message":"is required"}]}`,
+		},
+		{
+			name:   "url not found",
+			caller: ops,
+			body:   `{"owner":"dev"}`,
+			setupMock: func(m *MockOwnerChanger) {
+				m.On("GetOwner", storage.DefaultWorkspace, "promo").Return("", storage.ErrUrlNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"url not found","code":"url_not_found","request_id":"test-request"}`,
+		},
+		{
+			name:   "storage error",
+			caller: admin,
+			body:   `{"owner":"dev"}`,
+			setupMock: func(m *MockOwnerChanger) {
+				m.On("GetOwner", storage.DefaultWorkspace, "promo").Return("ops", nil)
+				m.On("SetOwner", storage.DefaultWorkspace, "promo", "dev").Return(errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to transfer url","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockChanger := new(MockOwnerChanger)
+			tt.setupMock(mockChanger)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Put("/url/{alias}/owner", New(slog.Default(), mockChanger, func(*http.Request) auth.Principal { return tt.caller }, defaultWorkspace))
+
+			req, err := http.NewRequest(http.MethodPut, "/url/promo/owner", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockChanger.AssertExpectations(t)
+		})
+	}
+}
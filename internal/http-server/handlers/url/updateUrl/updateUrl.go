@@ -6,6 +6,7 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	"github.com/popvaleks/url-shortener/internal/auth"
 	"github.com/popvaleks/url-shortener/internal/storage"
 	"log/slog"
 	"net/http"
@@ -14,13 +15,16 @@ import (
 )
 
 type UrlEditer interface {
-	UpdateUrl(url, alias string) (string, error)
+	GetOwner(workspace, alias string) (string, error)
+	UpdateLink(workspace, alias, newAlias, url string) error
 }
 
 // Request represents URL update request
-// @Description Request to update original URL for existing alias
+// @Description Request to update original URL or alias of an existing link, at least one is required.
+// @Description A new alias must fit the alias policy like in POST /url.
 type Request struct {
-	Url string `json:"url" validate:"required,url"`
+	Url   string `json:"url,omitempty" validate:"omitempty,url"`
+	Alias string `json:"alias,omitempty" validate:"omitempty,alias" example:"spring-sale"`
 }
 
 // ResponseAlias represents alias in response
@@ -39,31 +43,45 @@ type Response struct {
 
 // New
 // @Summary Update URL by alias
-// @Description Updates original URL for existing alias
+// @Description Updates original URL for existing alias and renames it.
+// @Description Only the owner of the link or an admin can change it.
 // @Tags url
 // @Accept  json
 // @Produce  json
 // @Param alias path string true "Alias to update"
 // @Param input body Request true "New URL data"
 // @Success 200 {object} Response
-// @Failure 400 {object} resp.Response "Invalid request or validation error"
-// @Failure 404 {object} resp.Response "Alias not found"
-// @Failure 500 {object} resp.Response "Internal server error"
+// @Failure 400 {object} resp.Problem "Invalid request or validation error"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "API key has no write scope or the alias belongs to another owner"
+// @Failure 404 {object} resp.Problem "Alias not found"
+// @Failure 409 {object} resp.Problem "New alias already exists"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
 // @Router /{alias} [patch]
-func New(log *slog.Logger, urlEditer UrlEditer) http.HandlerFunc {
+func New(
+	log *slog.Logger,
+	urlEditer UrlEditer,
+	validate *validator.Validate,
+	principal func(*http.Request) auth.Principal,
+	workspace func(*http.Request) string,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.updateUrl.New"
 
-		log = log.With(
+		ws := workspace(r)
+
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
 		)
 
 		alias := chi.URLParam(r, "alias")
 		if alias == "" {
 			log.Info("alias not allowed")
 
-			render.JSON(w, r, resp.Error("alias not allowed"))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "alias not allowed")
 
 			return
 		}
@@ -74,45 +92,90 @@ func New(log *slog.Logger, urlEditer UrlEditer) http.HandlerFunc {
 		if err != nil {
 			log.Error("failed to decode request", slog.String("error", err.Error()))
 
-			render.JSON(w, r, resp.Error("failed to decode request"))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request")
 
 			return
 		}
 
 		log.Info("success decode req", slog.Any("request", req)) // can remove, debug only
 
-		if err := validator.New().Struct(req); err != nil {
+		if err := validate.Struct(req); err != nil {
 			var validatorErr validator.ValidationErrors
 			errors.As(err, &validatorErr)
 
 			log.Error("failed to validate request", slog.String("error", err.Error()))
 
-			render.JSON(w, r, resp.ValidationError(validatorErr))
+			resp.RenderValidationError(w, r, validatorErr)
+
+			return
+		}
+
+		if req.Url == "" && req.Alias == "" {
+			log.Info("nothing to update")
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeValidationFailed, "url or alias is required")
+
+			return
+		}
+
+		owner, err := urlEditer.GetOwner(ws, alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("alias not found")
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeAliasNotFound, "alias not found")
 
 			return
 		}
+		if err != nil {
+			log.Error("failed to get owner", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to update url")
 
-		sAlias, err := urlEditer.UpdateUrl(req.Url, alias)
+			return
+		}
+
+		if caller := principal(r); !caller.CanManage(owner) {
+			log.Info("alias belongs to another owner", slog.String("owner", owner), slog.String("caller", caller.Name))
+
+			resp.RenderError(w, r, http.StatusForbidden, resp.CodeNotOwner, "url belongs to another owner")
+
+			return
+		}
+
+		// the rename and the new url are applied together, a taken alias
+		// leaves the url as it was
+		err = urlEditer.UpdateLink(ws, alias, req.Alias, req.Url)
 		if errors.Is(err, storage.ErrAliasNotFound) {
-			log.Info("alias not found", slog.String("url", req.Url))
+			log.Info("alias not found")
 
-			render.JSON(w, r, resp.Error("alias not found"))
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeAliasNotFound, "alias not found")
+
+			return
+		}
+		if errors.Is(err, storage.ErrUrlExists) {
+			log.Info("new alias already exists", slog.String("new_alias", req.Alias))
+
+			resp.RenderError(w, r, http.StatusConflict, resp.CodeAliasExists, "alias already exists")
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to update url", slog.String("error", err.Error()))
 
-			render.JSON(w, r, resp.Error("failed to update url"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to update url")
 
 			return
 		}
 
-		log.Info("success update url", slog.String("alias", sAlias))
+		if req.Alias != "" {
+			alias = req.Alias
+		}
+
+		log.Info("success update url", slog.String("alias", alias))
 
 		render.JSON(w, r, Response{
 			Response: resp.OK(),
-			Result:   ResponseAlias{sAlias},
+			Result:   ResponseAlias{alias},
 		})
 	}
 }
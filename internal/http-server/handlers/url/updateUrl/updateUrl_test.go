@@ -2,6 +2,7 @@ package updateUrl
 
 import (
 	"errors"
+	"github.com/popvaleks/url-shortener/internal/auth"
 	"github.com/popvaleks/url-shortener/internal/storage"
 	"log/slog"
 	"net/http"
@@ -11,6 +12,9 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	aliasgen "github.com/popvaleks/url-shortener/internal/lib/alias"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -19,11 +23,35 @@ type MockUrlEditer struct {
 	mock.Mock
 }
 
-func (m *MockUrlEditer) UpdateUrl(url, alias string) (string, error) {
-	args := m.Called(url, alias)
+func (m *MockUrlEditer) GetOwner(workspace, alias string) (string, error) {
+	args := m.Called(workspace, alias)
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockUrlEditer) UpdateLink(workspace, alias, newAlias, url string) error {
+	args := m.Called(workspace, alias, newAlias, url)
+	return args.Error(0)
+}
+
+func testValidator() *validator.Validate {
+	policy := aliasgen.NewPolicy(aliasgen.PolicyOptions{MinLength: 3, MaxLength: 32, Charset: aliasgen.DefaultCharset})
+	policy.Reserve("url")
+
+	v := resp.NewValidator()
+	if err := policy.Register(v); err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// ops is the caller of every request, it owns the links of the mocks
+func ops(*http.Request) auth.Principal {
+	return auth.Principal{Name: "ops"}
+}
+
+func defaultWorkspace(*http.Request) string { return storage.DefaultWorkspace }
+
 func TestUpdateUrlHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -38,7 +66,8 @@ func TestUpdateUrlHandler(t *testing.T) {
 			requestBody: `{"url": "http://example.com"}`,
 			alias:       "test",
 			setupMock: func(m *MockUrlEditer) {
-				m.On("UpdateUrl", "http://example.com", "test").Return("test", nil)
+				m.On("GetOwner", storage.DefaultWorkspace, "test").Return("ops", nil)
+				m.On("UpdateLink", storage.DefaultWorkspace, "test", "", "http://example.com").Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"result": {"alias":"test"},"status":"OK"}`,
@@ -48,20 +77,92 @@ func TestUpdateUrlHandler(t *testing.T) {
 			requestBody: `{"url": "http://example.com"}`,
 			alias:       "notFound",
 			setupMock: func(m *MockUrlEditer) {
-				m.On("UpdateUrl", "http://example.com", "notFound").Return("", storage.ErrAliasNotFound)
+				m.On("GetOwner", storage.DefaultWorkspace, "notFound").Return("ops", nil)
+				m.On("UpdateLink", storage.DefaultWorkspace, "notFound", "", "http://example.com").Return(storage.ErrAliasNotFound)
 			},
-			expectedStatus: http.StatusOK,
-			expectedBody:   `{"error":"alias not found", "status":"Error"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"alias not found","code":"alias_not_found","request_id":"test-request"}`,
 		},
 		{
 			name:        "internal server error",
 			requestBody: `{"url": "http://example.com"}`,
 			alias:       "err",
 			setupMock: func(m *MockUrlEditer) {
-				m.On("UpdateUrl", "http://example.com", "err").Return("", errors.New("internal error"))
+				m.On("GetOwner", storage.DefaultWorkspace, "err").Return("ops", nil)
+				m.On("UpdateLink", storage.DefaultWorkspace, "err", "", "http://example.com").Return(errors.New("internal error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to update url","code":"internal_error","request_id":"test-request"}`,
+		},
+		{
+			name:        "rename",
+			requestBody: `{"alias": "spring-sale"}`,
+			alias:       "test",
+			setupMock: func(m *MockUrlEditer) {
+				m.On("GetOwner", storage.DefaultWorkspace, "test").Return("ops", nil)
+				m.On("UpdateLink", storage.DefaultWorkspace, "test", "spring-sale", "").Return(nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"status": "Error","error": "failed to update url"}`,
+			expectedBody:   `{"result": {"alias":"spring-sale"},"status":"OK"}`,
+		},
+		{
+			name:        "rename and update",
+			requestBody: `{"url": "http://example.com", "alias": "spring-sale"}`,
+			alias:       "test",
+			setupMock: func(m *MockUrlEditer) {
+				m.On("GetOwner", storage.DefaultWorkspace, "test").Return("ops", nil)
+				// переименование и новый url — одна операция хранилища
+				m.On("UpdateLink", storage.DefaultWorkspace, "test", "spring-sale", "http://example.com").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"result": {"alias":"spring-sale"},"status":"OK"}`,
+		},
+		{
+			name:        "new alias taken",
+			requestBody: `{"url": "http://example.com", "alias": "taken"}`,
+			alias:       "test",
+			setupMock: func(m *MockUrlEditer) {
+				m.On("GetOwner", storage.DefaultWorkspace, "test").Return("ops", nil)
+				m.On("UpdateLink", storage.DefaultWorkspace, "test", "taken", "http://example.com").Return(storage.ErrUrlExists)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"about:blank","title":"Conflict","status":409,"detail":"alias already exists","code":"alias_exists","request_id":"test-request"}`,
+		},
+		{
+			name:        "another owner",
+			requestBody: `{"url": "http://example.com"}`,
+			alias:       "test",
+			setupMock: func(m *MockUrlEditer) {
+				m.On("GetOwner", storage.DefaultWorkspace, "test").Return("dev", nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"url belongs to another owner","code":"not_owner","request_id":"test-request"}`,
+		},
+		{
+			name:        "link not found",
+			requestBody: `{"alias": "spring-sale"}`,
+			alias:       "missing",
+			setupMock: func(m *MockUrlEditer) {
+				m.On("GetOwner", storage.DefaultWorkspace, "missing").Return("", storage.ErrUrlNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"alias not found","code":"alias_not_found","request_id":"test-request"}`,
+		},
+		{
+			name:           "reserved alias",
+			requestBody:    `{"alias": "URL"}`,
+			alias:          "test",
+			setupMock:      func(m *MockUrlEditer) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Alias is not an allowed alias","code":"validation_failed","request_id":"test-request","errors":[{"field":"alias","rule":"alias_reserved","message":"is a reserved alias"}]}`,
+		},
+		{
+			name:           "nothing to update",
+			requestBody:    `{}`,
+			alias:          "test",
+			setupMock:      func(m *MockUrlEditer) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"url or alias is required","code":"validation_failed","request_id":"test-request"}`,
 		},
 	}
 
@@ -75,10 +176,11 @@ func TestUpdateUrlHandler(t *testing.T) {
 
 			r := chi.NewRouter()
 			r.Use(middleware.RequestID)
-			r.Patch("/{alias}", New(log, mockEditer))
+			r.Patch("/{alias}", New(log, mockEditer, testValidator(), ops, defaultWorkspace))
 
 			req, err := http.NewRequest("PATCH", "/"+tt.alias, strings.NewReader(tt.requestBody))
 			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
 
 			rr := httptest.NewRecorder()
 			r.ServeHTTP(rr, req)
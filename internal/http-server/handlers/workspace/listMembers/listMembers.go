@@ -0,0 +1,78 @@
+package listMembers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type MemberLister interface {
+	ListMembers(workspace string) ([]storage.Member, error)
+}
+
+// Member represents a member of the workspace
+// @Description Name of API keys and their role: viewer, editor or admin
+type Member struct {
+	Name string `json:"name" example:"ci"`
+	Role string `json:"role" example:"editor"`
+}
+
+// Response represents member list response
+// @Description Members of the workspace
+// swagger:model
+type Response struct {
+	resp.Response
+	Workspace string   `json:"workspace" example:"docs"`
+	Members   []Member `json:"members"`
+}
+
+// New
+// @Summary List workspace members
+// @Description Lists the members of the workspace by name. Global admin keys are not listed,
+// @Description they are admins of every workspace. Under /w/{workspace}/members for other workspaces.
+// @Tags workspace
+// @Produce  json
+// @Success 200 {object} Response
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "No admin role in the workspace"
+// @Failure 404 {object} resp.Problem "Workspace not found"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /members [get]
+func New(log *slog.Logger, memberLister MemberLister, workspace func(*http.Request) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.workspace.listMembers.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		members, err := memberLister.ListMembers(ws)
+		if err != nil {
+			log.Error("failed to list members", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to list members")
+
+			return
+		}
+
+		response := Response{Response: resp.OK(), Workspace: ws, Members: make([]Member, 0, len(members))}
+		for _, member := range members {
+			response.Members = append(response.Members, Member{
+				Name: member.Name,
+				Role: member.Role,
+			})
+		}
+
+		render.JSON(w, r, response)
+	}
+}
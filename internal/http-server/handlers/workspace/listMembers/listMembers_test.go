@@ -0,0 +1,90 @@
+package listMembers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMemberLister struct {
+	mock.Mock
+}
+
+func (m *MockMemberLister) ListMembers(workspace string) ([]storage.Member, error) {
+	args := m.Called(workspace)
+	members, _ := args.Get(0).([]storage.Member)
+	return members, args.Error(1)
+}
+
+func TestListMembersHandler(t *testing.T) {
+	log := slog.Default()
+
+	tests := []struct {
+		name           string
+		setupMock      func(*MockMemberLister)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			setupMock: func(m *MockMemberLister) {
+				m.On("ListMembers", "docs").Return([]storage.Member{
+					{Workspace: "docs", Name: "ci", Role: "editor"},
+					{Workspace: "docs", Name: "ops", Role: "admin"},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","workspace":"docs","members":[` +
+				`{"name":"ci","role":"editor"},{"name":"ops","role":"admin"}]}`,
+		},
+		{
+			name: "no members",
+			setupMock: func(m *MockMemberLister) {
+				m.On("ListMembers", "docs").Return([]storage.Member{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","workspace":"docs","members":[]}`,
+		},
+		{
+			name: "storage error",
+			setupMock: func(m *MockMemberLister) {
+				m.On("ListMembers", "docs").Return(nil, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to list members","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockLister := new(MockMemberLister)
+			tt.setupMock(mockLister)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Get("/w/{workspace}/members", New(log, mockLister, func(r *http.Request) string { return chi.URLParam(r, "workspace") }))
+
+			req, err := http.NewRequest(http.MethodGet, "/w/docs/members", nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockLister.AssertExpectations(t)
+		})
+	}
+}
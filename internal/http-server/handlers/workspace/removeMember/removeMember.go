@@ -0,0 +1,77 @@
+package removeMember
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type MemberRemover interface {
+	RemoveMember(workspace, name string) error
+}
+
+// Response represents member removal response
+// @Description Success response for member removal
+// swagger:model
+type Response struct {
+	resp.Response
+}
+
+// New
+// @Summary Remove a workspace member
+// @Description Takes the role in the workspace from API keys with the name. Their links stay.
+// @Description Under /w/{workspace}/members/{name} for other workspaces.
+// @Tags workspace
+// @Produce  json
+// @Param name path string true "Name of API keys"
+// @Success 200 {object} Response
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "No admin role in the workspace"
+// @Failure 404 {object} resp.Problem "Workspace or member not found"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /members/{name} [delete]
+func New(log *slog.Logger, memberRemover MemberRemover, workspace func(*http.Request) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.workspace.removeMember.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		name := chi.URLParam(r, "name")
+
+		err := memberRemover.RemoveMember(ws, name)
+		if errors.Is(err, storage.ErrMemberNotFound) {
+			log.Info("member not found", slog.String("name", name))
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeMemberNotFound, "member not found")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to remove member", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to remove member")
+
+			return
+		}
+
+		log.Info("success remove member", slog.String("name", name))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package removeMember
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMemberRemover struct {
+	mock.Mock
+}
+
+func (m *MockMemberRemover) RemoveMember(workspace, name string) error {
+	args := m.Called(workspace, name)
+	return args.Error(0)
+}
+
+func TestRemoveMemberHandler(t *testing.T) {
+	log := slog.Default()
+
+	tests := []struct {
+		name           string
+		setupMock      func(*MockMemberRemover)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			setupMock: func(m *MockMemberRemover) {
+				m.On("RemoveMember", "docs", "ci").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK"}`,
+		},
+		{
+			name: "member not found",
+			setupMock: func(m *MockMemberRemover) {
+				m.On("RemoveMember", "docs", "ci").Return(storage.ErrMemberNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"member not found","code":"member_not_found","request_id":"test-request"}`,
+		},
+		{
+			name: "storage error",
+			setupMock: func(m *MockMemberRemover) {
+				m.On("RemoveMember", "docs", "ci").Return(errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to remove member","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockRemover := new(MockMemberRemover)
+			tt.setupMock(mockRemover)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Delete("/w/{workspace}/members/{name}", New(log, mockRemover, func(r *http.Request) string { return chi.URLParam(r, "workspace") }))
+
+			req, err := http.NewRequest(http.MethodDelete, "/w/docs/members/ci", nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockRemover.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package setMember
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+type MemberSetter interface {
+	SetMember(member storage.Member) error
+}
+
+// Request represents member role request
+// @Description viewer reads links, editor also creates and edits its own,
+// @Description admin also manages all links and the members of the workspace.
+type Request struct {
+	Role string `json:"role" validate:"required,oneof=viewer editor admin" example:"editor"`
+}
+
+// Response represents a saved member
+// @Description The member with its new role
+// swagger:model
+type Response struct {
+	resp.Response
+	Workspace string `json:"workspace" example:"docs"`
+	Name      string `json:"name" example:"ci"`
+	Role      string `json:"role" example:"editor"`
+}
+
+// New
+// @Summary Add a workspace member
+// @Description Gives API keys with the name a role in the workspace, or changes the role
+// @Description they have. Under /w/{workspace}/members/{name} for other workspaces.
+// @Tags workspace
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Name of API keys, see POST /admin/keys"
+// @Param request body Request true "Role"
+// @Success 200 {object} Response
+// @Failure 400 {object} resp.Problem "Invalid request"
+// @Failure 401 {object} resp.Problem "API key required"
+// @Failure 403 {object} resp.Problem "No admin role in the workspace"
+// @Failure 404 {object} resp.Problem "Workspace not found"
+// @Failure 500 {object} resp.Problem "Internal server error"
+// @Security ApiKeyAuth
+// @Router /members/{name} [put]
+func New(log *slog.Logger, memberSetter MemberSetter, workspace func(*http.Request) string) http.HandlerFunc {
+	validate := resp.NewValidator()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.workspace.setMember.New"
+
+		ws := workspace(r)
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("workspace", ws),
+		)
+
+		var req Request
+
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.CodeInvalidBody, "failed to decode request")
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			var validatorErr validator.ValidationErrors
+			errors.As(err, &validatorErr)
+
+			log.Info("failed to validate request", slog.String("error", err.Error()))
+
+			resp.RenderValidationError(w, r, validatorErr)
+
+			return
+		}
+
+		member := storage.Member{Workspace: ws, Name: chi.URLParam(r, "name"), Role: req.Role}
+
+		err := memberSetter.SetMember(member)
+		if errors.Is(err, storage.ErrWorkspaceNotFound) {
+			log.Info("workspace not found")
+
+			resp.RenderError(w, r, http.StatusNotFound, resp.CodeWorkspaceNotFound, "workspace not found")
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to set member", slog.String("error", err.Error()))
+
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "failed to set member")
+
+			return
+		}
+
+		log.Info("success set member", slog.String("name", member.Name), slog.String("role", member.Role))
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			Workspace: member.Workspace,
+			Name:      member.Name,
+			Role:      member.Role,
+		})
+	}
+}
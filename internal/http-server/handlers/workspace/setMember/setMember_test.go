@@ -0,0 +1,106 @@
+package setMember
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMemberSetter struct {
+	mock.Mock
+}
+
+func (m *MockMemberSetter) SetMember(member storage.Member) error {
+	args := m.Called(member)
+	return args.Error(0)
+}
+
+func TestSetMemberHandler(t *testing.T) {
+	log := slog.Default()
+
+	tests := []struct {
+		name           string
+		body           string
+		setupMock      func(*MockMemberSetter)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			body: `{"role":"editor"}`,
+			setupMock: func(m *MockMemberSetter) {
+				m.On("SetMember", storage.Member{Workspace: "docs", Name: "ci", Role: "editor"}).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","workspace":"docs","name":"ci","role":"editor"}`,
+		},
+		{
+			name:           "invalid json",
+			body:           `{"role":`,
+			setupMock:      func(m *MockMemberSetter) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"failed to decode request","code":"invalid_body","request_id":"test-request"}`,
+		},
+		{
+			name:           "unknown role",
+			body:           `{"role":"owner"}`,
+			setupMock:      func(m *MockMemberSetter) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Role is not valid","code":"validation_failed","request_id":"test-request",` +
+				`"errors":[{"field":"role","rule":"oneof","message":"must be one of: viewer, editor, admin","param":"viewer editor admin"}]}`,
+		},
+		{
+			name: "workspace not found",
+			body: `{"role":"viewer"}`,
+			setupMock: func(m *MockMemberSetter) {
+				m.On("SetMember", storage.Member{Workspace: "docs", Name: "ci", Role: "viewer"}).Return(storage.ErrWorkspaceNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"workspace not found","code":"workspace_not_found","request_id":"test-request"}`,
+		},
+		{
+			name: "storage error",
+			body: `{"role":"admin"}`,
+			setupMock: func(m *MockMemberSetter) {
+				m.On("SetMember", storage.Member{Workspace: "docs", Name: "ci", Role: "admin"}).Return(errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"failed to set member","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockSetter := new(MockMemberSetter)
+			tt.setupMock(mockSetter)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Put("/w/{workspace}/members/{name}", New(log, mockSetter, func(r *http.Request) string { return chi.URLParam(r, "workspace") }))
+
+			req, err := http.NewRequest(http.MethodPut, "/w/docs/members/ci", bytes.NewBufferString(tt.body))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockSetter.AssertExpectations(t)
+		})
+	}
+}
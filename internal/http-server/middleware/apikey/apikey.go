@@ -0,0 +1,118 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+// HeaderAPIKey carries the key for clients that cannot set Authorization.
+const HeaderAPIKey = "X-API-Key"
+
+// Authenticator checks keys, see auth.Keys.
+type Authenticator interface {
+	Authenticate(plain string) (storage.APIKey, error)
+}
+
+type keyCtx struct{}
+
+// New lets requests through if they carry a key that allows scope, in
+// "Authorization: Bearer <key>" or in X-API-Key. The key and its principal
+// are put into the context of the request, see FromContext. A request
+// authenticated before, e.g. by jwtauth.New, only has its scope checked.
+func New(log *slog.Logger, keys Authenticator, scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/apikey"),
+			slog.String("scope", scope),
+		)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(slog.String("request_id", middleware.GetReqID(r.Context())))
+
+			if p, ok := auth.FromContext(r.Context()); ok {
+				if !p.Allows(scope) {
+					log.Info("token lacks scope", slog.String("name", p.Name))
+
+					resp.RenderError(w, r, http.StatusForbidden, resp.CodeForbidden, "token has no "+scope+" scope")
+
+					return
+				}
+
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			plain := keyOf(r)
+			if plain == "" {
+				log.Info("no api key")
+
+				w.Header().Set("WWW-Authenticate", `Bearer realm="url-shortener"`)
+				resp.RenderError(w, r, http.StatusUnauthorized, resp.CodeUnauthorized, "api key required")
+
+				return
+			}
+
+			key, err := keys.Authenticate(plain)
+			if errors.Is(err, auth.ErrInvalidKey) {
+				log.Info("invalid api key")
+
+				w.Header().Set("WWW-Authenticate", `Bearer realm="url-shortener", error="invalid_token"`)
+				resp.RenderError(w, r, http.StatusUnauthorized, resp.CodeUnauthorized, "invalid api key")
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to authenticate", slog.String("error", err.Error()))
+
+				resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+				return
+			}
+
+			if !auth.Allows(key, scope) {
+				log.Info("api key lacks scope", slog.Int64("key_id", key.ID))
+
+				resp.RenderError(w, r, http.StatusForbidden, resp.CodeForbidden, "api key has no "+scope+" scope")
+
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), keyCtx{}, key)
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(ctx, auth.PrincipalOf(key))))
+		})
+	}
+}
+
+// FromContext returns the key the request was authenticated with.
+func FromContext(ctx context.Context) (storage.APIKey, bool) {
+	key, ok := ctx.Value(keyCtx{}).(storage.APIKey)
+
+	return key, ok
+}
+
+// Principal returns the principal of the key or token the request was
+// authenticated with. A request without either gets a principal
+// without a name and rights.
+func Principal(r *http.Request) auth.Principal {
+	p, _ := auth.FromContext(r.Context())
+
+	return p
+}
+
+func keyOf(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+
+	return strings.TrimSpace(r.Header.Get(HeaderAPIKey))
+}
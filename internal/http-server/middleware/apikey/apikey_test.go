@@ -0,0 +1,179 @@
+package apikey
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuthenticator struct {
+	mock.Mock
+}
+
+func (m *MockAuthenticator) Authenticate(plain string) (storage.APIKey, error) {
+	args := m.Called(plain)
+	return args.Get(0).(storage.APIKey), args.Error(1)
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	log := slog.Default()
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		setupMock      func(*MockAuthenticator)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:    "bearer key",
+			headers: map[string]string{"Authorization": "Bearer us_1_good"},
+			setupMock: func(m *MockAuthenticator) {
+				m.On("Authenticate", "us_1_good").Return(storage.APIKey{ID: 1, Scopes: []string{auth.ScopeWrite}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"key_id":1}`,
+		},
+		{
+			name:    "x-api-key header",
+			headers: map[string]string{HeaderAPIKey: "us_2_good"},
+			setupMock: func(m *MockAuthenticator) {
+				m.On("Authenticate", "us_2_good").Return(storage.APIKey{ID: 2, Scopes: []string{auth.ScopeAdmin}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"key_id":2}`,
+		},
+		{
+			name:           "no key",
+			headers:        map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			setupMock:      func(m *MockAuthenticator) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"api key required","code":"unauthorized","request_id":"test-request"}`,
+		},
+		{
+			name:    "invalid key",
+			headers: map[string]string{"Authorization": "Bearer us_1_bad"},
+			setupMock: func(m *MockAuthenticator) {
+				m.On("Authenticate", "us_1_bad").Return(storage.APIKey{}, auth.ErrInvalidKey)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"invalid api key","code":"unauthorized","request_id":"test-request"}`,
+		},
+		{
+			// ключ только на чтение не пускает к записи
+			name:    "missing scope",
+			headers: map[string]string{"Authorization": "Bearer us_3_read"},
+			setupMock: func(m *MockAuthenticator) {
+				m.On("Authenticate", "us_3_read").Return(storage.APIKey{ID: 3, Scopes: []string{auth.ScopeRead}}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"api key has no write scope","code":"forbidden","request_id":"test-request"}`,
+		},
+		{
+			name:    "storage error",
+			headers: map[string]string{"Authorization": "Bearer us_4_good"},
+			setupMock: func(m *MockAuthenticator) {
+				m.On("Authenticate", "us_4_good").Return(storage.APIKey{}, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := new(MockAuthenticator)
+			tt.setupMock(mockAuth)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.With(New(log, mockAuth, auth.ScopeWrite)).Post("/url", func(w http.ResponseWriter, r *http.Request) {
+				key, ok := FromContext(r.Context())
+				assert.True(t, ok)
+				_, _ = fmt.Fprintf(w, `{"key_id":%d}`, key.ID)
+			})
+
+			req, err := http.NewRequest(http.MethodPost, "/url", nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+			}
+
+			mockAuth.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAPIKeyMiddlewareAuthenticatedBefore(t *testing.T) {
+	tests := []struct {
+		name           string
+		principal      auth.Principal
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "token with scope",
+			principal:      auth.NewPrincipal("gw-user", []string{auth.ScopeWrite}),
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"principal":"gw-user"}`,
+		},
+		{
+			name:           "token without scope",
+			principal:      auth.NewPrincipal("gw-user", []string{auth.ScopeRead}),
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"token has no write scope","code":"forbidden","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ключ не проверяется, если запрос уже аутентифицирован
+			mockAuth := new(MockAuthenticator)
+
+			authenticated := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), tt.principal)))
+				})
+			}
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.With(authenticated, New(slog.Default(), mockAuth, auth.ScopeWrite)).Post("/url", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprintf(w, `{"principal":%q}`, Principal(r).Name)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/url", nil)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockAuth.AssertNotCalled(t, "Authenticate", mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Package jwtauth authenticates requests with JWTs next to API keys.
+package jwtauth
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+)
+
+// Verifier checks tokens, see token.Verifier.
+type Verifier interface {
+	Verify(raw string) (auth.Principal, error)
+}
+
+// New puts the principal of a JWT in "Authorization: Bearer <token>"
+// into the context of the request, see auth.FromContext. Requests with
+// an invalid token are rejected, requests without one pass as they are:
+// API keys do not look like JWTs and are checked by apikey.New, which
+// also checks the scopes of tokens.
+func New(log *slog.Logger, verifier Verifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(slog.String("component", "middleware/jwtauth"))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, ok := tokenOf(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			log := log.With(slog.String("request_id", middleware.GetReqID(r.Context())))
+
+			p, err := verifier.Verify(raw)
+			if err != nil {
+				// the reason is for the log only, see token.ErrInvalidToken
+				log.Info("invalid token", slog.String("error", err.Error()))
+
+				w.Header().Set("WWW-Authenticate", `Bearer realm="url-shortener", error="invalid_token"`)
+				resp.RenderError(w, r, http.StatusUnauthorized, resp.CodeUnauthorized, "invalid token")
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+		})
+	}
+}
+
+// tokenOf returns the bearer token of r if it has the three parts of a
+// JWT.
+func tokenOf(r *http.Request) (string, bool) {
+	scheme, raw, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	raw = strings.TrimSpace(raw)
+
+	return raw, strings.Count(raw, ".") == 2
+}
@@ -0,0 +1,104 @@
+package jwtauth
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/auth/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockVerifier struct {
+	mock.Mock
+}
+
+func (m *MockVerifier) Verify(raw string) (auth.Principal, error) {
+	args := m.Called(raw)
+	return args.Get(0).(auth.Principal), args.Error(1)
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	log := slog.Default()
+
+	tests := []struct {
+		name           string
+		authorization  string
+		setupMock      func(*MockVerifier)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:          "valid token",
+			authorization: "Bearer aaa.bbb.ccc",
+			setupMock: func(m *MockVerifier) {
+				m.On("Verify", "aaa.bbb.ccc").Return(auth.NewPrincipal("gw-user", []string{auth.ScopeRead}), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"principal":"gw-user"}`,
+		},
+		{
+			name:          "invalid token",
+			authorization: "Bearer aaa.bbb.bad",
+			setupMock: func(m *MockVerifier) {
+				m.On("Verify", "aaa.bbb.bad").Return(auth.Principal{}, fmt.Errorf("%w: token is expired", token.ErrInvalidToken))
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"invalid token","code":"unauthorized","request_id":"test-request"}`,
+		},
+		{
+			// API-ключ проверяет apikey.New
+			name:           "api key",
+			authorization:  "Bearer us_1a2b3c4d_ff",
+			setupMock:      func(m *MockVerifier) {},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"principal":""}`,
+		},
+		{
+			name:           "no authorization",
+			setupMock:      func(m *MockVerifier) {},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"principal":""}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockVerifier := new(MockVerifier)
+			tt.setupMock(mockVerifier)
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.With(New(log, mockVerifier)).Get("/url", func(w http.ResponseWriter, r *http.Request) {
+				p, _ := auth.FromContext(r.Context())
+				_, _ = fmt.Fprintf(w, `{"principal":%q}`, p.Name)
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "/url", nil)
+			assert.NoError(t, err)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "invalid_token")
+			}
+
+			mockVerifier.AssertExpectations(t)
+		})
+	}
+}
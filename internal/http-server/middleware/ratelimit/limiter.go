@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit lets a client make Requests requests per Per on average and up
+// to Burst at once.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	// Burst is Requests when not set.
+	Burst int
+}
+
+// Result is the state of the bucket of a client after a request.
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket, Remaining the requests left in it.
+	Limit     int
+	Remaining int
+	// RetryAfter is the wait for the next request, zero if it is allowed
+	// already. Reset is the wait until the bucket is full again.
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Limiter keeps a token bucket per client. Buckets idle long enough to
+// fill up are dropped, they are no different from new ones, so memory
+// holds only the clients of the last refill period.
+type Limiter struct {
+	rate  float64 // tokens per second
+	burst float64
+	// idle is the time an empty bucket takes to fill up
+	idle time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter of limit, Requests and Per must be positive.
+func NewLimiter(limit Limit) *Limiter {
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Requests
+	}
+
+	rate := float64(limit.Requests) / limit.Per.Seconds()
+
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		idle:    time.Duration(float64(burst) / rate * float64(time.Second)),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of client.
+func (l *Limiter) Allow(client string) Result {
+	return l.take(client, 1)
+}
+
+// Peek tells whether client has a token left without taking it.
+func (l *Limiter) Peek(client string) Result {
+	return l.take(client, 0)
+}
+
+func (l *Limiter) take(client string, cost float64) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	res := Result{Limit: int(l.burst)}
+
+	if b.tokens >= 1 {
+		b.tokens -= cost
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.wait(1 - b.tokens)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = l.wait(l.burst - b.tokens)
+
+	return res
+}
+
+// Len returns the number of clients with a bucket.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets)
+}
+
+// sweep drops the buckets that are full by now, once per idle period.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.idle {
+		return
+	}
+
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= l.idle {
+			delete(l.buckets, client)
+		}
+	}
+
+	l.swept = now
+}
+
+// wait returns the time it takes to get tokens.
+func (l *Limiter) wait(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
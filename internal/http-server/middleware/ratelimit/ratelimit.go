@@ -0,0 +1,120 @@
+// Package ratelimit limits the requests of every client with a token
+// bucket: an API key, the user of a token or the IP of an anonymous
+// request.
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/http-server/middleware/apikey"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+)
+
+// New lets requests through while the bucket of their client has tokens
+// and replies 429 with Retry-After otherwise. Every reply gets the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+// client names the client of a request, see Client.
+func New(log *slog.Logger, limiter *Limiter, client func(*http.Request) string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(slog.String("component", "middleware/ratelimit"))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := client(r)
+			res := limiter.Allow(c)
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", seconds(res.Reset))
+
+			if !res.Allowed {
+				log.Info("rate limit exceeded",
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					slog.String("client", c),
+				)
+
+				h.Set("Retry-After", seconds(res.RetryAfter))
+				resp.RenderError(w, r, http.StatusTooManyRequests, resp.CodeRateLimited, "too many requests")
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Guard goes in front of authentication and limits the requests that
+// fail it, so that keys cannot be guessed at any rate. A request from an
+// IP without tokens left is rejected before its key is checked, every
+// reply asking for credentials takes a token. Authenticated requests
+// take none, clients behind one IP are limited by New only.
+func Guard(log *slog.Logger, limiter *Limiter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(slog.String("component", "middleware/ratelimit"))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := "ip:" + IP(r)
+
+			if res := limiter.Peek(ip); !res.Allowed {
+				log.Info("too many failed authentications",
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					slog.String("client", ip),
+				)
+
+				h := w.Header()
+				h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+				h.Set("RateLimit-Remaining", "0")
+				h.Set("RateLimit-Reset", seconds(res.Reset))
+				h.Set("Retry-After", seconds(res.RetryAfter))
+				resp.RenderError(w, r, http.StatusTooManyRequests, resp.CodeRateLimited, "too many requests")
+
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			// legacy errors come with 200, the challenge is sent anyway
+			if ww.Status() == http.StatusUnauthorized || ww.Header().Get("WWW-Authenticate") != "" {
+				limiter.Allow(ip)
+			}
+		})
+	}
+}
+
+// Client names the client of r: the API key or the token user it was
+// authenticated with, or its IP. middleware.RealIP is expected to have
+// put the IP of the client behind a proxy into RemoteAddr.
+func Client(r *http.Request) string {
+	if key, ok := apikey.FromContext(r.Context()); ok {
+		return "key:" + strconv.FormatInt(key.ID, 10)
+	}
+
+	if p, ok := auth.FromContext(r.Context()); ok && p.Name != "" {
+		return "user:" + p.Name
+	}
+
+	return "ip:" + IP(r)
+}
+
+// IP returns the IP of r without the port.
+func IP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// seconds rounds d up, headers count whole seconds.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
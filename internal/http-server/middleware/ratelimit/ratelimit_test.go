@@ -0,0 +1,264 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/http-server/middleware/apikey"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time { return c.t }
+
+func newLimiter(limit Limit) (*Limiter, *clock) {
+	c := &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLimiter(limit)
+	l.now = c.now
+
+	return l, c
+}
+
+func TestLimiter(t *testing.T) {
+	// 1 запрос в секунду, до 3 подряд
+	l, c := newLimiter(Limit{Requests: 60, Per: time.Minute, Burst: 3})
+
+	for i := 2; i >= 0; i-- {
+		res := l.Allow("a")
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res := l.Allow("a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	// у другого клиента своя корзина
+	assert.True(t, l.Allow("b").Allowed)
+
+	c.t = c.t.Add(1500 * time.Millisecond)
+	res = l.Allow("a")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 2500*time.Millisecond, res.Reset)
+
+	res = l.Allow("a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	// корзина не переполняется
+	c.t = c.t.Add(time.Hour)
+	assert.Equal(t, 2, l.Allow("a").Remaining)
+}
+
+func TestLimiterBurstDefault(t *testing.T) {
+	l, _ := newLimiter(Limit{Requests: 2, Per: time.Minute})
+
+	assert.True(t, l.Allow("a").Allowed)
+	assert.True(t, l.Allow("a").Allowed)
+
+	res := l.Allow("a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 30*time.Second, res.RetryAfter)
+}
+
+func TestLimiterEvictsIdleClients(t *testing.T) {
+	l, c := newLimiter(Limit{Requests: 10, Per: 10 * time.Second})
+
+	l.Allow("a")
+	l.Allow("b")
+	assert.Equal(t, 2, l.Len())
+
+	c.t = c.t.Add(5 * time.Second)
+	l.Allow("b")
+
+	// "a" простаивает дольше времени наполнения корзины, "b" ещё нет
+	c.t = c.t.Add(6 * time.Second)
+	l.Allow("c")
+	assert.Equal(t, 2, l.Len())
+
+	c.t = c.t.Add(time.Minute)
+	l.Allow("d")
+	assert.Equal(t, 1, l.Len())
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	l, c := newLimiter(Limit{Requests: 1, Per: 10 * time.Second, Burst: 2})
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.With(New(slog.Default(), l, Client)).Post("/url", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	do := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := do("10.0.0.1:5000")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "10", rr.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, rr.Header().Get("Retry-After"))
+
+	// другой порт того же адреса — тот же клиент
+	assert.Equal(t, http.StatusCreated, do("10.0.0.1:5001").Code)
+
+	c.t = c.t.Add(2 * time.Second)
+	rr = do("10.0.0.1:5000")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "8", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "18", rr.Header().Get("RateLimit-Reset"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"too many requests","code":"rate_limited","request_id":"test-request"}`, rr.Body.String())
+
+	assert.Equal(t, http.StatusCreated, do("10.0.0.2:5000").Code)
+}
+
+func TestGuard(t *testing.T) {
+	l, c := newLimiter(Limit{Requests: 1, Per: 10 * time.Second, Burst: 3})
+
+	// ключ "good" проходит, остальные получают 401
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-API-Key") != "good" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.With(Guard(slog.Default(), l), authenticate).Post("/url", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	do := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		req.Header.Set(middleware.RequestIDHeader, "test-request")
+		req.Header.Set("X-API-Key", key)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// успешные запросы токены не тратят
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusCreated, do("good").Code)
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, do("guess").Code)
+	}
+
+	rr := do("guess")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "10", rr.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"too many requests","code":"rate_limited","request_id":"test-request"}`, rr.Body.String())
+
+	// перебор с этого IP останавливается до проверки ключа
+	assert.Equal(t, http.StatusTooManyRequests, do("good").Code)
+
+	c.t = c.t.Add(10 * time.Second)
+	assert.Equal(t, http.StatusUnauthorized, do("guess").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("guess").Code)
+}
+
+func TestGuardLegacyErrors(t *testing.T) {
+	l, _ := newLimiter(Limit{Requests: 1, Per: 10 * time.Second, Burst: 2})
+
+	// в режиме совместимости 401 приходит со статусом 200, но с вызовом
+	challenge := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="url-shortener"`)
+		_, _ = w.Write([]byte(`{"status":"Error","error":"api key required"}`))
+	})
+
+	h := middleware.RequestID(Guard(slog.Default(), l)(challenge))
+
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		codes = append(codes, rr.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestClient(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/url", nil)
+	req.RemoteAddr = "[2001:db8::1]:443"
+	assert.Equal(t, "ip:2001:db8::1", Client(req))
+
+	req = req.WithContext(auth.NewContext(context.Background(), auth.NewPrincipal("gw-user", nil)))
+	assert.Equal(t, "user:gw-user", Client(req))
+}
+
+type keys struct{}
+
+func (keys) Authenticate(plain string) (storage.APIKey, error) {
+	if plain != "good" {
+		return storage.APIKey{}, auth.ErrInvalidKey
+	}
+
+	return storage.APIKey{ID: 1, Name: "reader", Scopes: []string{auth.ScopeRead}}, nil
+}
+
+func TestGuardReadEndpoints(t *testing.T) {
+	l, _ := newLimiter(Limit{Requests: 1, Per: 10 * time.Second, Burst: 3})
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.With(Guard(slog.Default(), l), apikey.New(slog.Default(), keys{}, auth.ScopeRead)).Get("/url", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	do := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/url", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		req.Header.Set(apikey.HeaderAPIKey, key)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("good"))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, do("guess"))
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, do("guess"))
+	assert.Equal(t, http.StatusTooManyRequests, do("good"))
+}
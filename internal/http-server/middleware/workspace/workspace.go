@@ -0,0 +1,169 @@
+// Package workspace tells the workspace of a request and checks the role
+// of the caller in it.
+package workspace
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	resp "github.com/popvaleks/url-shortener/internal/lib/api/response"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+// Param is the path parameter with the workspace, routes are mounted
+// under "/w/{workspace}".
+const Param = "workspace"
+
+// Members finds workspaces and their members, see storage.Repository.
+type Members interface {
+	GetWorkspace(name string) (storage.Workspace, error)
+	GetMember(workspace, name string) (storage.Member, error)
+}
+
+// Resolver tells the workspace of a request.
+type Resolver struct {
+	// hosts maps lower-cased hosts without a port to workspaces
+	hosts map[string]string
+}
+
+// NewResolver returns a resolver that serves hosts from their workspaces,
+// e.g. "docs.example.com" from "docs".
+func NewResolver(hosts map[string]string) *Resolver {
+	lower := make(map[string]string, len(hosts))
+	for host, ws := range hosts {
+		lower[strings.ToLower(host)] = ws
+	}
+
+	return &Resolver{hosts: lower}
+}
+
+// Of returns the workspace of r: the one in the path, the one of the
+// host or storage.DefaultWorkspace.
+func (res *Resolver) Of(r *http.Request) string {
+	if ws := chi.URLParam(r, Param); ws != "" {
+		return ws
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if ws, ok := res.hosts[strings.ToLower(host)]; ok {
+		return ws
+	}
+
+	return storage.DefaultWorkspace
+}
+
+type roleCtx struct{}
+
+// New lets requests through if the caller has role in the workspace of
+// the request. Global admins are admins of every workspace. A token may
+// carry its role in a workspace. In the default workspace a principal
+// without a membership has the role of its scopes, in other workspaces
+// it has no role.
+func New(
+	log *slog.Logger,
+	members Members,
+	resolver *Resolver,
+	principal func(*http.Request) auth.Principal,
+	role string,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/workspace"),
+			slog.String("role", role),
+		)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ws := resolver.Of(r)
+
+			log := log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("workspace", ws),
+			)
+
+			if _, err := members.GetWorkspace(ws); err != nil {
+				if errors.Is(err, storage.ErrWorkspaceNotFound) {
+					log.Info("workspace not found")
+
+					resp.RenderError(w, r, http.StatusNotFound, resp.CodeWorkspaceNotFound, "workspace not found")
+
+					return
+				}
+
+				log.Error("failed to get workspace", slog.String("error", err.Error()))
+
+				resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+				return
+			}
+
+			caller := principal(r)
+
+			granted, err := roleOf(members, ws, caller)
+			if err != nil {
+				log.Error("failed to get member", slog.String("error", err.Error()))
+
+				resp.RenderError(w, r, http.StatusInternalServerError, resp.CodeInternal, "internal server error")
+
+				return
+			}
+
+			if !auth.RoleAllows(granted, role) {
+				log.Info("caller lacks role", slog.String("name", caller.Name), slog.String("granted", granted))
+
+				resp.RenderError(w, r, http.StatusForbidden, resp.CodeForbidden, "no "+role+" role in the workspace")
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), roleCtx{}, granted)))
+		})
+	}
+}
+
+// roleOf returns the role of caller in ws, empty for none.
+func roleOf(members Members, ws string, caller auth.Principal) (string, error) {
+	if caller.Admin {
+		return auth.RoleAdmin, nil
+	}
+
+	if caller.Workspace == ws && caller.WorkspaceRole != "" {
+		return caller.WorkspaceRole, nil
+	}
+
+	member, err := members.GetMember(ws, caller.Name)
+	switch {
+	case err == nil:
+		return member.Role, nil
+	case !errors.Is(err, storage.ErrMemberNotFound):
+		return "", err
+	case ws == storage.DefaultWorkspace:
+		return caller.Role, nil
+	default:
+		return "", nil
+	}
+}
+
+// Principal wraps principal for the handlers behind New: admins of the
+// workspace of the request manage all its links like global admins do.
+func Principal(principal func(*http.Request) auth.Principal) func(*http.Request) auth.Principal {
+	return func(r *http.Request) auth.Principal {
+		p := principal(r)
+
+		if role, _ := r.Context().Value(roleCtx{}).(string); role == auth.RoleAdmin {
+			p.Admin = true
+		}
+
+		return p
+	}
+}
@@ -0,0 +1,164 @@
+package workspace
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/popvaleks/url-shortener/internal/auth"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver(t *testing.T) {
+	resolver := NewResolver(map[string]string{"Docs.Example.com": "docs"})
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) { _, _ = fmt.Fprint(w, resolver.Of(r)) })
+	r.Get("/w/{workspace}/{alias}", func(w http.ResponseWriter, r *http.Request) { _, _ = fmt.Fprint(w, resolver.Of(r)) })
+
+	tests := []struct {
+		host, path string
+		expected   string
+	}{
+		{"short.example.com", "/abc", storage.DefaultWorkspace},
+		{"docs.example.com:8080", "/abc", "docs"},
+		{"DOCS.example.com", "/abc", "docs"},
+		{"short.example.com", "/w/blog/abc", "blog"},
+		// путь главнее хоста
+		{"docs.example.com", "/w/blog/abc", "blog"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Host = tt.host
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, tt.expected, rr.Body.String(), "%s%s", tt.host, tt.path)
+	}
+}
+
+func TestWorkspaceMiddleware(t *testing.T) {
+	repo := memory.New()
+	require.NoError(t, repo.CreateWorkspace(storage.Workspace{Name: "docs", CreatedAt: time.Now()}))
+	require.NoError(t, repo.SetMember(storage.Member{Workspace: "docs", Name: "writer", Role: auth.RoleEditor}))
+	require.NoError(t, repo.SetMember(storage.Member{Workspace: "docs", Name: "reader", Role: auth.RoleViewer}))
+	require.NoError(t, repo.SetMember(storage.Member{Workspace: "docs", Name: "lead", Role: auth.RoleAdmin}))
+
+	tests := []struct {
+		name           string
+		path           string
+		caller         auth.Principal
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "editor member",
+			path:           "/w/docs/url",
+			caller:         auth.Principal{Name: "writer", Role: auth.RoleViewer},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"admin":false}`,
+		},
+		{
+			name:           "workspace admin manages all links",
+			path:           "/w/docs/url",
+			caller:         auth.Principal{Name: "lead", Role: auth.RoleEditor},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"admin":true}`,
+		},
+		{
+			name:           "global admin",
+			path:           "/w/docs/url",
+			caller:         auth.Principal{Name: "root", Admin: true, Role: auth.RoleAdmin},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"admin":true}`,
+		},
+		{
+			name:           "viewer member",
+			path:           "/w/docs/url",
+			caller:         auth.Principal{Name: "reader", Role: auth.RoleEditor},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"no editor role in the workspace","code":"forbidden","request_id":"test-request"}`,
+		},
+		{
+			// роль из прав ключа действует только в пространстве по умолчанию
+			name:           "not a member",
+			path:           "/w/docs/url",
+			caller:         auth.Principal{Name: "ops", Role: auth.RoleEditor},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"no editor role in the workspace","code":"forbidden","request_id":"test-request"}`,
+		},
+		{
+			// роль из токена не требует участника в хранилище
+			name:           "token role",
+			path:           "/w/docs/url",
+			caller:         auth.Principal{Name: "gw-user", Role: auth.RoleViewer, Workspace: "docs", WorkspaceRole: auth.RoleEditor},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"admin":false}`,
+		},
+		{
+			name:           "token role of another workspace",
+			path:           "/w/docs/url",
+			caller:         auth.Principal{Name: "gw-user", Role: auth.RoleEditor, Workspace: "blog", WorkspaceRole: auth.RoleAdmin},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"no editor role in the workspace","code":"forbidden","request_id":"test-request"}`,
+		},
+		{
+			name:           "default workspace by scope",
+			path:           "/url",
+			caller:         auth.Principal{Name: "ops", Role: auth.RoleEditor},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"admin":false}`,
+		},
+		{
+			name:           "default workspace without the scope",
+			path:           "/url",
+			caller:         auth.Principal{Name: "ci", Role: auth.RoleViewer},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"type":"about:blank","title":"Forbidden","status":403,"detail":"no editor role in the workspace","code":"forbidden","request_id":"test-request"}`,
+		},
+		{
+			name:           "unknown workspace",
+			path:           "/w/blog/url",
+			caller:         auth.Principal{Name: "root", Admin: true, Role: auth.RoleAdmin},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"workspace not found","code":"workspace_not_found","request_id":"test-request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal := func(*http.Request) auth.Principal { return tt.caller }
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprintf(w, `{"admin":%t}`, Principal(principal)(r).Admin)
+			}
+
+			routes := func(r chi.Router) {
+				r.With(New(slog.Default(), repo, NewResolver(nil), principal, auth.RoleEditor)).Post("/url", handler)
+			}
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			routes(r)
+			r.Route("/w/{workspace}", routes)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Header.Set(middleware.RequestIDHeader, "test-request")
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
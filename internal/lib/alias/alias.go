@@ -0,0 +1,155 @@
+// Package alias makes aliases for links saved without one and checks
+// the ones chosen by users.
+//
+// A Generator produces candidates of a given length, a Source wraps it
+// with the retry policy: a candidate taken by another link is replaced
+// by a new one, and repeated collisions make every later alias longer,
+// as they mean the namespace of the current length is getting dense.
+// A Policy holds the rules for chosen aliases, generated ones are only
+// kept clear of reserved words, profanity and lookalikes.
+package alias
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+const (
+	KindRandom   = "random"
+	KindSequence = "sequence"
+	KindHashids  = "hashids"
+	KindWords    = "words"
+)
+
+// DefaultAlphabet is base62.
+const DefaultAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// MaxLength caps the growth of aliases.
+const MaxLength = 32
+
+// ErrExhausted is returned by Source.Save when every attempt collided
+// and by Source.Next when the policy rejected every candidate.
+var ErrExhausted = errors.New("no free alias found")
+
+// Generator makes alias candidates.
+type Generator interface {
+	// Generate returns a candidate of at least length characters.
+	Generate(length int) (string, error)
+}
+
+// NewGenerator returns a generator of kind over alphabet. salt is only
+// used by hashids. Sequences start from the current time in milliseconds,
+// so that a restarted server does not walk through the aliases it has
+// already issued.
+func NewGenerator(kind, alphabet, salt string) (Generator, error) {
+	start := uint64(time.Now().UnixMilli())
+
+	switch kind {
+	case KindRandom:
+		return NewRandom(alphabet)
+	case KindSequence:
+		return NewSequence(alphabet, start)
+	case KindHashids:
+		return NewHashids(alphabet, salt, start)
+	case KindWords:
+		return NewWords(), nil
+	}
+
+	return nil, fmt.Errorf("unknown alias generator %q", kind)
+}
+
+// Options configure a Source.
+type Options struct {
+	// Length is the initial length of aliases.
+	Length int
+	// MaxAttempts is the number of candidates tried for one link.
+	MaxAttempts int
+	// GrowAfter is the number of collisions of one link after which
+	// the length grows by one for every later alias.
+	GrowAfter int
+	// Policy, if set, rejects candidates that fail CheckGenerated,
+	// they are replaced by new ones of the same length.
+	Policy *Policy
+}
+
+// Source hands out aliases of a length that grows with collisions.
+// It is safe for concurrent use.
+type Source struct {
+	gen    Generator
+	opts   Options
+	length atomic.Int64
+}
+
+func NewSource(gen Generator, opts Options) *Source {
+	s := &Source{gen: gen, opts: opts}
+	s.length.Store(int64(opts.Length))
+
+	return s
+}
+
+// Length returns the current length of aliases.
+func (s *Source) Length() int {
+	return int(s.length.Load())
+}
+
+// MaxAttempts returns the number of candidates tried for one link.
+func (s *Source) MaxAttempts() int {
+	return s.opts.MaxAttempts
+}
+
+// Next returns a candidate for a link whose previous candidates
+// collided attempt times. Up to MaxAttempts candidates are made until
+// one passes the policy.
+func (s *Source) Next(attempt int) (string, error) {
+	length := s.length.Load()
+
+	if attempt > 0 && attempt%s.opts.GrowAfter == 0 && length < MaxLength {
+		// of the links colliding at once only one makes the alias longer
+		if s.length.CompareAndSwap(length, length+1) {
+			length++
+		} else {
+			length = s.length.Load()
+		}
+	}
+
+	for i := 0; i < s.opts.MaxAttempts; i++ {
+		alias, err := s.gen.Generate(int(length))
+		if err != nil {
+			return "", err
+		}
+
+		if s.opts.Policy == nil || s.opts.Policy.CheckGenerated(alias) == nil {
+			return alias, nil
+		}
+	}
+
+	return "", ErrExhausted
+}
+
+// Save calls save with candidates until it does not return
+// storage.ErrUrlExists and returns the accepted alias. Any other error
+// of save is returned as is, ErrExhausted after MaxAttempts collisions.
+func (s *Source) Save(save func(alias string) error) (string, error) {
+	for attempt := 0; attempt < s.opts.MaxAttempts; attempt++ {
+		alias, err := s.Next(attempt)
+		if err != nil {
+			return "", err
+		}
+
+		err = save(alias)
+		if errors.Is(err, storage.ErrUrlExists) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return alias, nil
+	}
+
+	return "", ErrExhausted
+}
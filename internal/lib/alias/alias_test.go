@@ -0,0 +1,207 @@
+package alias
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerators(t *testing.T) {
+	for _, kind := range []string{KindRandom, KindSequence, KindHashids, KindWords} {
+		t.Run(kind, func(t *testing.T) {
+			gen, err := NewGenerator(kind, DefaultAlphabet, "salt")
+			require.NoError(t, err)
+
+			const n = 10000
+
+			var (
+				mu   sync.Mutex
+				seen = make(map[string]bool, n)
+				wg   sync.WaitGroup
+			)
+
+			// генераторы вызываются из параллельных запросов
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					for range n / 8 {
+						alias, err := gen.Generate(8)
+						assert.NoError(t, err)
+						assert.GreaterOrEqual(t, len(alias), 8)
+
+						if kind != KindWords {
+							for _, c := range alias {
+								assert.True(t, strings.ContainsRune(DefaultAlphabet, c), alias)
+							}
+						}
+
+						mu.Lock()
+						seen[alias] = true
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			if kind == KindWords {
+				assert.Greater(t, len(seen), n*99/100)
+			} else {
+				assert.Len(t, seen, n, "aliases do not repeat")
+			}
+		})
+	}
+}
+
+func TestSequence(t *testing.T) {
+	gen, err := NewSequence("0123456789", 98)
+	require.NoError(t, err)
+
+	var aliases []string
+	for range 3 {
+		alias, err := gen.Generate(3)
+		require.NoError(t, err)
+		aliases = append(aliases, alias)
+	}
+
+	assert.Equal(t, []string{"098", "099", "100"}, aliases)
+
+	alias, err := gen.Generate(2)
+	require.NoError(t, err)
+	assert.Equal(t, "101", alias, "length is the minimum")
+}
+
+func TestHashids(t *testing.T) {
+	first, err := NewHashids(DefaultAlphabet, "one", 1)
+	require.NoError(t, err)
+	second, err := NewHashids(DefaultAlphabet, "two", 1)
+	require.NoError(t, err)
+
+	a1, _ := first.Generate(6)
+	a2, _ := first.Generate(6)
+	b1, _ := second.Generate(6)
+
+	assert.Len(t, a1, 6)
+	assert.NotEqual(t, a1[:5], a2[:5], "consecutive aliases look unrelated")
+	assert.NotEqual(t, a1, b1, "the salt changes aliases")
+
+	again, _ := NewHashids(DefaultAlphabet, "one", 1)
+	a1again, _ := again.Generate(6)
+	assert.Equal(t, a1, a1again, "the same salt gives the same aliases")
+
+	_, err = NewHashids("abc", "", 1)
+	assert.Error(t, err)
+}
+
+func TestWords(t *testing.T) {
+	alias, err := NewWords().Generate(7)
+	require.NoError(t, err)
+	require.Len(t, alias, 7)
+
+	for i, c := range alias {
+		letters := consonants
+		if i%2 == 1 {
+			letters = vowels
+		}
+		assert.True(t, strings.ContainsRune(letters, c), alias)
+	}
+}
+
+func TestInvalidAlphabet(t *testing.T) {
+	_, err := NewGenerator(KindRandom, "aab", "")
+	assert.Error(t, err)
+
+	_, err = NewGenerator(KindSequence, "a", "")
+	assert.Error(t, err)
+
+	_, err = NewGenerator("uuid", DefaultAlphabet, "")
+	assert.Error(t, err)
+}
+
+// fixed repeats "a" to the requested length and records the lengths.
+type fixed struct {
+	lengths []int
+}
+
+func (f *fixed) Generate(length int) (string, error) {
+	f.lengths = append(f.lengths, length)
+	return strings.Repeat("a", length), nil
+}
+
+// queue returns its aliases in order, then "z" repeated to the length.
+type queue struct {
+	aliases []string
+}
+
+func (q *queue) Generate(length int) (string, error) {
+	if len(q.aliases) == 0 {
+		return strings.Repeat("z", length), nil
+	}
+
+	alias := q.aliases[0]
+	q.aliases = q.aliases[1:]
+
+	return alias, nil
+}
+
+func TestSourcePolicy(t *testing.T) {
+	policy := NewPolicy(PolicyOptions{MinLength: 1, MaxLength: 32, Charset: DefaultCharset})
+	policy.Reserve("admin")
+
+	gen := &queue{aliases: []string{"admin", "sh1t", "bavoki"}}
+	s := NewSource(gen, Options{Length: 6, MaxAttempts: 3, GrowAfter: 1, Policy: policy})
+
+	alias, err := s.Save(func(alias string) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, "bavoki", alias, "reserved and profane candidates are replaced")
+	assert.Equal(t, 6, s.Length(), "rejected candidates are not collisions")
+
+	// генератор, выдающий только зарезервированное слово, исчерпывает попытки
+	gen.aliases = []string{"admin", "admin", "admin"}
+	_, err = s.Save(func(alias string) error {
+		t.Fatalf("rejected alias %q is saved", alias)
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrExhausted)
+}
+
+func TestSourceRetriesAndGrows(t *testing.T) {
+	gen := &fixed{}
+	s := NewSource(gen, Options{Length: 4, MaxAttempts: 5, GrowAfter: 2})
+
+	taken := 3
+	alias, err := s.Save(func(alias string) error {
+		if taken > 0 {
+			taken--
+			return storage.ErrUrlExists
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{4, 4, 5, 5}, gen.lengths, "two collisions make aliases longer")
+	assert.Equal(t, "aaaaa", alias)
+	assert.Equal(t, 5, s.Length(), "the length stays grown for later links")
+}
+
+func TestSourceExhausted(t *testing.T) {
+	s := NewSource(&fixed{}, Options{Length: 4, MaxAttempts: 3, GrowAfter: 10})
+
+	calls := 0
+	_, err := s.Save(func(alias string) error {
+		calls++
+		return storage.ErrUrlExists
+	})
+	assert.ErrorIs(t, err, ErrExhausted)
+	assert.Equal(t, 3, calls)
+
+	failure := errors.New("database error")
+	_, err = s.Save(func(alias string) error { return failure })
+	assert.ErrorIs(t, err, failure, "other errors are not retried")
+}
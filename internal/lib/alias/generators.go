@@ -0,0 +1,228 @@
+package alias
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"sync/atomic"
+)
+
+func checkAlphabet(alphabet string, minSize int) ([]rune, error) {
+	chars := []rune(alphabet)
+
+	seen := make(map[rune]bool, len(chars))
+	for _, c := range chars {
+		if seen[c] {
+			return nil, errors.New("alias alphabet has repeated characters")
+		}
+		seen[c] = true
+	}
+
+	if len(chars) < minSize {
+		return nil, errors.New("alias alphabet is too short")
+	}
+
+	return chars, nil
+}
+
+// randomIndex returns a uniformly distributed number in [0, n).
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(i.Int64()), nil
+}
+
+// Random picks every character with crypto/rand.
+type Random struct {
+	alphabet []rune
+}
+
+func NewRandom(alphabet string) (*Random, error) {
+	chars, err := checkAlphabet(alphabet, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Random{alphabet: chars}, nil
+}
+
+func (g *Random) Generate(length int) (string, error) {
+	b := make([]rune, length)
+
+	for i := range b {
+		n, err := randomIndex(len(g.alphabet))
+		if err != nil {
+			return "", err
+		}
+		b[i] = g.alphabet[n]
+	}
+
+	return string(b), nil
+}
+
+// Sequence encodes an increasing counter in the base of its alphabet,
+// left-padded with the first character. Aliases are short and never
+// repeat within a process, but reveal the order of links.
+type Sequence struct {
+	alphabet []rune
+	next     atomic.Uint64
+}
+
+func NewSequence(alphabet string, start uint64) (*Sequence, error) {
+	chars, err := checkAlphabet(alphabet, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Sequence{alphabet: chars}
+	g.next.Store(start)
+
+	return g, nil
+}
+
+func (g *Sequence) Generate(length int) (string, error) {
+	encoded := encode(g.next.Add(1)-1, g.alphabet)
+
+	if pad := length - len(encoded); pad > 0 {
+		encoded = append([]rune(strings.Repeat(string(g.alphabet[0]), pad)), encoded...)
+	}
+
+	return string(encoded), nil
+}
+
+// encode writes n in the base of alphabet, most significant digit first.
+func encode(n uint64, alphabet []rune) []rune {
+	base := uint64(len(alphabet))
+
+	var digits []rune
+	for {
+		digits = append(digits, alphabet[n%base])
+		n /= base
+
+		if n == 0 {
+			break
+		}
+	}
+
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+
+	return digits
+}
+
+// Hashids encodes a counter like Sequence, but with an alphabet
+// shuffled by the salt and by the counter itself, as hashids does,
+// so that consecutive aliases look unrelated.
+//
+// One character of the shuffled alphabet is a guard that the encoded
+// counter never contains. Padding to the length goes before the last
+// guard, which keeps padded aliases unique.
+type Hashids struct {
+	alphabet []rune
+	salt     []rune
+	guard    rune
+	next     atomic.Uint64
+}
+
+func NewHashids(alphabet, salt string, start uint64) (*Hashids, error) {
+	chars, err := checkAlphabet(alphabet, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	shuffled := shuffle(chars, []rune(salt))
+
+	g := &Hashids{
+		alphabet: shuffled[:len(shuffled)-1],
+		salt:     []rune(salt),
+		guard:    shuffled[len(shuffled)-1],
+	}
+	g.next.Store(start)
+
+	return g, nil
+}
+
+func (g *Hashids) Generate(length int) (string, error) {
+	n := g.next.Add(1) - 1
+
+	lottery := g.alphabet[n%uint64(len(g.alphabet))]
+
+	buffer := append([]rune{lottery}, g.salt...)
+	buffer = append(buffer, g.alphabet...)
+
+	alphabet := shuffle(g.alphabet, buffer[:len(g.alphabet)])
+	hash := append([]rune{lottery}, encode(n, alphabet)...)
+
+	if len(hash) >= length {
+		return string(hash), nil
+	}
+
+	padding := make([]rune, 0, length)
+	for len(padding) < length-len(hash)-1 {
+		alphabet = shuffle(alphabet, alphabet)
+		padding = append(padding, alphabet...)
+	}
+	padding = append(padding[:length-len(hash)-1], g.guard)
+
+	return string(append(padding, hash...)), nil
+}
+
+// shuffle is the consistent shuffle of hashids: the same salt
+// always gives the same permutation.
+func shuffle(alphabet, salt []rune) []rune {
+	result := make([]rune, len(alphabet))
+	copy(result, alphabet)
+
+	if len(salt) == 0 {
+		return result
+	}
+
+	for i, v, p := len(result)-1, 0, 0; i > 0; i-- {
+		v %= len(salt)
+		integer := int(salt[v])
+		p += integer
+		j := (integer + v + p) % i
+		result[i], result[j] = result[j], result[i]
+		v++
+	}
+
+	return result
+}
+
+const (
+	consonants = "bdfgklmnprstvz"
+	vowels     = "aeiou"
+)
+
+// Words makes pronounceable aliases of alternating lower-case
+// consonants and vowels, e.g. "bavokite". They are easy to read out
+// but have less entropy per character than Random.
+type Words struct{}
+
+func NewWords() Words {
+	return Words{}
+}
+
+func (Words) Generate(length int) (string, error) {
+	b := make([]byte, length)
+
+	for i := range b {
+		letters := consonants
+		if i%2 == 1 {
+			letters = vowels
+		}
+
+		n, err := randomIndex(len(letters))
+		if err != nil {
+			return "", err
+		}
+		b[i] = letters[n]
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,231 @@
+package alias
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// DefaultCharset is the characters allowed in aliases chosen by users.
+// Dots are left out, the router treats ".json" and alike as a format.
+const DefaultCharset = DefaultAlphabet + "-_"
+
+// DefaultProfanity is used when PolicyOptions.Profanity is empty.
+var DefaultProfanity = []string{
+	"fuck", "shit", "cunt", "bitch", "whore", "slut", "bastard", "asshole",
+	"nigger", "faggot", "retard", "wank", "blyat", "pizda", "mudak",
+}
+
+var (
+	ErrLength    = errors.New("alias length is out of bounds")
+	ErrCharset   = errors.New("alias has characters that are not allowed")
+	ErrReserved  = errors.New("alias is reserved")
+	ErrProfane   = errors.New("alias is profane")
+	ErrAmbiguous = errors.New("alias mixes lookalike characters")
+)
+
+// lookalikes fold characters that read alike into one, so that "5wagger"
+// is as reserved as "swagger" and "sh1t" as profane as "shit".
+var lookalikes = strings.NewReplacer(
+	"0", "o", "1", "l", "i", "l", "|", "l", "3", "e", "4", "a", "@", "a",
+	"5", "s", "$", "s", "7", "t", "8", "b", "9", "g",
+	// separators do not make a word different
+	"-", "", "_", "", ".", "",
+)
+
+// ambiguous groups characters that are hard to tell apart in most fonts.
+// An alias with two characters of one group cannot be read out reliably.
+var ambiguous = []string{"0O", "1lI|", "5S", "2Z", "8B"}
+
+// PolicyOptions configure a Policy.
+type PolicyOptions struct {
+	MinLength int
+	MaxLength int
+	// Charset is the characters allowed in aliases.
+	Charset string
+	// Reserved aliases are rejected whatever their case and lookalikes.
+	Reserved []string
+	// Profanity is rejected anywhere in an alias.
+	Profanity []string
+	// RejectAmbiguous rejects aliases like "l1nk" or "C0OL" that mix
+	// lookalike characters.
+	RejectAmbiguous bool
+}
+
+// Policy decides which aliases users may choose. Aliases made by
+// a Generator only go through CheckGenerated.
+type Policy struct {
+	opts    PolicyOptions
+	charset map[rune]bool
+
+	mu       sync.RWMutex
+	reserved map[string]bool
+	profane  []string
+}
+
+func NewPolicy(opts PolicyOptions) *Policy {
+	if len(opts.Profanity) == 0 {
+		opts.Profanity = DefaultProfanity
+	}
+
+	p := &Policy{
+		opts:     opts,
+		charset:  make(map[rune]bool, len(opts.Charset)),
+		reserved: make(map[string]bool, len(opts.Reserved)),
+	}
+
+	for _, c := range opts.Charset {
+		p.charset[c] = true
+	}
+
+	for _, word := range opts.Profanity {
+		if folded := fold(word); folded != "" {
+			p.profane = append(p.profane, folded)
+		}
+	}
+
+	p.Reserve(opts.Reserved...)
+
+	return p
+}
+
+// Reserve adds words to the reserved aliases, e.g. the paths of routes.
+func (p *Policy) Reserve(words ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, word := range words {
+		if folded := fold(word); folded != "" {
+			p.reserved[folded] = true
+		}
+	}
+}
+
+// Check returns nil if alias may be chosen or the rule it breaks.
+func (p *Policy) Check(alias string) error {
+	if n := len([]rune(alias)); n < p.opts.MinLength || n > p.opts.MaxLength {
+		return ErrLength
+	}
+
+	for _, rule := range p.rules() {
+		if !rule.ok(alias) {
+			return rule.err
+		}
+	}
+
+	return nil
+}
+
+// CheckGenerated returns nil if a generated alias may be handed out or
+// the rule it breaks. The generator is trusted with the length and the
+// charset, the reserved, profane and ambiguous aliases are rejected.
+func (p *Policy) CheckGenerated(alias string) error {
+	for _, rule := range p.wordRules() {
+		if !rule.ok(alias) {
+			return rule.err
+		}
+	}
+
+	return nil
+}
+
+type rule struct {
+	tag string
+	err error
+	ok  func(alias string) bool
+}
+
+// rules are the checks of Check after the length, in order.
+func (p *Policy) rules() []rule {
+	return append([]rule{{"alias_charset", ErrCharset, p.inCharset}}, p.wordRules()...)
+}
+
+// wordRules are the checks of what an alias reads as.
+func (p *Policy) wordRules() []rule {
+	return []rule{
+		{"alias_reserved", ErrReserved, p.notReserved},
+		{"alias_profane", ErrProfane, p.notProfane},
+		{"alias_ambiguous", ErrAmbiguous, p.notAmbiguous},
+	}
+}
+
+func (p *Policy) inCharset(alias string) bool {
+	for _, c := range alias {
+		if !p.charset[c] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Policy) notReserved(alias string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return !p.reserved[fold(alias)]
+}
+
+func (p *Policy) notProfane(alias string) bool {
+	folded := fold(alias)
+	for _, word := range p.profane {
+		if strings.Contains(folded, word) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Policy) notAmbiguous(alias string) bool {
+	return !p.opts.RejectAmbiguous || !isAmbiguous(alias)
+}
+
+// Register makes the policy the "alias" validation of v. The validation
+// is an alias of min, max and an "alias_*" tag per rule, so that
+// ActualTag of a failure names the rule that was broken.
+func (p *Policy) Register(v *validator.Validate) error {
+	tags := []string{
+		fmt.Sprintf("min=%d", p.opts.MinLength),
+		fmt.Sprintf("max=%d", p.opts.MaxLength),
+	}
+
+	for _, rule := range p.rules() {
+		ok := rule.ok
+		if err := v.RegisterValidation(rule.tag, func(fl validator.FieldLevel) bool {
+			return ok(fl.Field().String())
+		}); err != nil {
+			return err
+		}
+
+		tags = append(tags, rule.tag)
+	}
+
+	v.RegisterAlias("alias", strings.Join(tags, ","))
+
+	return nil
+}
+
+func fold(s string) string {
+	return lookalikes.Replace(strings.ToLower(s))
+}
+
+func isAmbiguous(alias string) bool {
+	for _, group := range ambiguous {
+		found := 0
+		for _, c := range group {
+			if strings.ContainsRune(alias, c) {
+				found++
+			}
+		}
+
+		if found > 1 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package alias
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	p := NewPolicy(PolicyOptions{
+		MinLength: 3,
+		MaxLength: 10,
+		Charset:   DefaultCharset,
+		Reserved:  []string{"api"},
+	})
+	p.Reserve("url", "swagger", "admin")
+
+	tests := []struct {
+		alias string
+		err   error
+	}{
+		{"my-link", nil},
+		{"Team_42", nil},
+		{"ab", ErrLength},
+		{"much-too-long", ErrLength},
+		{"привет", ErrCharset},
+		{"a.json", ErrCharset},
+		{"a b", ErrCharset},
+		{"url", ErrReserved},
+		{"URL", ErrReserved},
+		{"api", ErrReserved},
+		// похожие символы и разделители не делают слово другим
+		{"5wagger", ErrReserved},
+		{"adm1n", ErrReserved},
+		{"sw-agger", ErrReserved},
+		{"urls", nil},
+		{"holyshit", ErrProfane},
+		{"sh1t", ErrProfane},
+		{"B1tch", ErrProfane},
+		{"l1nk", nil},
+	}
+
+	for _, tt := range tests {
+		if tt.err == nil {
+			assert.NoError(t, p.Check(tt.alias), tt.alias)
+		} else {
+			assert.ErrorIs(t, p.Check(tt.alias), tt.err, tt.alias)
+		}
+	}
+}
+
+func TestPolicyAmbiguous(t *testing.T) {
+	p := NewPolicy(PolicyOptions{MinLength: 1, MaxLength: 32, Charset: DefaultCharset, RejectAmbiguous: true})
+
+	assert.ErrorIs(t, p.Check("l1nk"), ErrAmbiguous)
+	assert.ErrorIs(t, p.Check("C0OL"), ErrAmbiguous)
+	assert.NoError(t, p.Check("link2"), "one character of a group is fine")
+	assert.NoError(t, p.Check("C00L"))
+}
+
+func TestPolicyProfanityList(t *testing.T) {
+	p := NewPolicy(PolicyOptions{MinLength: 1, MaxLength: 32, Charset: DefaultCharset, Profanity: []string{"darn"}})
+
+	assert.ErrorIs(t, p.Check("d4rn-it"), ErrProfane)
+	assert.NoError(t, p.Check("shit"), "the list replaces the default one")
+}
+
+func TestPolicyCheckGenerated(t *testing.T) {
+	p := NewPolicy(PolicyOptions{MinLength: 3, MaxLength: 10, Charset: "abc", RejectAmbiguous: true})
+	p.Reserve("url")
+
+	assert.ErrorIs(t, p.CheckGenerated("URL"), ErrReserved)
+	assert.ErrorIs(t, p.CheckGenerated("xsh1tx"), ErrProfane)
+	assert.ErrorIs(t, p.CheckGenerated("l1nk"), ErrAmbiguous)
+	assert.NoError(t, p.CheckGenerated("Xy9_long-generated"), "length and charset are up to the generator")
+}
+
+func TestPolicyRegister(t *testing.T) {
+	p := NewPolicy(PolicyOptions{MinLength: 3, MaxLength: 10, Charset: DefaultCharset})
+	p.Reserve("url")
+
+	v := validator.New()
+	require.NoError(t, p.Register(v))
+
+	type request struct {
+		Alias string `validate:"omitempty,alias"`
+	}
+
+	assert.NoError(t, v.Struct(request{}))
+	assert.NoError(t, v.Struct(request{Alias: "my-link"}))
+	assert.Error(t, v.Struct(request{Alias: "url"}))
+
+	// ActualTag names the broken rule
+	for alias, tag := range map[string]string{
+		"ab":            "min",
+		"way-too-long1": "max",
+		"my/link":       "alias_charset",
+		"URL":           "alias_reserved",
+		"a-shit":        "alias_profane",
+	} {
+		var errs validator.ValidationErrors
+		require.ErrorAs(t, v.Struct(request{Alias: alias}), &errs, alias)
+		assert.Equal(t, "alias", errs[0].Tag(), alias)
+		assert.Equal(t, tag, errs[0].ActualTag(), alias)
+	}
+}
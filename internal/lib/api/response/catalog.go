@@ -0,0 +1,189 @@
+package response
+
+import "github.com/go-playground/locales"
+
+// Keys of the messages about fields. {0} is the field in summaries and
+// the param of the rule in field messages.
+const (
+	msgSummaryRequired = "summary.required"
+	msgSummaryUrl      = "summary.url"
+	msgSummaryAlias    = "summary.alias"
+	msgSummaryInvalid  = "summary.invalid"
+
+	msgFieldRequired       = "field.required"
+	msgFieldUrl            = "field.url"
+	msgFieldMinString      = "field.min.string"
+	msgFieldMaxString      = "field.max.string"
+	msgFieldMinItems       = "field.min.items"
+	msgFieldMaxItems       = "field.max.items"
+	msgFieldMinNumber      = "field.min.number"
+	msgFieldMaxNumber      = "field.max.number"
+	msgFieldOneOf          = "field.oneof"
+	msgFieldExcludesAll    = "field.excludesall"
+	msgFieldExcludedWith   = "field.excluded_with"
+	msgFieldAliasCharset   = "field.alias_charset"
+	msgFieldAliasReserved  = "field.alias_reserved"
+	msgFieldAliasProfane   = "field.alias_profane"
+	msgFieldAliasAmbiguous = "field.alias_ambiguous"
+	msgFieldInvalid        = "field.invalid"
+)
+
+var catalogEn = catalog{
+	messages: map[string]string{
+		// old clients parse these, keep them as they are
+		msgSummaryRequired: "field {0} is a required field",
+		msgSummaryUrl:      "field {0} is not a valid URL",
+		msgSummaryAlias:    "field {0} is not an allowed alias",
+		msgSummaryInvalid:  "field {0} is not valid",
+
+		msgFieldRequired:       "is required",
+		msgFieldUrl:            "must be a valid URL",
+		msgFieldMinNumber:      "must be at least {0}",
+		msgFieldMaxNumber:      "must be at most {0}",
+		msgFieldOneOf:          "must be one of: {0}",
+		msgFieldExcludesAll:    "must not contain any of the characters {0}",
+		msgFieldExcludedWith:   "must not be set together with {0}",
+		msgFieldAliasCharset:   "has characters that are not allowed in an alias",
+		msgFieldAliasReserved:  "is a reserved alias",
+		msgFieldAliasProfane:   "has a word that is not allowed in an alias",
+		msgFieldAliasAmbiguous: "mixes characters that look alike",
+		msgFieldInvalid:        "is not valid",
+	},
+	cardinals: map[string]map[locales.PluralRule]string{
+		msgFieldMinString: {
+			locales.PluralRuleOne:   "must be at least {0} character long",
+			locales.PluralRuleOther: "must be at least {0} characters long",
+		},
+		msgFieldMaxString: {
+			locales.PluralRuleOne:   "must be at most {0} character long",
+			locales.PluralRuleOther: "must be at most {0} characters long",
+		},
+		msgFieldMinItems: {
+			locales.PluralRuleOne:   "must have at least {0} item",
+			locales.PluralRuleOther: "must have at least {0} items",
+		},
+		msgFieldMaxItems: {
+			locales.PluralRuleOne:   "must have at most {0} item",
+			locales.PluralRuleOther: "must have at most {0} items",
+		},
+	},
+}
+
+var catalogRu = catalog{
+	messages: map[string]string{
+		msgSummaryRequired: "поле {0} обязательно",
+		msgSummaryUrl:      "поле {0} не является корректным URL",
+		msgSummaryAlias:    "поле {0} не является допустимым алиасом",
+		msgSummaryInvalid:  "поле {0} заполнено неверно",
+
+		msgFieldRequired:       "обязательное поле",
+		msgFieldUrl:            "должно быть корректным URL",
+		msgFieldMinNumber:      "должно быть не меньше {0}",
+		msgFieldMaxNumber:      "должно быть не больше {0}",
+		msgFieldOneOf:          "должно быть одним из: {0}",
+		msgFieldExcludesAll:    "не должно содержать символы {0}",
+		msgFieldExcludedWith:   "нельзя передавать вместе с {0}",
+		msgFieldAliasCharset:   "содержит символы, недопустимые в алиасе",
+		msgFieldAliasReserved:  "этот алиас зарезервирован",
+		msgFieldAliasProfane:   "содержит недопустимое слово",
+		msgFieldAliasAmbiguous: "смешивает похожие символы",
+		msgFieldInvalid:        "заполнено неверно",
+
+		"internal server error":                 "внутренняя ошибка сервера",
+		"failed to decode request":              "не удалось разобрать запрос",
+		"url not found":                         "ссылка не найдена",
+		"alias not found":                       "алиас не найден",
+		"tag not found":                         "тег не найден",
+		"url expired":                           "срок действия ссылки истёк",
+		"click limit reached":                   "достигнут лимит переходов",
+		"alias not allowed":                     "недопустимый алиас",
+		"alias or tag not allowed":              "недопустимый алиас или тег",
+		"alias already exists":                  "алиас уже занят",
+		"aliases already exist":                 "алиасы уже заняты",
+		"url already exists":                    "ссылка уже существует",
+		"url or alias is required":              "нужно указать url или alias",
+		"tag is required":                       "нужно указать тег",
+		"failed to save url":                    "не удалось сохранить ссылку",
+		"failed to save urls":                   "не удалось сохранить ссылки",
+		"failed to update url":                  "не удалось обновить ссылку",
+		"failed to generate alias":              "не удалось сгенерировать алиас",
+		"failed to import urls":                 "не удалось импортировать ссылки",
+		"failed to read file":                   "не удалось прочитать файл",
+		"failed to create backup":               "не удалось создать бэкап",
+		"file must be at most 64 MB":            "файл должен быть не больше 64 МБ",
+		"batch not saved":                       "пакет не сохранён",
+		"not saved because another item failed": "не сохранено из-за ошибки в другом элементе",
+		"api key required":                      "нужен API-ключ",
+		"invalid api key":                       "неверный API-ключ",
+		"api key has no read scope":             "у API-ключа нет права read",
+		"api key has no write scope":            "у API-ключа нет права write",
+		"api key has no admin scope":            "у API-ключа нет права admin",
+		"api key not found":                     "API-ключ не найден",
+		"invalid token":                         "неверный токен",
+		"token has no read scope":               "у токена нет права read",
+		"token has no write scope":              "у токена нет права write",
+		"token has no admin scope":              "у токена нет права admin",
+		"too many requests":                     "слишком много запросов",
+		"failed to create api key":              "не удалось создать API-ключ",
+		"failed to list api keys":               "не удалось получить API-ключи",
+		"failed to revoke api key":              "не удалось отозвать API-ключ",
+		"id must be a number":                   "id должен быть числом",
+		"url belongs to another owner":          "ссылка принадлежит другому владельцу",
+		"failed to transfer url":                "не удалось передать ссылку",
+		"workspace not found":                   "рабочее пространство не найдено",
+		"workspace already exists":              "рабочее пространство уже существует",
+		"member not found":                      "участник не найден",
+		"no viewer role in the workspace":       "нет роли viewer в рабочем пространстве",
+		"no editor role in the workspace":       "нет роли editor в рабочем пространстве",
+		"no admin role in the workspace":        "нет роли admin в рабочем пространстве",
+		"failed to create workspace":            "не удалось создать рабочее пространство",
+		"failed to list workspaces":             "не удалось получить рабочие пространства",
+		"failed to list members":                "не удалось получить участников",
+		"failed to set member":                  "не удалось сохранить участника",
+		"failed to remove member":               "не удалось удалить участника",
+
+		"only admins can overwrite links on import":  "перезаписывать ссылки при импорте может только администратор",
+		"only admins can list links of other owners": "ссылки других владельцев может смотреть только администратор",
+
+		"q is required":                                        "нужно указать q",
+		"limit must be a number from 1 to 100":                 "limit должен быть числом от 1 до 100",
+		"limit must be a number from 1 to 1000":                "limit должен быть числом от 1 до 1000",
+		"offset must be a non-negative number":                 "offset должен быть неотрицательным числом",
+		"sort must be created or alias":                        "sort должен быть created или alias",
+		"order must be asc or desc":                            "order должен быть asc или desc",
+		"invalid cursor":                                       "некорректный cursor",
+		"cursor was made for another sort or order":            "cursor получен для другой сортировки или порядка",
+		"format must be csv or ndjson":                         "format должен быть csv или ndjson",
+		"on_conflict must be fail, skip or overwrite":          "on_conflict должен быть fail, skip или overwrite",
+		"dry_run must be true or false":                        "dry_run должен быть true или false",
+		"ttl must be a positive duration like 90m, 168h or 7d": "ttl должен быть положительной длительностью, например 90m, 168h или 7d",
+		"expires_at must be in the future":                     "expires_at должен быть в будущем",
+	},
+	// after "не короче" and alike the noun is genitive: 1 символа, 5 символов
+	cardinals: map[string]map[locales.PluralRule]string{
+		msgFieldMinString: {
+			locales.PluralRuleOne:   "должно быть не короче {0} символа",
+			locales.PluralRuleFew:   "должно быть не короче {0} символов",
+			locales.PluralRuleMany:  "должно быть не короче {0} символов",
+			locales.PluralRuleOther: "должно быть не короче {0} символа",
+		},
+		msgFieldMaxString: {
+			locales.PluralRuleOne:   "должно быть не длиннее {0} символа",
+			locales.PluralRuleFew:   "должно быть не длиннее {0} символов",
+			locales.PluralRuleMany:  "должно быть не длиннее {0} символов",
+			locales.PluralRuleOther: "должно быть не длиннее {0} символа",
+		},
+		msgFieldMinItems: {
+			locales.PluralRuleOne:   "должно содержать не меньше {0} элемента",
+			locales.PluralRuleFew:   "должно содержать не меньше {0} элементов",
+			locales.PluralRuleMany:  "должно содержать не меньше {0} элементов",
+			locales.PluralRuleOther: "должно содержать не меньше {0} элемента",
+		},
+		msgFieldMaxItems: {
+			locales.PluralRuleOne:   "должно содержать не больше {0} элемента",
+			locales.PluralRuleFew:   "должно содержать не больше {0} элементов",
+			locales.PluralRuleMany:  "должно содержать не больше {0} элементов",
+			locales.PluralRuleOther: "должно содержать не больше {0} элемента",
+		},
+	},
+}
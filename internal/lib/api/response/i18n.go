@@ -0,0 +1,150 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+)
+
+// Languages of the catalog. English is the fallback.
+const (
+	LangEn = "en"
+	LangRu = "ru"
+)
+
+// catalog holds the messages of one language.
+type catalog struct {
+	// messages with {0}-like params. Messages of the handlers are keyed
+	// by their English text and need no English entry.
+	messages map[string]string
+	// cardinals are messages that agree with the number in {0}
+	cardinals map[string]map[locales.PluralRule]string
+}
+
+var translators = newTranslators(map[locales.Translator]catalog{
+	en.New(): catalogEn,
+	ru.New(): catalogRu,
+})
+
+func newTranslators(catalogs map[locales.Translator]catalog) *ut.UniversalTranslator {
+	fallback := en.New()
+	supported := make([]locales.Translator, 0, len(catalogs))
+	for locale := range catalogs {
+		supported = append(supported, locale)
+	}
+
+	uni := ut.New(fallback, supported...)
+
+	for locale, c := range catalogs {
+		trans, _ := uni.GetTranslator(locale.Locale())
+
+		for key, text := range c.messages {
+			if err := trans.Add(key, text, false); err != nil {
+				panic(fmt.Sprintf("catalog %s: %v", locale.Locale(), err))
+			}
+		}
+
+		for key, texts := range c.cardinals {
+			for rule, text := range texts {
+				if err := trans.AddCardinal(key, text, rule, false); err != nil {
+					panic(fmt.Sprintf("catalog %s: %v", locale.Locale(), err))
+				}
+			}
+		}
+	}
+
+	if err := uni.VerifyTranslations(); err != nil {
+		panic(err)
+	}
+
+	return uni
+}
+
+// Translator returns the translator for the language r prefers
+// in Accept-Language, English if none is known.
+func Translator(r *http.Request) ut.Translator {
+	trans, _ := translators.FindTranslator(acceptedLanguages(r)...)
+
+	return trans
+}
+
+// T translates msg, an English message of the catalog, into the language
+// of r. Messages missing from the catalog stay in English.
+func T(r *http.Request, msg string) string {
+	return translate(Translator(r), msg)
+}
+
+func translate(trans ut.Translator, key string, params ...string) string {
+	if text, err := trans.T(key, params...); err == nil {
+		return text
+	}
+
+	if text, err := translators.GetFallback().T(key, params...); err == nil {
+		return text
+	}
+
+	return key
+}
+
+// translateCount translates a cardinal message for the number n.
+func translateCount(trans ut.Translator, key, n string) string {
+	num, _ := strconv.ParseFloat(n, 64)
+
+	if text, err := trans.C(key, num, 0, n); err == nil {
+		return text
+	}
+
+	text, _ := translators.GetFallback().C(key, num, 0, n)
+
+	return text
+}
+
+// acceptedLanguages lists the locales of the Accept-Language header of r,
+// most preferred first: "ru-RU;q=0.9, en" gives en, ru_ru, ru.
+func acceptedLanguages(r *http.Request) []string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+
+	var langs []lang
+
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		langs = append(langs, lang{tag: strings.ToLower(strings.ReplaceAll(tag, "-", "_")), q: q})
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	locales := make([]string, 0, 2*len(langs))
+	for _, l := range langs {
+		locales = append(locales, l.tag)
+		if base, _, found := strings.Cut(l.tag, "_"); found {
+			locales = append(locales, base)
+		}
+	}
+
+	return locales
+}
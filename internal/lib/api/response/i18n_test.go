@@ -0,0 +1,92 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptedLanguages(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected []string
+	}{
+		{header: "", expected: []string{}},
+		{header: "ru", expected: []string{"ru"}},
+		{header: "ru-RU,ru;q=0.9,en;q=0.8", expected: []string{"ru_ru", "ru", "ru", "en"}},
+		{header: "de;q=0.5, en-GB", expected: []string{"en_gb", "en", "de"}},
+		{header: "ru;q=0, *", expected: []string{}},
+		{header: "ru;q=abc, en", expected: []string{"en"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", tt.header)
+
+			assert.Equal(t, tt.expected, acceptedLanguages(req))
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		msg            string
+		expected       string
+	}{
+		{name: "no header", msg: "url not found", expected: "url not found"},
+		{name: "russian", acceptLanguage: "ru", msg: "url not found", expected: "ссылка не найдена"},
+		{name: "region", acceptLanguage: "ru-BY", msg: "url not found", expected: "ссылка не найдена"},
+		{name: "unknown language", acceptLanguage: "de", msg: "url not found", expected: "url not found"},
+		{name: "preferred language", acceptLanguage: "en;q=0.5, ru", msg: "url not found", expected: "ссылка не найдена"},
+		// нет перевода - остается английский
+		{name: "missing key", acceptLanguage: "ru", msg: "line 3: invalid json", expected: "line 3: invalid json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+
+			assert.Equal(t, tt.expected, T(req, tt.msg))
+		})
+	}
+}
+
+func TestTranslateCount(t *testing.T) {
+	ru, _ := translators.GetTranslator(LangRu)
+	en, _ := translators.GetTranslator(LangEn)
+
+	assert.Equal(t, "must be at least 1 character long", translateCount(en, msgFieldMinString, "1"))
+	assert.Equal(t, "must be at least 3 characters long", translateCount(en, msgFieldMinString, "3"))
+	assert.Equal(t, "должно быть не короче 1 символа", translateCount(ru, msgFieldMinString, "1"))
+	assert.Equal(t, "должно быть не короче 3 символов", translateCount(ru, msgFieldMinString, "3"))
+	assert.Equal(t, "должно быть не короче 21 символа", translateCount(ru, msgFieldMinString, "21"))
+	assert.Equal(t, "должно содержать не больше 20 элементов", translateCount(ru, msgFieldMaxItems, "20"))
+}
+
+func TestCatalogsHaveSameKeys(t *testing.T) {
+	for key := range catalogEn.messages {
+		assert.Contains(t, catalogRu.messages, key)
+	}
+
+	for key := range catalogEn.cardinals {
+		assert.Contains(t, catalogRu.cardinals, key)
+	}
+}
+
+func TestRenderErrorTranslated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept-Language", "ru")
+
+	rr := httptest.NewRecorder()
+	RenderError(rr, req, http.StatusNotFound, CodeUrlNotFound, "url not found")
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, LangRu, rr.Header().Get("Content-Language"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"ссылка не найдена","code":"url_not_found"}`, rr.Body.String())
+}
@@ -1,16 +1,24 @@
 package response
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	"mime"
+	"net/http"
+	"reflect"
 	"strings"
 )
 
 // Generic API response
 // swagger:model
 type Response struct {
-	Status string `json:"status"`
-	Error  string `json:"error,omitempty"`
+	Status string       `json:"status"`
+	Error  string       `json:"error,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
 }
 
 const (
@@ -18,6 +26,63 @@ const (
 	StatusError = "Error"
 )
 
+// ContentTypeProblem is the media type of Problem, RFC 7807.
+const ContentTypeProblem = "application/problem+json"
+
+// Codes of problems. They are part of the API: clients match on them,
+// so a code is never renamed, only added.
+const (
+	CodeInvalidBody       = "invalid_body"
+	CodeInvalidQuery      = "invalid_query"
+	CodeInvalidFile       = "invalid_file"
+	CodeFileTooLarge      = "file_too_large"
+	CodeValidationFailed  = "validation_failed"
+	CodeUnauthorized      = "unauthorized"
+	CodeForbidden         = "forbidden"
+	CodeNotOwner          = "not_owner"
+	CodeUrlNotFound       = "url_not_found"
+	CodeAliasNotFound     = "alias_not_found"
+	CodeTagNotFound       = "tag_not_found"
+	CodeAPIKeyNotFound    = "api_key_not_found"
+	CodeWorkspaceNotFound = "workspace_not_found"
+	CodeMemberNotFound    = "member_not_found"
+	CodeAliasExists       = "alias_exists"
+	CodeWorkspaceExists   = "workspace_exists"
+	CodeUrlExpired        = "url_expired"
+	CodeClickLimitReached = "click_limit_reached"
+	CodeAliasExhausted    = "alias_exhausted"
+	CodeRateLimited       = "rate_limited"
+	CodeInternal          = "internal_error"
+)
+
+// Problem is an error response in the application/problem+json format
+// @Description Error details, RFC 7807. code is stable and meant for programs,
+// @Description detail is the message for people.
+// swagger:model
+type Problem struct {
+	Type      string `json:"type" example:"about:blank"`
+	Title     string `json:"title" example:"Not Found"`
+	Status    int    `json:"status" example:"404"`
+	Detail    string `json:"detail,omitempty" example:"url not found"`
+	Code      string `json:"code" example:"url_not_found"`
+	RequestID string `json:"request_id,omitempty"`
+	// Errors are the failed fields of a validation_failed problem
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a field of a request that failed validation
+// swagger:model
+type FieldError struct {
+	// Field is the JSON path of the field, e.g. items[2].url
+	Field string `json:"field" example:"url"`
+	// Rule is the validation that failed: required, url, min, max, oneof,
+	// alias_charset, alias_reserved, alias_profane, alias_ambiguous, ...
+	Rule    string `json:"rule" example:"url"`
+	Message string `json:"message" example:"must be a valid URL"`
+	// Param is the argument of the rule, e.g. 3 for min=3
+	Param string `json:"param,omitempty"`
+}
+
 func OK() Response {
 	return Response{
 		Status: StatusOK,
@@ -31,22 +96,196 @@ func Error(msg string) Response {
 	}
 }
 
-func ValidationError(errs validator.ValidationErrors) Response {
+// NewValidator returns a validator that names fields by their json tags,
+// so that FieldErrors point at the fields clients send.
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+
+		return name
+	})
+
+	return v
+}
+
+// ValidationError joins errs into one message, kept for clients that
+// show it as is, and lists them field by field in Errors. Both are in
+// the language of r.
+func ValidationError(r *http.Request, errs validator.ValidationErrors) Response {
+	trans := Translator(r)
+
 	var errMsgs []string
 
 	for _, err := range errs {
-		switch err.ActualTag() {
+		// the summary names Go fields, as it always did
+		key := msgSummaryInvalid
+		switch err.Tag() {
 		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
+			key = msgSummaryRequired
 		case "url":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid URL", err.Field()))
-		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not valid", err.Field()))
+			key = msgSummaryUrl
+		case "alias":
+			key = msgSummaryAlias
 		}
+
+		errMsgs = append(errMsgs, translate(trans, key, err.StructField()))
 	}
 
 	return Response{
 		Status: StatusError,
 		Error:  strings.Join(errMsgs, ", "),
+		Errors: fieldErrors(trans, errs),
+	}
+}
+
+func fieldErrors(trans ut.Translator, errs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(errs))
+
+	for _, err := range errs {
+		fields = append(fields, FieldError{
+			Field:   fieldPath(err),
+			Rule:    err.ActualTag(),
+			Message: fieldMessage(trans, err),
+			Param:   err.Param(),
+		})
+	}
+
+	return fields
+}
+
+// fieldPath is the namespace of err without the name of the validated struct.
+func fieldPath(err validator.FieldError) string {
+	_, path, found := strings.Cut(err.Namespace(), ".")
+	if !found {
+		return err.Field()
+	}
+
+	return path
+}
+
+func fieldMessage(trans ut.Translator, err validator.FieldError) string {
+	switch err.ActualTag() {
+	case "required":
+		return translate(trans, msgFieldRequired)
+	case "url":
+		return translate(trans, msgFieldUrl)
+	case "min":
+		return boundMessage(trans, err, msgFieldMinString, msgFieldMinItems, msgFieldMinNumber)
+	case "max":
+		return boundMessage(trans, err, msgFieldMaxString, msgFieldMaxItems, msgFieldMaxNumber)
+	case "oneof":
+		return translate(trans, msgFieldOneOf, strings.Join(strings.Fields(err.Param()), ", "))
+	case "excludesall":
+		return translate(trans, msgFieldExcludesAll, err.Param())
+	case "excluded_with":
+		return translate(trans, msgFieldExcludedWith, strings.ToLower(err.Param()))
+	case "alias_charset":
+		return translate(trans, msgFieldAliasCharset)
+	case "alias_reserved":
+		return translate(trans, msgFieldAliasReserved)
+	case "alias_profane":
+		return translate(trans, msgFieldAliasProfane)
+	case "alias_ambiguous":
+		return translate(trans, msgFieldAliasAmbiguous)
+	default:
+		return translate(trans, msgFieldInvalid)
+	}
+}
+
+// boundMessage is the message of min or max, which bound the length
+// of strings, the number of items or the value of numbers.
+func boundMessage(trans ut.Translator, err validator.FieldError, chars, items, number string) string {
+	switch err.Kind() {
+	case reflect.String:
+		return translateCount(trans, chars, err.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return translateCount(trans, items, err.Param())
+	default:
+		return translate(trans, number, err.Param())
+	}
+}
+
+// RenderError replies with status and a Problem, or with the Response
+// envelope of older versions and 200 to requests marked by Legacy.
+// detail is translated with T.
+func RenderError(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	renderError(w, r, status, code, T(r, detail), nil)
+}
+
+func renderError(w http.ResponseWriter, r *http.Request, status int, code, detail string, fields []FieldError) {
+	w.Header().Set("Content-Language", Translator(r).Locale())
+
+	if isLegacy(r) {
+		render.JSON(w, r, Response{Status: StatusError, Error: detail, Errors: fields})
+
+		return
+	}
+
+	// render.JSON would set application/json
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Code:      code,
+		RequestID: middleware.GetReqID(r.Context()),
+		Errors:    fields,
+	})
+}
+
+// RenderValidationError replies with 400 and the failed fields of errs.
+func RenderValidationError(w http.ResponseWriter, r *http.Request, errs validator.ValidationErrors) {
+	errResp := ValidationError(r, errs)
+	renderError(w, r, http.StatusBadRequest, CodeValidationFailed, errResp.Error, errResp.Errors)
+}
+
+// ErrorStatus sets status for an error reply that is not a Problem, like
+// the per-item results of a batch. Requests marked by Legacy keep 200.
+func ErrorStatus(r *http.Request, status int) {
+	if !isLegacy(r) {
+		render.Status(r, status)
 	}
 }
+
+type legacyKey struct{}
+
+// Legacy marks requests to get errors like older versions sent them: the
+// {"status":"Error","error":"..."} envelope with status 200, clients of
+// which look at the body only. A client asking for
+// application/problem+json gets problems and real statuses anyway, so
+// that clients can move one by one.
+func Legacy(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsProblem(r) {
+			r = r.WithContext(context.WithValue(r.Context(), legacyKey{}, true))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLegacy(r *http.Request) bool {
+	legacy, _ := r.Context().Value(legacyKey{}).(bool)
+
+	return legacy
+}
+
+func acceptsProblem(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == ContentTypeProblem {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,202 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderError(t *testing.T) {
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RenderError(w, r, http.StatusNotFound, CodeUrlNotFound, "url not found")
+	})
+
+	tests := []struct {
+		name           string
+		legacy         bool
+		accept         string
+		expectedStatus int
+		contentType    string
+		expectedBody   string
+	}{
+		{
+			name:           "problem",
+			expectedStatus: http.StatusNotFound,
+			contentType:    ContentTypeProblem,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"url not found","code":"url_not_found","request_id":"req-1"}`,
+		},
+		{
+			// старые клиенты смотрят только на тело, статус остаётся 200
+			name:           "legacy envelope",
+			legacy:         true,
+			expectedStatus: http.StatusOK,
+			contentType:    "application/json",
+			expectedBody:   `{"status":"Error","error":"url not found"}`,
+		},
+		{
+			// клиент, который уже умеет problem+json, получает его и в режиме совместимости
+			name:           "legacy with problem accepted",
+			legacy:         true,
+			accept:         "application/json, application/problem+json;q=0.9",
+			expectedStatus: http.StatusNotFound,
+			contentType:    ContentTypeProblem,
+			expectedBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"url not found","code":"url_not_found","request_id":"req-1"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.RequestID(notFound)
+			if tt.legacy {
+				handler = middleware.RequestID(Legacy(notFound))
+			}
+
+			req := httptest.NewRequest("GET", "/missing", nil)
+			req.Header.Set(middleware.RequestIDHeader, "req-1")
+			req.Header.Set("Accept", tt.accept)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.contentType, rr.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	type item struct {
+		Url string `json:"url" validate:"required,url"`
+	}
+
+	type request struct {
+		Name  string   `json:"name" validate:"required"`
+		Mode  string   `json:"mode,omitempty" validate:"omitempty,oneof=fast slow"`
+		Tags  []string `json:"tags" validate:"max=1,dive,min=2"`
+		Items []item   `json:"items" validate:"dive"`
+	}
+
+	var errs validator.ValidationErrors
+	err := NewValidator().Struct(request{
+		Mode:  "medium",
+		Tags:  []string{"a"},
+		Items: []item{{Url: "http://example.com"}, {Url: "example"}},
+	})
+	require.ErrorAs(t, err, &errs)
+
+	tests := []struct {
+		name            string
+		acceptLanguage  string
+		expectedSummary string
+		expectedErrors  []FieldError
+	}{
+		{
+			// сводка по-прежнему называет поля Go, как раньше
+			name:            "english",
+			expectedSummary: "field Name is a required field, field Mode is not valid, field Tags[0] is not valid, field Url is not a valid URL",
+			expectedErrors: []FieldError{
+				{Field: "name", Rule: "required", Message: "is required"},
+				{Field: "mode", Rule: "oneof", Message: "must be one of: fast, slow", Param: "fast slow"},
+				{Field: "tags[0]", Rule: "min", Message: "must be at least 2 characters long", Param: "2"},
+				{Field: "items[1].url", Rule: "url", Message: "must be a valid URL"},
+			},
+		},
+		{
+			name:            "russian",
+			acceptLanguage:  "ru-RU,ru;q=0.9,en;q=0.8",
+			expectedSummary: "поле Name обязательно, поле Mode заполнено неверно, поле Tags[0] заполнено неверно, поле Url не является корректным URL",
+			expectedErrors: []FieldError{
+				{Field: "name", Rule: "required", Message: "обязательное поле"},
+				{Field: "mode", Rule: "oneof", Message: "должно быть одним из: fast, slow", Param: "fast slow"},
+				{Field: "tags[0]", Rule: "min", Message: "должно быть не короче 2 символов", Param: "2"},
+				{Field: "items[1].url", Rule: "url", Message: "должно быть корректным URL"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/url", nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+
+			got := ValidationError(req, errs)
+
+			assert.Equal(t, tt.expectedSummary, got.Error)
+			assert.Equal(t, tt.expectedErrors, got.Errors)
+		})
+	}
+}
+
+func TestRenderValidationError(t *testing.T) {
+	type request struct {
+		Url string `json:"url" validate:"required,url"`
+	}
+
+	invalid := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var errs validator.ValidationErrors
+		_ = errors.As(NewValidator().Struct(request{Url: "example"}), &errs)
+
+		RenderValidationError(w, r, errs)
+	})
+
+	tests := []struct {
+		name           string
+		legacy         bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "problem",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"field Url is not a valid URL","code":"validation_failed","request_id":"req-1","errors":[{"field":"url","rule":"url","message":"must be a valid URL"}]}`,
+		},
+		{
+			name:           "legacy envelope",
+			legacy:         true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"Error","error":"field Url is not a valid URL","errors":[{"field":"url","rule":"url","message":"must be a valid URL"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h http.Handler = invalid
+			if tt.legacy {
+				h = Legacy(h)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/url", nil)
+			req.Header.Set(middleware.RequestIDHeader, "req-1")
+
+			rr := httptest.NewRecorder()
+			middleware.RequestID(h).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	conflict := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ErrorStatus(r, http.StatusConflict)
+		render.JSON(w, r, Error("aliases already exist"))
+	})
+
+	rr := httptest.NewRecorder()
+	conflict.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/url/import", nil))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = httptest.NewRecorder()
+	Legacy(conflict).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/url/import", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"Error","error":"aliases already exist"}`, rr.Body.String())
+}
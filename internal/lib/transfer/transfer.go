@@ -0,0 +1,343 @@
+// Package transfer encodes links as CSV or NDJSON for export and import.
+// Both formats carry the same fields, so a file exported in one
+// environment can be imported in another.
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+var ErrUnknownFormat = errors.New("format must be csv or ndjson")
+
+// ContentType returns the media type of format.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+
+	return "application/x-ndjson"
+}
+
+// FormatOf returns the format of a media type or an empty string.
+func FormatOf(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+
+	switch strings.TrimSpace(strings.ToLower(mediaType)) {
+	case "text/csv":
+		return FormatCSV
+	case "application/x-ndjson", "application/jsonl":
+		return FormatNDJSON
+	}
+
+	return ""
+}
+
+// Record is a link in a file. NDJSON lines are records, CSV files
+// have a column per field with tags separated by tagSeparator.
+type Record struct {
+	Alias     string     `json:"alias" validate:"required"`
+	Url       string     `json:"url" validate:"required,url"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxClicks *int64     `json:"max_clicks,omitempty" validate:"omitempty,min=1"`
+	Clicks    int64      `json:"clicks,omitempty" validate:"min=0"`
+	Tags      []string   `json:"tags,omitempty" validate:"omitempty,max=20,dive,min=1,max=50,excludesall=/?#%"`
+	Owner     string     `json:"owner,omitempty" validate:"max=100"`
+}
+
+const tagSeparator = ";"
+
+var columns = []string{"alias", "url", "created_at", "expires_at", "max_clicks", "clicks", "tags", "owner"}
+
+// RecordOf converts a stored link.
+func RecordOf(link storage.Link) Record {
+	createdAt := link.CreatedAt.UTC()
+
+	return Record{
+		Alias:     link.Alias,
+		Url:       link.Url,
+		CreatedAt: &createdAt,
+		ExpiresAt: link.ExpiresAt,
+		MaxClicks: link.MaxClicks,
+		Clicks:    link.Clicks,
+		Tags:      link.Tags,
+		Owner:     link.Owner,
+	}
+}
+
+// Link converts the record back. A missing creation time stays zero.
+func (r Record) Link() storage.Link {
+	link := storage.Link{
+		Alias:     r.Alias,
+		Url:       r.Url,
+		ExpiresAt: r.ExpiresAt,
+		MaxClicks: r.MaxClicks,
+		Clicks:    r.Clicks,
+		Tags:      storage.NormalizeTags(r.Tags),
+		Owner:     r.Owner,
+	}
+	if r.CreatedAt != nil {
+		link.CreatedAt = *r.CreatedAt
+	}
+
+	return link
+}
+
+// Writer encodes records one by one.
+type Writer interface {
+	Write(r Record) error
+	// Flush writes buffered records to the underlying writer.
+	Flush() error
+}
+
+// NewWriter returns a Writer of format. A CSV writer starts with the header.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+
+		return &csvWriter{w: cw}, nil
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+
+		return &ndjsonWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) Write(r Record) error {
+	row := []string{
+		r.Alias,
+		r.Url,
+		formatTime(r.CreatedAt),
+		formatTime(r.ExpiresAt),
+		"",
+		strconv.FormatInt(r.Clicks, 10),
+		strings.Join(r.Tags, tagSeparator),
+		r.Owner,
+	}
+	if r.MaxClicks != nil {
+		row[4] = strconv.FormatInt(*r.MaxClicks, 10)
+	}
+
+	return w.w.Write(row)
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+
+	return w.w.Error()
+}
+
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(r Record) error {
+	return w.enc.Encode(r)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.w.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// LineError is a record that could not be decoded.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// maxLineSize limits a single NDJSON record.
+const maxLineSize = 1 << 20
+
+// Read decodes records of format from r and calls fn with each record
+// and its line number. Decoding stops at the first error, a malformed
+// record is reported as *LineError.
+func Read(r io.Reader, format string, fn func(line int, rec Record) error) error {
+	switch format {
+	case FormatCSV:
+		return readCSV(r, fn)
+	case FormatNDJSON:
+		return readNDJSON(r, fn)
+	}
+
+	return ErrUnknownFormat
+}
+
+func readNDJSON(r io.Reader, fn func(line int, rec Record) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		data := strings.TrimSpace(scanner.Text())
+		if data == "" {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal([]byte(data), &rec); err != nil {
+			return &LineError{Line: line, Err: errors.New("invalid json")}
+		}
+
+		if err := fn(line, rec); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func readCSV(r io.Reader, fn func(line int, rec Record) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return csvError(err)
+	}
+
+	// column positions by name, unknown columns are ignored
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+
+	for _, name := range []string{"alias", "url"} {
+		if _, ok := index[name]; !ok {
+			return &LineError{Line: 1, Err: fmt.Errorf("column %s is missing", name)}
+		}
+	}
+
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return csvError(err)
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		rec, err := parseRow(row, index)
+		if err != nil {
+			return &LineError{Line: line, Err: err}
+		}
+
+		if err := fn(line, rec); err != nil {
+			return err
+		}
+	}
+}
+
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &LineError{Line: parseErr.Line, Err: parseErr.Err}
+	}
+
+	return err
+}
+
+func parseRow(row []string, index map[string]int) (Record, error) {
+	cell := func(name string) string {
+		i, ok := index[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+
+		return strings.TrimSpace(row[i])
+	}
+
+	rec := Record{
+		Alias: cell("alias"),
+		Url:   cell("url"),
+		Owner: cell("owner"),
+	}
+
+	var err error
+
+	if rec.CreatedAt, err = parseTime(cell("created_at")); err != nil {
+		return Record{}, fmt.Errorf("created_at: %w", err)
+	}
+
+	if rec.ExpiresAt, err = parseTime(cell("expires_at")); err != nil {
+		return Record{}, fmt.Errorf("expires_at: %w", err)
+	}
+
+	if s := cell("max_clicks"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return Record{}, errors.New("max_clicks must be a number")
+		}
+		rec.MaxClicks = &n
+	}
+
+	if s := cell("clicks"); s != "" {
+		if rec.Clicks, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return Record{}, errors.New("clicks must be a number")
+		}
+	}
+
+	if s := cell("tags"); s != "" {
+		rec.Tags = strings.FieldsFunc(s, func(r rune) bool { return string(r) == tagSeparator })
+	}
+
+	return rec, nil
+}
+
+func parseTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, errors.New("time must be in RFC 3339 format")
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,100 @@
+package transfer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxClicks := int64(100)
+
+	records := []Record{
+		{Alias: "promo", Url: "https://example.com/?a=1,b=2", CreatedAt: &createdAt, ExpiresAt: &expiresAt,
+			MaxClicks: &maxClicks, Clicks: 7, Tags: []string{"spring", "team-a"}, Owner: "ops"},
+		{Alias: "plain", Url: "https://example.org", CreatedAt: &createdAt},
+	}
+
+	for _, format := range []string{FormatCSV, FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			w, err := NewWriter(&buf, format)
+			require.NoError(t, err)
+			for _, rec := range records {
+				require.NoError(t, w.Write(rec))
+			}
+			require.NoError(t, w.Flush())
+
+			var read []Record
+			err = Read(&buf, format, func(line int, rec Record) error {
+				read = append(read, rec)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, records, read)
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	// порядок колонок произвольный, лишние игнорируются
+	input := "url,Alias,comment,tags\n" +
+		"https://example.com,a,first,promo;;Spring\n" +
+		"\n" +
+		"https://example.org,b,second,\n"
+
+	var (
+		lines   []int
+		records []Record
+	)
+
+	err := Read(strings.NewReader(input), FormatCSV, func(line int, rec Record) error {
+		lines = append(lines, line)
+		records = append(records, rec)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{2, 4}, lines)
+	assert.Equal(t, []Record{
+		{Alias: "a", Url: "https://example.com", Tags: []string{"promo", "Spring"}},
+		{Alias: "b", Url: "https://example.org"},
+	}, records)
+	assert.Equal(t, []string{"promo", "spring"}, records[0].Link().Tags)
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		err    string
+	}{
+		{"missing column", FormatCSV, "alias,target\na,https://example.com\n", "line 1: column url is missing"},
+		{"bad time", FormatCSV, "alias,url,created_at\na,https://example.com,yesterday\n", "line 2: created_at: time must be in RFC 3339 format"},
+		{"bad number", FormatCSV, "alias,url,max_clicks\na,https://example.com,many\n", "line 2: max_clicks must be a number"},
+		{"bad quotes", FormatCSV, "alias,url\n\"a,https://example.com\n", "line 2: extraneous or missing \" in quoted-field"},
+		{"bad json", FormatNDJSON, `{"alias":"a","url":"https://example.com"}` + "\n{\n", "line 2: invalid json"},
+		{"unknown format", "xml", "", ErrUnknownFormat.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Read(strings.NewReader(tt.input), tt.format, func(int, Record) error { return nil })
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, FormatCSV, FormatOf("text/csv; charset=utf-8"))
+	assert.Equal(t, FormatNDJSON, FormatOf("application/x-ndjson"))
+	assert.Equal(t, "", FormatOf("application/json"))
+}
@@ -0,0 +1,93 @@
+// Package reaper periodically removes expired links from the storage.
+package reaper
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// ModePurge deletes expired links.
+	ModePurge = "purge"
+	// ModeArchive moves expired links to the archive table.
+	ModeArchive = "archive"
+)
+
+type ExpiredRemover interface {
+	PurgeExpired(now time.Time) (int64, error)
+	ArchiveExpired(now time.Time) (int64, error)
+}
+
+type Reaper struct {
+	log      *slog.Logger
+	remover  ExpiredRemover
+	interval time.Duration
+	mode     string
+}
+
+func New(log *slog.Logger, remover ExpiredRemover, interval time.Duration, mode string) *Reaper {
+	return &Reaper{
+		log:      log.With(slog.String("component", "reaper")),
+		remover:  remover,
+		interval: interval,
+		mode:     mode,
+	}
+}
+
+// Run reaps once right away and then every interval until ctx is done.
+func (r *Reaper) Run(ctx context.Context) {
+	r.log.Info("reaper started", slog.String("mode", r.mode), slog.String("interval", r.interval.String()))
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.reap()
+
+		select {
+		case <-ctx.Done():
+			r.log.Info("reaper stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reap removes links expired by now and returns their number.
+func (r *Reaper) Reap(now time.Time) (int64, error) {
+	const op = "reaper.Reap"
+
+	var (
+		n   int64
+		err error
+	)
+
+	switch r.mode {
+	case ModeArchive:
+		n, err = r.remover.ArchiveExpired(now)
+	case ModePurge:
+		n, err = r.remover.PurgeExpired(now)
+	default:
+		err = fmt.Errorf("unknown mode %q", r.mode)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+func (r *Reaper) reap() {
+	n, err := r.Reap(time.Now())
+	if err != nil {
+		r.log.Error("failed to reap expired urls", slog.String("error", err.Error()))
+		return
+	}
+
+	if n > 0 {
+		r.log.Info("reaped expired urls", slog.Int64("count", n), slog.String("mode", r.mode))
+	}
+}
@@ -0,0 +1,42 @@
+package reaper
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReap(t *testing.T) {
+	for _, mode := range []string{ModePurge, ModeArchive} {
+		t.Run(mode, func(t *testing.T) {
+			repo := memory.New()
+			now := time.Now()
+			past := now.Add(-time.Second)
+
+			_, err := repo.SaveUrl(storage.DefaultWorkspace, "https://example.com", "old", storage.SaveOptions{ExpiresAt: &past})
+			require.NoError(t, err)
+			_, err = repo.SaveUrl(storage.DefaultWorkspace, "https://example.com", "permanent", storage.SaveOptions{})
+			require.NoError(t, err)
+
+			n, err := New(slog.Default(), repo, time.Minute, mode).Reap(now)
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, n)
+
+			_, err = repo.GetUrl(storage.DefaultWorkspace, "old")
+			assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+			_, err = repo.GetUrl(storage.DefaultWorkspace, "permanent")
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestReapUnknownMode(t *testing.T) {
+	_, err := New(slog.Default(), memory.New(), time.Minute, "shred").Reap(time.Now())
+	assert.Error(t, err)
+}
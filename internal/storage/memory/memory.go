@@ -0,0 +1,850 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+var _ storage.Repository = (*Storage)(nil)
+
+type record struct {
+	id        int64
+	url       string
+	domain    string
+	createdAt time.Time
+	expiresAt *time.Time
+	maxClicks *int64
+	clicks    int64
+	generated bool
+	owner     string
+	// tags are sorted. Records are copied by value, so a change
+	// must build a new slice instead of modifying this one.
+	tags []string
+}
+
+type archived struct {
+	record
+	key
+	archivedAt time.Time
+}
+
+// key addresses a link, aliases are unique per workspace.
+type key struct {
+	workspace string
+	alias     string
+}
+
+// Storage keeps urls in a map guarded by a mutex. Data is lost on restart,
+// so it is meant for local development and tests.
+type Storage struct {
+	mu      sync.RWMutex
+	lastID  int64
+	urls    map[key]record
+	archive []archived
+	// clicks are keyed by record id, so that a reused alias starts from zero.
+	clicks map[int64][]storage.Click
+	// apiKeys are in id order.
+	apiKeys    []storage.APIKey
+	workspaces map[string]storage.Workspace
+	// members are keyed by workspace and name.
+	members map[key]storage.Member
+}
+
+func New() *Storage {
+	return &Storage{
+		urls:   make(map[key]record),
+		clicks: make(map[int64][]storage.Click),
+		workspaces: map[string]storage.Workspace{
+			storage.DefaultWorkspace: {Name: storage.DefaultWorkspace, CreatedAt: time.Now()},
+		},
+		members: make(map[key]storage.Member),
+	}
+}
+
+func (s *Storage) SaveUrl(workspace, inputUrl, alias string, opts storage.SaveOptions) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.urls[key{workspace, alias}]; ok {
+		return 0, storage.ErrUrlExists
+	}
+
+	return s.insert(workspace, storage.NewLink{Url: inputUrl, Alias: alias, Opts: opts}), nil
+}
+
+func (s *Storage) SaveUrls(workspace string, links []storage.NewLink, atomic bool) ([]storage.SaveResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]storage.SaveResult, len(links))
+
+	// aliases are checked before anything is saved, so that an
+	// atomic batch needs no rollback
+	taken := make(map[string]bool, len(links))
+	failed := false
+
+	for i, link := range links {
+		if _, ok := s.urls[key{workspace, link.Alias}]; ok || taken[link.Alias] {
+			results[i].Err = storage.ErrUrlExists
+			failed = true
+		}
+		taken[link.Alias] = true
+	}
+
+	if atomic && failed {
+		return results, nil
+	}
+
+	for i, link := range links {
+		if results[i].Err == nil {
+			results[i].ID = s.insert(workspace, link)
+		}
+	}
+
+	return results, nil
+}
+
+// insert stores a link with a free alias. The caller holds the write lock.
+func (s *Storage) insert(workspace string, link storage.NewLink) int64 {
+	s.lastID++
+	s.urls[key{workspace, link.Alias}] = record{
+		id:        s.lastID,
+		url:       link.Url,
+		domain:    storage.Domain(link.Url),
+		createdAt: time.Now(),
+		expiresAt: link.Opts.ExpiresAt,
+		maxClicks: link.Opts.MaxClicks,
+		tags:      mergeTags(nil, link.Opts.Tags),
+		generated: link.Opts.Generated,
+		owner:     link.Opts.Owner,
+	}
+
+	return s.lastID
+}
+
+func (s *Storage) FindAlias(workspace, target, owner string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var (
+		found string
+		id    int64
+	)
+
+	target = storage.NormalizeURL(target)
+
+	for k, rec := range s.urls {
+		if k.workspace != workspace || storage.NormalizeURL(rec.url) != target || rec.owner != owner || !rec.generated || rec.expiresAt != nil || rec.maxClicks != nil {
+			continue
+		}
+
+		if found == "" || rec.id < id {
+			found, id = k.alias, rec.id
+		}
+	}
+
+	if found == "" {
+		return "", storage.ErrUrlNotFound
+	}
+
+	return found, nil
+}
+
+func (s *Storage) GetUrl(workspace, alias string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.urls[key{workspace, alias}]
+	if !ok {
+		return "", storage.ErrUrlNotFound
+	}
+
+	if err := rec.link(alias).CheckUsable(time.Now()); err != nil {
+		return "", err
+	}
+
+	return rec.url, nil
+}
+
+func (s *Storage) HitUrl(workspace, alias string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{workspace, alias}
+
+	rec, ok := s.urls[k]
+	if !ok {
+		return "", storage.ErrUrlNotFound
+	}
+
+	if err := rec.link(alias).CheckUsable(time.Now()); err != nil {
+		return "", err
+	}
+
+	if rec.maxClicks != nil {
+		rec.clicks++
+		s.urls[k] = rec
+	}
+
+	return rec.url, nil
+}
+
+func (s *Storage) DeleteUrl(workspace, alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{workspace, alias}
+
+	rec, ok := s.urls[k]
+	if !ok {
+		return storage.ErrUrlNotFound
+	}
+
+	s.remove(k, rec)
+
+	return nil
+}
+
+func (s *Storage) ListUrls(params storage.ListParams) (storage.Page, error) {
+	if params.Limit <= 0 {
+		return storage.Page{}, errors.New("storage.memory.ListUrls: limit must be positive")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var links []storage.Link
+
+	for k, rec := range s.urls {
+		if k.workspace != params.Workspace || !strings.HasPrefix(k.alias, params.AliasPrefix) {
+			continue
+		}
+
+		if params.Owner != "" && rec.owner != params.Owner {
+			continue
+		}
+
+		if params.Tag != "" && !slices.Contains(rec.tags, storage.NormalizeTag(params.Tag)) {
+			continue
+		}
+
+		if params.Domain != "" && rec.domain != params.Domain &&
+			!strings.HasSuffix(rec.domain, "."+params.Domain) {
+			continue
+		}
+
+		link := rec.link(k.alias)
+		if params.After != nil && !before(params, params.After, storage.CursorOf(link)) {
+			continue
+		}
+
+		links = append(links, link)
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return before(params, storage.CursorOf(links[i]), storage.CursorOf(links[j]))
+	})
+
+	page := storage.Page{Links: links}
+	if len(links) > params.Limit {
+		page.Links = links[:params.Limit]
+		page.Next = storage.CursorOf(page.Links[params.Limit-1])
+	}
+
+	return page, nil
+}
+
+// before reports whether a is listed before b.
+func before(params storage.ListParams, a, b *storage.Cursor) bool {
+	less := a.ID < b.ID
+
+	switch {
+	case params.Sort == storage.SortAlias && a.Alias != b.Alias:
+		less = a.Alias < b.Alias
+	case params.Sort != storage.SortAlias && !a.CreatedAt.Equal(b.CreatedAt):
+		less = a.CreatedAt.Before(b.CreatedAt)
+	}
+
+	if params.Desc {
+		return !less && a.ID != b.ID
+	}
+
+	return less
+}
+
+func (s *Storage) SearchUrls(workspace, owner, query string, limit, offset int) ([]storage.Link, error) {
+	terms := storage.SearchTerms(query)
+	if len(terms) == 0 {
+		return []storage.Link{}, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type match struct {
+		link storage.Link
+		// rank is the number of terms found in the alias
+		rank int
+	}
+
+	var matches []match
+
+	for k, rec := range s.urls {
+		if k.workspace != workspace || owner != "" && rec.owner != owner {
+			continue
+		}
+
+		lowerAlias, lowerUrl := strings.ToLower(k.alias), strings.ToLower(rec.url)
+
+		m := match{link: rec.link(k.alias)}
+		for _, term := range terms {
+			inAlias := strings.Contains(lowerAlias, term)
+			if !inAlias && !strings.Contains(lowerUrl, term) {
+				m.rank = -1
+				break
+			}
+			if inAlias {
+				m.rank++
+			}
+		}
+
+		if m.rank >= 0 {
+			matches = append(matches, m)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank > matches[j].rank
+		}
+		return matches[i].link.ID < matches[j].link.ID
+	})
+
+	links := make([]storage.Link, 0, limit)
+	for i := offset; i < len(matches) && len(links) < limit; i++ {
+		links = append(links, matches[i].link)
+	}
+
+	return links, nil
+}
+
+func (s *Storage) ExportUrls(workspace, owner string, fn func(storage.Link) error) error {
+	s.mu.RLock()
+	links := make([]storage.Link, 0, len(s.urls))
+	for k, rec := range s.urls {
+		if k.workspace == workspace && (owner == "" || rec.owner == owner) {
+			links = append(links, rec.link(k.alias))
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+
+	for _, link := range links {
+		if err := fn(link); err != nil {
+			return fmt.Errorf("storage.memory.ExportUrls: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) ImportUrls(workspace string, links []storage.Link, onConflict string, dryRun bool) (storage.ImportReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the report is made before anything changes, so that a failed
+	// or dry run import needs no rollback
+	var report storage.ImportReport
+
+	created := make(map[string]bool)
+
+	for _, link := range links {
+		if _, ok := s.urls[key{workspace, link.Alias}]; !ok && !created[link.Alias] {
+			created[link.Alias] = true
+			report.Created++
+
+			continue
+		}
+
+		report.Conflicts = append(report.Conflicts, link.Alias)
+
+		if onConflict == storage.ConflictOverwrite {
+			report.Updated++
+		} else {
+			report.Skipped++
+		}
+	}
+
+	if onConflict == storage.ConflictFail && len(report.Conflicts) > 0 {
+		return storage.ImportReport{Conflicts: report.Conflicts}, storage.ErrUrlExists
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	for _, link := range links {
+		k := key{workspace, link.Alias}
+
+		rec, ok := s.urls[k]
+
+		switch {
+		case !ok:
+			s.lastID++
+			rec = record{id: s.lastID, createdAt: time.Now()}
+		case onConflict != storage.ConflictOverwrite:
+			continue
+		}
+
+		if !link.CreatedAt.IsZero() {
+			rec.createdAt = link.CreatedAt
+		}
+		rec.url = link.Url
+		rec.domain = storage.Domain(link.Url)
+		rec.expiresAt = link.ExpiresAt
+		rec.maxClicks = link.MaxClicks
+		rec.clicks = link.Clicks
+		rec.tags = mergeTags(nil, link.Tags)
+		rec.owner = link.Owner
+
+		s.urls[k] = rec
+	}
+
+	return report, nil
+}
+
+func (s *Storage) AddTags(workspace, alias string, tags []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{workspace, alias}
+
+	rec, ok := s.urls[k]
+	if !ok {
+		return nil, storage.ErrUrlNotFound
+	}
+
+	rec.tags = mergeTags(rec.tags, tags)
+	s.urls[k] = rec
+
+	return rec.tags, nil
+}
+
+func (s *Storage) RemoveTag(workspace, alias, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{workspace, alias}
+
+	rec, ok := s.urls[k]
+	if !ok {
+		return storage.ErrUrlNotFound
+	}
+
+	i := slices.Index(rec.tags, storage.NormalizeTag(tag))
+	if i < 0 {
+		return storage.ErrTagNotFound
+	}
+
+	rec.tags = slices.Delete(slices.Clone(rec.tags), i, i+1)
+	s.urls[k] = rec
+
+	return nil
+}
+
+func (s *Storage) DeleteByTag(workspace, tag string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag = storage.NormalizeTag(tag)
+
+	var deleted int64
+
+	for k, rec := range s.urls {
+		if k.workspace == workspace && slices.Contains(rec.tags, tag) {
+			s.remove(k, rec)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// mergeTags returns a new sorted slice with tags added to current.
+func mergeTags(current, tags []string) []string {
+	merged := slices.Clone(current)
+
+	for _, tag := range storage.NormalizeTags(tags) {
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+
+	slices.Sort(merged)
+
+	return merged
+}
+
+func (s *Storage) UpdateUrl(workspace, url, alias string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{workspace, alias}
+
+	rec, ok := s.urls[k]
+	if !ok {
+		return "", storage.ErrAliasNotFound
+	}
+
+	rec.url = url
+	rec.domain = storage.Domain(url)
+	s.urls[k] = rec
+
+	return alias, nil
+}
+
+func (s *Storage) GetOwner(workspace, alias string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.urls[key{workspace, alias}]
+	if !ok {
+		return "", storage.ErrUrlNotFound
+	}
+
+	return rec.owner, nil
+}
+
+func (s *Storage) SetOwner(workspace, alias, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{workspace, alias}
+
+	rec, ok := s.urls[k]
+	if !ok {
+		return storage.ErrUrlNotFound
+	}
+
+	rec.owner = owner
+	s.urls[k] = rec
+
+	return nil
+}
+
+func (s *Storage) RenameAlias(workspace, alias, newAlias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, newKey := key{workspace, alias}, key{workspace, newAlias}
+
+	rec, ok := s.urls[k]
+	if !ok {
+		return storage.ErrAliasNotFound
+	}
+
+	if alias == newAlias {
+		return nil
+	}
+
+	if _, ok := s.urls[newKey]; ok {
+		return storage.ErrUrlExists
+	}
+
+	rec.generated = false
+	delete(s.urls, k)
+	s.urls[newKey] = rec
+
+	return nil
+}
+
+func (s *Storage) UpdateLink(workspace, alias, newAlias, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{workspace, alias}
+
+	rec, ok := s.urls[k]
+	if !ok {
+		return storage.ErrAliasNotFound
+	}
+
+	if newAlias != "" && newAlias != alias {
+		newKey := key{workspace, newAlias}
+		if _, ok := s.urls[newKey]; ok {
+			return storage.ErrUrlExists
+		}
+
+		rec.generated = false
+		delete(s.urls, k)
+		k = newKey
+	}
+
+	if url != "" {
+		rec.url = url
+		rec.domain = storage.Domain(url)
+	}
+
+	s.urls[k] = rec
+
+	return nil
+}
+
+func (s *Storage) PurgeExpired(now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+
+	for k, rec := range s.urls {
+		if rec.link(k.alias).Expired(now) {
+			s.remove(k, rec)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+func (s *Storage) ArchiveExpired(now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var moved int64
+
+	for k, rec := range s.urls {
+		if rec.link(k.alias).Expired(now) {
+			s.archive = append(s.archive, archived{record: rec, key: k, archivedAt: now})
+			s.remove(k, rec)
+			moved++
+		}
+	}
+
+	return moved, nil
+}
+
+func (s *Storage) SaveClicks(clicks []storage.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, click := range clicks {
+		rec, ok := s.urls[key{click.Workspace, click.Alias}]
+		if !ok {
+			continue
+		}
+
+		s.clicks[rec.id] = append(s.clicks[rec.id], click)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetStats(workspace, alias string) (storage.Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.urls[key{workspace, alias}]
+	if !ok {
+		return storage.Stats{}, storage.ErrUrlNotFound
+	}
+
+	perDay := make(map[string]int64)
+	for _, click := range s.clicks[rec.id] {
+		perDay[click.At.UTC().Format(time.DateOnly)]++
+	}
+
+	stats := storage.Stats{PerDay: make([]storage.DayCount, 0, len(perDay))}
+	for date, count := range perDay {
+		stats.Total += count
+		stats.PerDay = append(stats.PerDay, storage.DayCount{Date: date, Count: count})
+	}
+
+	sort.Slice(stats.PerDay, func(i, j int) bool {
+		return stats.PerDay[i].Date < stats.PerDay[j].Date
+	})
+
+	return stats, nil
+}
+
+func (s *Storage) SaveAPIKey(key storage.APIKey) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.apiKeys {
+		if k.Prefix == key.Prefix {
+			return 0, storage.ErrAPIKeyExists
+		}
+	}
+
+	// keys are never deleted
+	key.ID = int64(len(s.apiKeys)) + 1
+	key.Scopes = slices.Clone(key.Scopes)
+	s.apiKeys = append(s.apiKeys, key)
+
+	return key.ID, nil
+}
+
+func (s *Storage) GetAPIKey(prefix string) (storage.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.apiKeys {
+		if key.Prefix == prefix {
+			return key, nil
+		}
+	}
+
+	return storage.APIKey{}, storage.ErrAPIKeyNotFound
+}
+
+func (s *Storage) ListAPIKeys() ([]storage.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.apiKeys), nil
+}
+
+func (s *Storage) RevokeAPIKey(id int64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, key := range s.apiKeys {
+		if key.ID != id {
+			continue
+		}
+
+		if key.RevokedAt == nil {
+			s.apiKeys[i].RevokedAt = &now
+		}
+
+		return nil
+	}
+
+	return storage.ErrAPIKeyNotFound
+}
+
+func (s *Storage) CreateWorkspace(ws storage.Workspace) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workspaces[ws.Name]; ok {
+		return storage.ErrWorkspaceExists
+	}
+
+	s.workspaces[ws.Name] = ws
+
+	return nil
+}
+
+func (s *Storage) GetWorkspace(name string) (storage.Workspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ws, ok := s.workspaces[name]
+	if !ok {
+		return storage.Workspace{}, storage.ErrWorkspaceNotFound
+	}
+
+	return ws, nil
+}
+
+func (s *Storage) ListWorkspaces() ([]storage.Workspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]storage.Workspace, 0, len(s.workspaces))
+	for _, ws := range s.workspaces {
+		list = append(list, ws)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list, nil
+}
+
+func (s *Storage) SetMember(member storage.Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workspaces[member.Workspace]; !ok {
+		return storage.ErrWorkspaceNotFound
+	}
+
+	s.members[key{member.Workspace, member.Name}] = member
+
+	return nil
+}
+
+func (s *Storage) GetMember(workspace, name string) (storage.Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	member, ok := s.members[key{workspace, name}]
+	if !ok {
+		return storage.Member{}, storage.ErrMemberNotFound
+	}
+
+	return member, nil
+}
+
+func (s *Storage) ListMembers(workspace string) ([]storage.Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := []storage.Member{}
+	for k, member := range s.members {
+		if k.workspace == workspace {
+			list = append(list, member)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list, nil
+}
+
+func (s *Storage) RemoveMember(workspace, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{workspace, name}
+	if _, ok := s.members[k]; !ok {
+		return storage.ErrMemberNotFound
+	}
+
+	delete(s.members, k)
+
+	return nil
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+// remove deletes the link with everything attached to it. The caller holds the write lock.
+func (s *Storage) remove(k key, rec record) {
+	delete(s.urls, k)
+	delete(s.clicks, rec.id)
+}
+
+func (r record) link(alias string) storage.Link {
+	return storage.Link{
+		ID:        r.id,
+		Alias:     alias,
+		Url:       r.url,
+		CreatedAt: r.createdAt,
+		ExpiresAt: r.expiresAt,
+		MaxClicks: r.maxClicks,
+		Clicks:    r.clicks,
+		Tags:      r.tags,
+		Owner:     r.owner,
+	}
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		return New()
+	})
+}
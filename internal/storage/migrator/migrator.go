@@ -0,0 +1,304 @@
+// Package migrator applies numbered SQL migrations and records
+// them in the schema_migrations table.
+//
+// Migrations are read from files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, e.g. 0001_create_url.up.sql. Each one
+// runs in its own transaction together with its bookkeeping row.
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	SQLite   = "sqlite3"
+	Postgres = "postgres"
+)
+
+// lockID is an arbitrary key for the postgres advisory lock that keeps
+// replicas started at the same time from applying a migration twice.
+const lockID = 7217356
+
+var (
+	ErrSchemaTooNew    = errors.New("database schema is newer than the application")
+	ErrNoDownMigration = errors.New("down migration is missing")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// New reads migrations from the root of fsys. dialect is SQLite or Postgres.
+func New(db *sql.DB, dialect string, fsys fs.FS) (*Migrator, error) {
+	const op = "storage.migrator.New"
+
+	if dialect != SQLite && dialect != Postgres {
+		return nil, fmt.Errorf("%s: unknown dialect %q", op, dialect)
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("%s: version %d has two names: %s and %s", op, version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%s: version %d has no up migration", op, m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Latest returns the newest schema version this binary knows about.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the schema version of the database, 0 for an empty one.
+func (m *Migrator) Version() (int, error) {
+	const op = "storage.migrator.Version"
+
+	if err := m.ensureTable(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var version int
+
+	err := m.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// Check returns ErrSchemaTooNew if the database was migrated by a newer binary.
+func (m *Migrator) Check() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if version > m.Latest() {
+		return fmt.Errorf("%w: database version %d, latest known %d", ErrSchemaTooNew, version, m.Latest())
+	}
+
+	return nil
+}
+
+// Up applies all pending migrations and returns how many were applied.
+func (m *Migrator) Up() (int, error) {
+	const op = "storage.migrator.Up"
+
+	if err := m.Check(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	current, err := m.Version()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	applied := 0
+
+	for _, migration := range m.migrations {
+		if migration.Version <= current {
+			continue
+		}
+
+		done, err := m.apply(migration, true)
+		if err != nil {
+			return applied, fmt.Errorf("%s: %04d_%s: %w", op, migration.Version, migration.Name, err)
+		}
+		if done {
+			applied++
+		}
+	}
+
+	return applied, nil
+}
+
+// Down reverts up to steps of the most recently applied migrations
+// and returns how many were reverted.
+func (m *Migrator) Down(steps int) (int, error) {
+	const op = "storage.migrator.Down"
+
+	if err := m.Check(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	current, err := m.Version()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reverted := 0
+
+	for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+		migration := m.migrations[i]
+		if migration.Version > current {
+			continue
+		}
+
+		if migration.Down == "" {
+			return reverted, fmt.Errorf("%s: %04d_%s: %w", op, migration.Version, migration.Name, ErrNoDownMigration)
+		}
+
+		done, err := m.apply(migration, false)
+		if err != nil {
+			return reverted, fmt.Errorf("%s: %04d_%s: %w", op, migration.Version, migration.Name, err)
+		}
+		if done {
+			reverted++
+		}
+	}
+
+	return reverted, nil
+}
+
+// apply runs one migration in a transaction. It reports false when another
+// process has already applied (or reverted) it in the meantime.
+func (m *Migrator) apply(migration Migration, up bool) (bool, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if m.dialect == Postgres {
+		if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", lockID); err != nil {
+			return false, err
+		}
+	}
+
+	var applied bool
+
+	err = tx.QueryRow(m.bind("SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = ?)"), migration.Version).
+		Scan(&applied)
+	if err != nil {
+		return false, err
+	}
+
+	if applied == up {
+		return false, nil
+	}
+
+	if up {
+		if _, err := tx.Exec(migration.Up); err != nil {
+			return false, err
+		}
+
+		_, err = tx.Exec(
+			m.bind("INSERT INTO schema_migrations(version, name, applied_at) VALUES(?, ?, ?)"),
+			migration.Version, migration.Name, time.Now().UTC(),
+		)
+	} else {
+		if _, err := tx.Exec(migration.Down); err != nil {
+			return false, err
+		}
+
+		_, err = tx.Exec(m.bind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations(
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL);
+	`)
+
+	return err
+}
+
+// bind rewrites ? placeholders into $n for postgres.
+func (m *Migrator) bind(query string) string {
+	if m.dialect != Postgres {
+		return query
+	}
+
+	var (
+		out []byte
+		n   int
+	)
+
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			out = strconv.AppendInt(append(out, '$'), int64(n), 10)
+			continue
+		}
+		out = append(out, query[i])
+	}
+
+	return string(out)
+}
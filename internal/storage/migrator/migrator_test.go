@@ -0,0 +1,134 @@
+package migrator
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = fstest.MapFS{
+	"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a(id INTEGER);")},
+	"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b(id INTEGER); CREATE TABLE c(id INTEGER);")},
+	"0002_create_b.down.sql": {Data: []byte("DROP TABLE c; DROP TABLE b;")},
+	"README.md":              {Data: []byte("not a migration")},
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open(SQLite, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", name).Scan(&exists)
+	require.NoError(t, err)
+
+	return exists
+}
+
+func TestUpAndDown(t *testing.T) {
+	db := openDB(t)
+
+	m, err := New(db, SQLite, testMigrations)
+	require.NoError(t, err)
+	assert.Equal(t, 2, m.Latest())
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	applied, err := m.Up()
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.True(t, tableExists(t, db, "c"))
+
+	applied, err = m.Up()
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied, "up is idempotent")
+
+	reverted, err := m.Down(1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+	assert.False(t, tableExists(t, db, "b"))
+	assert.True(t, tableExists(t, db, "a"))
+
+	version, err = m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	reverted, err = m.Down(5)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+	assert.False(t, tableExists(t, db, "a"))
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	db := openDB(t)
+
+	m, err := New(db, SQLite, fstest.MapFS{
+		"0001_ok.up.sql":     {Data: []byte("CREATE TABLE a(id INTEGER);")},
+		"0002_broken.up.sql": {Data: []byte("CREATE TABLE b(id INTEGER); NOT SQL;")},
+	})
+	require.NoError(t, err)
+
+	applied, err := m.Up()
+	assert.Error(t, err)
+	assert.Equal(t, 1, applied)
+	assert.False(t, tableExists(t, db, "b"))
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+}
+
+func TestSchemaTooNew(t *testing.T) {
+	db := openDB(t)
+
+	newer, err := New(db, SQLite, testMigrations)
+	require.NoError(t, err)
+	_, err = newer.Up()
+	require.NoError(t, err)
+
+	older, err := New(db, SQLite, fstest.MapFS{
+		"0001_create_a.up.sql": testMigrations["0001_create_a.up.sql"],
+	})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, older.Check(), ErrSchemaTooNew)
+
+	_, err = older.Up()
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+}
+
+func TestInvalidMigrations(t *testing.T) {
+	db := openDB(t)
+
+	_, err := New(db, SQLite, fstest.MapFS{
+		"0001_only_down.down.sql": {Data: []byte("DROP TABLE a;")},
+	})
+	assert.Error(t, err)
+
+	_, err = New(db, "mysql", testMigrations)
+	assert.Error(t, err)
+}
+
+func TestBind(t *testing.T) {
+	m := &Migrator{dialect: Postgres}
+	assert.Equal(t, "SELECT $1, $2", m.bind("SELECT ?, ?"))
+
+	m = &Migrator{dialect: SQLite}
+	assert.Equal(t, "SELECT ?, ?", m.bind("SELECT ?, ?"))
+}
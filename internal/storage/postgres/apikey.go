@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+func (s *Storage) SaveAPIKey(key storage.APIKey) (int64, error) {
+	const op = "storage.postgres.SaveAPIKey"
+
+	var id int64
+
+	err := s.db.QueryRow(`
+	INSERT INTO api_key(name, prefix, hash, scopes, created_at)
+	VALUES($1, $2, $3, $4, $5) RETURNING id`,
+		key.Name, key.Prefix, key.Hash, strings.Join(key.Scopes, " "), key.CreatedAt.UTC(),
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return 0, storage.ErrAPIKeyExists
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetAPIKey(prefix string) (storage.APIKey, error) {
+	const op = "storage.postgres.GetAPIKey"
+
+	key, err := scanAPIKey(s.db.QueryRow(`
+	SELECT id, name, prefix, hash, scopes, created_at, revoked_at
+	FROM api_key WHERE prefix = $1`, prefix))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) ListAPIKeys() ([]storage.APIKey, error) {
+	const op = "storage.postgres.ListAPIKeys"
+
+	rows, err := s.db.Query(`
+	SELECT id, name, prefix, hash, scopes, created_at, revoked_at
+	FROM api_key ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	keys := []storage.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) RevokeAPIKey(id int64, now time.Time) error {
+	const op = "storage.postgres.RevokeAPIKey"
+
+	result, err := s.db.Exec("UPDATE api_key SET revoked_at = coalesce(revoked_at, $1) WHERE id = $2", now.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (storage.APIKey, error) {
+	var (
+		key       storage.APIKey
+		scopes    string
+		revokedAt sql.NullTime
+	)
+
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &scopes, &key.CreatedAt, &revokedAt); err != nil {
+		return storage.APIKey{}, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	key.RevokedAt = fromNullTime(revokedAt)
+
+	return key, nil
+}
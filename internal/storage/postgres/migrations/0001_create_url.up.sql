@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS url(
+	id BIGSERIAL PRIMARY KEY,
+	alias TEXT NOT NULL UNIQUE,
+	url TEXT NOT NULL);
@@ -0,0 +1,9 @@
+ALTER TABLE url ADD COLUMN expires_at TIMESTAMPTZ;
+CREATE INDEX idx_url_expires_at ON url(expires_at);
+
+CREATE TABLE url_archive(
+	id BIGSERIAL PRIMARY KEY,
+	alias TEXT NOT NULL,
+	url TEXT NOT NULL,
+	expires_at TIMESTAMPTZ,
+	archived_at TIMESTAMPTZ NOT NULL);
@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN max_clicks BIGINT;
+ALTER TABLE url ADD COLUMN clicks BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+CREATE TABLE click(
+	id BIGSERIAL PRIMARY KEY,
+	url_id BIGINT NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	clicked_at TIMESTAMPTZ NOT NULL,
+	referrer TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '');
+CREATE INDEX idx_click_url_id ON click(url_id, clicked_at);
@@ -0,0 +1,4 @@
+DROP INDEX idx_url_domain;
+DROP INDEX idx_url_created_at;
+ALTER TABLE url DROP COLUMN domain;
+ALTER TABLE url DROP COLUMN created_at;
@@ -0,0 +1,11 @@
+-- links created before this migration share one creation time,
+-- the id keeps their order
+ALTER TABLE url ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE url ADD COLUMN domain TEXT NOT NULL DEFAULT '';
+
+UPDATE url SET domain = coalesce(
+	lower(substring(url FROM '^[A-Za-z][A-Za-z0-9+.-]*://(?:[^/?#@]*@)?([^/?#:]+)')),
+	'');
+
+CREATE INDEX idx_url_created_at ON url(created_at, id);
+CREATE INDEX idx_url_domain ON url(domain);
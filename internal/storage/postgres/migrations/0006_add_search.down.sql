@@ -0,0 +1,2 @@
+DROP INDEX idx_url_search;
+ALTER TABLE url DROP COLUMN search;
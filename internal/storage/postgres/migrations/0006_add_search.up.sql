@@ -0,0 +1,8 @@
+-- punctuation is replaced with spaces, otherwise the parser keeps
+-- https://example.com/pricing as a single url token
+ALTER TABLE url ADD COLUMN search tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('simple', regexp_replace(alias, '[^[:alnum:]]+', ' ', 'g')), 'A') ||
+	setweight(to_tsvector('simple', regexp_replace(url, '[^[:alnum:]]+', ' ', 'g')), 'B')
+) STORED;
+
+CREATE INDEX idx_url_search ON url USING GIN (search);
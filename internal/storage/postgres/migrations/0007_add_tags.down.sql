@@ -0,0 +1,2 @@
+DROP TABLE url_tag;
+DROP TABLE tag;
@@ -0,0 +1,9 @@
+CREATE TABLE tag(
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE);
+
+CREATE TABLE url_tag(
+	url_id BIGINT NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	tag_id BIGINT NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+	PRIMARY KEY (url_id, tag_id));
+CREATE INDEX idx_url_tag_tag_id ON url_tag(tag_id);
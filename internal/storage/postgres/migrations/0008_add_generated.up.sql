@@ -0,0 +1,6 @@
+-- generated marks aliases made up by the service, only those are
+-- reused by deduplication. Aliases of older links count as chosen.
+ALTER TABLE url ADD COLUMN generated BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- a btree entry is limited to about 2.7 kB, urls can be longer
+CREATE INDEX idx_url_url ON url USING hash (url);
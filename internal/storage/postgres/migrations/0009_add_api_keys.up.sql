@@ -0,0 +1,10 @@
+-- the key itself is not stored, a leaked database does not leak keys
+CREATE TABLE api_key(
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL UNIQUE,
+	hash TEXT NOT NULL,
+	-- scopes are separated by spaces
+	scopes TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	revoked_at TIMESTAMPTZ);
@@ -0,0 +1,5 @@
+-- owner is the name of the api key that created the link,
+-- older links have none and only admins can manage them
+ALTER TABLE url ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_url_owner ON url(owner);
@@ -0,0 +1,10 @@
+-- aliases are unique again, so only the links of the default workspace are kept
+DELETE FROM url WHERE workspace <> 'default';
+
+ALTER TABLE url_archive DROP COLUMN workspace;
+ALTER TABLE url DROP CONSTRAINT url_workspace_alias_key;
+ALTER TABLE url ADD CONSTRAINT url_alias_key UNIQUE (alias);
+ALTER TABLE url DROP COLUMN workspace;
+
+DROP TABLE workspace_member;
+DROP TABLE workspace;
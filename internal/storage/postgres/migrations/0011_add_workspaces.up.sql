@@ -0,0 +1,21 @@
+CREATE TABLE workspace(
+	name TEXT PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL);
+
+-- links created before workspaces belong to the default one
+INSERT INTO workspace(name, created_at) VALUES ('default', now());
+
+-- name is the name of an api key, keys with the same name share the role
+CREATE TABLE workspace_member(
+	workspace TEXT NOT NULL REFERENCES workspace(name) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	role TEXT NOT NULL,
+	PRIMARY KEY (workspace, name));
+
+-- aliases are unique per workspace
+ALTER TABLE url ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE url ALTER COLUMN workspace DROP DEFAULT;
+ALTER TABLE url DROP CONSTRAINT url_alias_key;
+ALTER TABLE url ADD CONSTRAINT url_workspace_alias_key UNIQUE (workspace, alias);
+
+ALTER TABLE url_archive ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default';
@@ -0,0 +1,3 @@
+DROP INDEX idx_url_norm_url;
+CREATE INDEX idx_url_url ON url USING hash (url);
+ALTER TABLE url DROP COLUMN norm_url;
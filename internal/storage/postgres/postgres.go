@@ -0,0 +1,1046 @@
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/migrator"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// uniqueViolation is the SQLSTATE postgres reports for a unique constraint violation.
+const uniqueViolation = "23505"
+
+var _ storage.Repository = (*Storage)(nil)
+
+type Storage struct {
+	db *sql.DB
+}
+
+func New(dsn string) (*Storage, error) {
+	const op = "storage.postgres.New"
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	m, err := NewMigrator(db)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := m.Up(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{db: db}, nil
+}
+
+// NewMigrator returns a migrator over the embedded postgres migrations.
+func NewMigrator(db *sql.DB) (*migrator.Migrator, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrator.New(db, migrator.Postgres, fsys)
+}
+
+func (s *Storage) SaveUrl(workspace, inputUrl, alias string, opts storage.SaveOptions) (int64, error) {
+	const op = "storage.postgres.SaveUrl"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := insertUrl(tx, workspace, storage.NewLink{Url: inputUrl, Alias: alias, Opts: opts})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) SaveUrls(workspace string, links []storage.NewLink, atomic bool) ([]storage.SaveResult, error) {
+	const op = "storage.postgres.SaveUrls"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	results := make([]storage.SaveResult, len(links))
+	failed := false
+
+	for i, link := range links {
+		// a failed statement aborts a postgres transaction,
+		// rolling back to the savepoint makes it usable again
+		if _, err := tx.Exec("SAVEPOINT batch_item"); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		id, err := insertUrl(tx, workspace, link)
+		switch {
+		case errors.Is(err, storage.ErrUrlExists):
+			results[i].Err = storage.ErrUrlExists
+			failed = true
+
+			if _, err := tx.Exec("ROLLBACK TO SAVEPOINT batch_item"); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		case err != nil:
+			return nil, fmt.Errorf("%s: item %d: %w", op, i, err)
+		default:
+			results[i].ID = id
+		}
+
+		if _, err := tx.Exec("RELEASE SAVEPOINT batch_item"); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if atomic && failed {
+		for i := range results {
+			results[i].ID = 0
+		}
+
+		return results, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return results, nil
+}
+
+// insertUrl stores link with its tags. It returns ErrUrlExists if the alias is taken.
+func insertUrl(tx *sql.Tx, workspace string, link storage.NewLink) (int64, error) {
+	var id int64
+
+	err := tx.QueryRow(
+		`INSERT INTO url(url, alias, domain, created_at, expires_at, max_clicks, generated, owner, workspace, norm_url)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		link.Url, link.Alias, storage.Domain(link.Url), time.Now(),
+		toNullTime(link.Opts.ExpiresAt), toNullInt(link.Opts.MaxClicks), link.Opts.Generated, link.Opts.Owner, workspace,
+		storage.NormalizeURL(link.Url),
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return 0, storage.ErrUrlExists
+		}
+
+		return 0, err
+	}
+
+	if err := addTags(tx, id, link.Opts.Tags); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *Storage) FindAlias(workspace, target, owner string) (string, error) {
+	const op = "storage.postgres.FindAlias"
+
+	var alias string
+
+	err := s.db.QueryRow(`
+	SELECT alias FROM url
+	WHERE norm_url = $1 AND workspace = $2 AND owner = $3 AND generated AND expires_at IS NULL AND max_clicks IS NULL
+	ORDER BY id LIMIT 1`,
+		storage.NormalizeURL(target), workspace, owner,
+	).Scan(&alias)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return alias, nil
+}
+
+func (s *Storage) GetUrl(workspace, alias string) (string, error) {
+	const op = "storage.postgres.GetUrl"
+
+	link, err := s.getLink(workspace, alias)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", storage.ErrUrlNotFound
+		}
+
+		return "", fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if err := link.CheckUsable(time.Now()); err != nil {
+		return "", err
+	}
+
+	return link.Url, nil
+}
+
+func (s *Storage) HitUrl(workspace, alias string) (string, error) {
+	const op = "storage.postgres.HitUrl"
+
+	link, err := s.getLink(workspace, alias)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", storage.ErrUrlNotFound
+		}
+
+		return "", fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if err := link.CheckUsable(time.Now()); err != nil {
+		return "", err
+	}
+
+	if link.MaxClicks == nil {
+		return link.Url, nil
+	}
+
+	// The update locks the row and re-evaluates the condition after
+	// any concurrent update commits, so clicks never exceed max_clicks.
+	result, err := s.db.Exec(
+		"UPDATE url SET clicks = clicks + 1 WHERE id = $1 AND clicks < max_clicks",
+		link.ID,
+	)
+	if err != nil {
+		return "", fmt.Errorf("%s: count click: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return "", storage.ErrClickLimit
+	}
+
+	return link.Url, nil
+}
+
+func (s *Storage) getLink(workspace, alias string) (storage.Link, error) {
+	return scanLink(s.db.QueryRow(
+		"SELECT id, alias, url, created_at, expires_at, max_clicks, clicks, owner FROM url WHERE workspace = $1 AND alias = $2",
+		workspace, alias,
+	))
+}
+
+// scanLink reads a row selected as
+// id, alias, url, created_at, expires_at, max_clicks, clicks, owner.
+func scanLink(row interface{ Scan(dest ...any) error }) (storage.Link, error) {
+	var (
+		link      storage.Link
+		expiresAt sql.NullTime
+		maxClicks sql.NullInt64
+	)
+
+	err := row.Scan(&link.ID, &link.Alias, &link.Url, &link.CreatedAt, &expiresAt, &maxClicks, &link.Clicks, &link.Owner)
+	if err != nil {
+		return storage.Link{}, err
+	}
+
+	link.ExpiresAt = fromNullTime(expiresAt)
+	link.MaxClicks = fromNullInt(maxClicks)
+
+	return link, nil
+}
+
+func (s *Storage) DeleteUrl(workspace, alias string) error {
+	const op = "storage.postgres.DeleteUrl"
+
+	result, err := s.db.Exec("DELETE FROM url WHERE workspace = $1 AND alias = $2", workspace, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrUrlNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) ListUrls(params storage.ListParams) (storage.Page, error) {
+	const op = "storage.postgres.ListUrls"
+
+	if params.Limit <= 0 {
+		return storage.Page{}, fmt.Errorf("%s: limit must be positive", op)
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+
+	// arg adds a query argument and returns its placeholder
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	where = append(where, "workspace = "+arg(params.Workspace))
+
+	if params.AliasPrefix != "" {
+		where = append(where, fmt.Sprintf("left(alias, %s) = %s",
+			arg(utf8.RuneCountInString(params.AliasPrefix)), arg(params.AliasPrefix)))
+	}
+
+	if params.Domain != "" {
+		subdomain := "." + params.Domain
+		where = append(where, fmt.Sprintf("(domain = %s OR right(domain, %s) = %s)",
+			arg(params.Domain), arg(utf8.RuneCountInString(subdomain)), arg(subdomain)))
+	}
+
+	key, cmp, order := "created_at", ">", "ASC"
+	if params.Sort == storage.SortAlias {
+		key = "alias"
+	}
+	if params.Desc {
+		cmp, order = "<", "DESC"
+	}
+
+	if params.Owner != "" {
+		where = append(where, "owner = "+arg(params.Owner))
+	}
+
+	if params.Tag != "" {
+		where = append(where, fmt.Sprintf(`id IN (
+		SELECT url_tag.url_id FROM url_tag JOIN tag ON tag.id = url_tag.tag_id WHERE tag.name = %s)`,
+			arg(storage.NormalizeTag(params.Tag))))
+	}
+
+	if params.After != nil {
+		var after string
+		if params.Sort == storage.SortAlias {
+			after = arg(params.After.Alias)
+		} else {
+			after = arg(params.After.CreatedAt)
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", key, cmp, after, arg(params.After.ID)))
+	}
+
+	query := "SELECT id, alias, url, created_at, expires_at, max_clicks, clicks, owner FROM url WHERE " +
+		strings.Join(where, " AND ")
+	// one extra row tells whether there is a next page
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", key, order, order, arg(params.Limit+1))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return storage.Page{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	links := make([]storage.Link, 0, params.Limit+1)
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return storage.Page{}, fmt.Errorf("%s: %w", op, err)
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return storage.Page{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	page := storage.Page{Links: links}
+	if len(links) > params.Limit {
+		page.Links = links[:params.Limit]
+		page.Next = storage.CursorOf(page.Links[params.Limit-1])
+	}
+
+	if err := s.loadTags(page.Links); err != nil {
+		return storage.Page{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+func (s *Storage) SearchUrls(workspace, owner, query string, limit, offset int) ([]storage.Link, error) {
+	const op = "storage.postgres.SearchUrls"
+
+	terms := storage.SearchTerms(query)
+	if len(terms) == 0 {
+		return []storage.Link{}, nil
+	}
+
+	// every term is a word prefix, terms are letters and digits only
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+
+	rows, err := s.db.Query(`
+	SELECT id, alias, url, created_at, expires_at, max_clicks, clicks, owner
+	FROM url, to_tsquery('simple', $1) AS query
+	WHERE search @@ query AND workspace = $2 AND ($3 = '' OR owner = $3)
+	ORDER BY ts_rank(search, query) DESC, id
+	LIMIT $4 OFFSET $5`,
+		strings.Join(terms, " & "), workspace, owner, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	links := make([]storage.Link, 0, limit)
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.loadTags(links); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+func (s *Storage) AddTags(workspace, alias string, tags []string) ([]string, error) {
+	const op = "storage.postgres.AddTags"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+
+	err = tx.QueryRow("SELECT id FROM url WHERE workspace = $1 AND alias = $2", workspace, alias).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrUrlNotFound
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := addTags(tx, id, tags); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	links := []storage.Link{{ID: id}}
+	if err := s.loadTags(links); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links[0].Tags, nil
+}
+
+func (s *Storage) RemoveTag(workspace, alias, tag string) error {
+	const op = "storage.postgres.RemoveTag"
+
+	result, err := s.db.Exec(`
+	DELETE FROM url_tag
+	WHERE url_id = (SELECT id FROM url WHERE workspace = $1 AND alias = $2)
+	AND tag_id = (SELECT id FROM tag WHERE name = $3)`,
+		workspace, alias, storage.NormalizeTag(tag),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var exists bool
+
+	err = s.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM url WHERE workspace = $1 AND alias = $2)", workspace, alias,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !exists {
+		return storage.ErrUrlNotFound
+	}
+
+	return storage.ErrTagNotFound
+}
+
+func (s *Storage) DeleteByTag(workspace, tag string) (int64, error) {
+	const op = "storage.postgres.DeleteByTag"
+
+	result, err := s.db.Exec(`
+	DELETE FROM url WHERE workspace = $1 AND id IN (
+		SELECT url_tag.url_id FROM url_tag JOIN tag ON tag.id = url_tag.tag_id WHERE tag.name = $2)`,
+		workspace, storage.NormalizeTag(tag),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	return rowsAffected, nil
+}
+
+// addTags labels the link with tags, creating the missing ones.
+func addTags(tx *sql.Tx, urlID int64, tags []string) error {
+	for _, tag := range storage.NormalizeTags(tags) {
+		_, err := tx.Exec("INSERT INTO tag(name) VALUES($1) ON CONFLICT (name) DO NOTHING", tag)
+		if err != nil {
+			return fmt.Errorf("create tag: %w", err)
+		}
+
+		_, err = tx.Exec(`
+		INSERT INTO url_tag(url_id, tag_id) SELECT $1, id FROM tag WHERE name = $2
+		ON CONFLICT DO NOTHING`,
+			urlID, tag,
+		)
+		if err != nil {
+			return fmt.Errorf("label url: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// loadTags fills in the tags of links.
+func (s *Storage) loadTags(links []storage.Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*storage.Link, len(links))
+	ids := make([]int64, 0, len(links))
+
+	for i := range links {
+		byID[links[i].ID] = &links[i]
+		ids = append(ids, links[i].ID)
+	}
+
+	rows, err := s.db.Query(`
+	SELECT url_tag.url_id, tag.name FROM url_tag JOIN tag ON tag.id = url_tag.tag_id
+	WHERE url_tag.url_id = ANY($1) ORDER BY tag.name`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("load tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id  int64
+			tag string
+		)
+		if err := rows.Scan(&id, &tag); err != nil {
+			return fmt.Errorf("load tags: %w", err)
+		}
+
+		link := byID[id]
+		link.Tags = append(link.Tags, tag)
+	}
+
+	return rows.Err()
+}
+
+// exportChunkSize is the number of links ExportUrls reads per query.
+const exportChunkSize = 500
+
+func (s *Storage) ExportUrls(workspace, owner string, fn func(storage.Link) error) error {
+	const op = "storage.postgres.ExportUrls"
+
+	var lastID int64
+
+	for {
+		links, err := s.exportChunk(workspace, owner, lastID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, link := range links {
+			if err := fn(link); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if len(links) < exportChunkSize {
+			return nil
+		}
+
+		lastID = links[len(links)-1].ID
+	}
+}
+
+// exportChunk reads the links following afterID. The rows are closed
+// before the tags are loaded and before the caller writes anything.
+func (s *Storage) exportChunk(workspace, owner string, afterID int64) ([]storage.Link, error) {
+	rows, err := s.db.Query(`
+	SELECT id, alias, url, created_at, expires_at, max_clicks, clicks, owner FROM url
+	WHERE workspace = $1 AND ($2 = '' OR owner = $2) AND id > $3 ORDER BY id LIMIT $4`,
+		workspace, owner, afterID, exportChunkSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]storage.Link, 0, exportChunkSize)
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+
+	if err := s.loadTags(links); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (s *Storage) ImportUrls(workspace string, links []storage.Link, onConflict string, dryRun bool) (storage.ImportReport, error) {
+	const op = "storage.postgres.ImportUrls"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.ImportReport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var report storage.ImportReport
+
+	for _, link := range links {
+		var id int64
+
+		err := tx.QueryRow(
+			"SELECT id FROM url WHERE workspace = $1 AND alias = $2 FOR UPDATE", workspace, link.Alias,
+		).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := importUrl(tx, workspace, link); err != nil {
+				return storage.ImportReport{}, fmt.Errorf("%s: %w", op, err)
+			}
+			report.Created++
+
+			continue
+		}
+		if err != nil {
+			return storage.ImportReport{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		report.Conflicts = append(report.Conflicts, link.Alias)
+
+		if onConflict != storage.ConflictOverwrite {
+			report.Skipped++
+
+			continue
+		}
+
+		if err := overwriteUrl(tx, id, link); err != nil {
+			return storage.ImportReport{}, fmt.Errorf("%s: %w", op, err)
+		}
+		report.Updated++
+	}
+
+	if onConflict == storage.ConflictFail && len(report.Conflicts) > 0 {
+		return storage.ImportReport{Conflicts: report.Conflicts}, storage.ErrUrlExists
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.ImportReport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return report, nil
+}
+
+func importUrl(tx *sql.Tx, workspace string, link storage.Link) error {
+	createdAt := link.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	var id int64
+
+	err := tx.QueryRow(`
+	INSERT INTO url(url, alias, domain, created_at, expires_at, max_clicks, clicks, owner, workspace, norm_url)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		link.Url, link.Alias, storage.Domain(link.Url), createdAt,
+		toNullTime(link.ExpiresAt), toNullInt(link.MaxClicks), link.Clicks, link.Owner, workspace,
+		storage.NormalizeURL(link.Url),
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("insert %s: %w", link.Alias, err)
+	}
+
+	return addTags(tx, id, link.Tags)
+}
+
+// overwriteUrl replaces the link with id by link. The id stays,
+// so the click history of the alias is kept.
+func overwriteUrl(tx *sql.Tx, id int64, link storage.Link) error {
+	var createdAt *time.Time
+	if !link.CreatedAt.IsZero() {
+		createdAt = &link.CreatedAt
+	}
+
+	_, err := tx.Exec(`
+	UPDATE url SET url = $1, domain = $2, created_at = COALESCE($3, created_at),
+		expires_at = $4, max_clicks = $5, clicks = $6, owner = $7, norm_url = $8
+	WHERE id = $9`,
+		link.Url, storage.Domain(link.Url), toNullTime(createdAt),
+		toNullTime(link.ExpiresAt), toNullInt(link.MaxClicks), link.Clicks, link.Owner, storage.NormalizeURL(link.Url), id,
+	)
+	if err != nil {
+		return fmt.Errorf("overwrite %s: %w", link.Alias, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM url_tag WHERE url_id = $1", id); err != nil {
+		return fmt.Errorf("overwrite %s: %w", link.Alias, err)
+	}
+
+	return addTags(tx, id, link.Tags)
+}
+
+func (s *Storage) UpdateUrl(workspace, url, alias string) (string, error) {
+	const op = "storage.postgres.UpdateUrl"
+
+	result, err := s.db.Exec(
+		"UPDATE url SET url = $1, domain = $2, norm_url = $3 WHERE workspace = $4 AND alias = $5",
+		url, storage.Domain(url), storage.NormalizeURL(url), workspace, alias,
+	)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return "", storage.ErrAliasNotFound
+	}
+
+	return alias, nil
+}
+
+func (s *Storage) GetOwner(workspace, alias string) (string, error) {
+	const op = "storage.postgres.GetOwner"
+
+	var owner string
+
+	err := s.db.QueryRow("SELECT owner FROM url WHERE workspace = $1 AND alias = $2", workspace, alias).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return owner, nil
+}
+
+func (s *Storage) SetOwner(workspace, alias, owner string) error {
+	const op = "storage.postgres.SetOwner"
+
+	result, err := s.db.Exec("UPDATE url SET owner = $1 WHERE workspace = $2 AND alias = $3", owner, workspace, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrUrlNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) RenameAlias(workspace, alias, newAlias string) error {
+	const op = "storage.postgres.RenameAlias"
+
+	result, err := s.db.Exec(
+		"UPDATE url SET alias = $1, generated = FALSE WHERE workspace = $2 AND alias = $3",
+		newAlias, workspace, alias,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return storage.ErrUrlExists
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrAliasNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) UpdateLink(workspace, alias, newAlias, url string) error {
+	const op = "storage.postgres.UpdateLink"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+
+	err = tx.QueryRow(
+		"SELECT id FROM url WHERE workspace = $1 AND alias = $2 FOR UPDATE",
+		workspace, alias,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrAliasNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if newAlias != "" && newAlias != alias {
+		_, err := tx.Exec("UPDATE url SET alias = $1, generated = FALSE WHERE id = $2", newAlias, id)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+				return storage.ErrUrlExists
+			}
+
+			return fmt.Errorf("%s: rename: %w", op, err)
+		}
+	}
+
+	if url != "" {
+		_, err := tx.Exec("UPDATE url SET url = $1, domain = $2, norm_url = $3 WHERE id = $4",
+			url, storage.Domain(url), storage.NormalizeURL(url), id,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: update url: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) PurgeExpired(now time.Time) (int64, error) {
+	const op = "storage.postgres.PurgeExpired"
+
+	result, err := s.db.Exec("DELETE FROM url WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (s *Storage) ArchiveExpired(now time.Time) (int64, error) {
+	const op = "storage.postgres.ArchiveExpired"
+
+	result, err := s.db.Exec(`
+	WITH expired AS (
+		DELETE FROM url WHERE expires_at <= $1
+		RETURNING workspace, alias, url, expires_at
+	)
+	INSERT INTO url_archive(workspace, alias, url, expires_at, archived_at)
+	SELECT workspace, alias, url, expires_at, $1 FROM expired`,
+		now,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (s *Storage) SaveClicks(clicks []storage.Click) error {
+	const op = "storage.postgres.SaveClicks"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO click(url_id, clicked_at, referrer, user_agent, ip)
+	SELECT id, $1, $2, $3, $4 FROM url WHERE workspace = $5 AND alias = $6`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	for _, click := range clicks {
+		_, err := stmt.Exec(click.At.UTC(), click.Referrer, click.UserAgent, click.IP, click.Workspace, click.Alias)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetStats(workspace, alias string) (storage.Stats, error) {
+	const op = "storage.postgres.GetStats"
+
+	var id int64
+
+	err := s.db.QueryRow("SELECT id FROM url WHERE workspace = $1 AND alias = $2", workspace, alias).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Stats{}, storage.ErrUrlNotFound
+		}
+
+		return storage.Stats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(`
+	SELECT to_char(clicked_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, COUNT(*) FROM click
+	WHERE url_id = $1 GROUP BY day ORDER BY day`, id)
+	if err != nil {
+		return storage.Stats{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	stats := storage.Stats{PerDay: []storage.DayCount{}}
+
+	for rows.Next() {
+		var day storage.DayCount
+		if err := rows.Scan(&day.Date, &day.Count); err != nil {
+			return storage.Stats{}, fmt.Errorf("%s: %w", op, err)
+		}
+		stats.Total += day.Count
+		stats.PerDay = append(stats.PerDay, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return storage.Stats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func fromNullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
+
+func toNullInt(n *int64) sql.NullInt64 {
+	if n == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: *n, Valid: true}
+}
+
+func fromNullInt(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+
+	return &n.Int64
+}
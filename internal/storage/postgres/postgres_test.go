@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorage runs the conformance suite against the database from
+// POSTGRES_TEST_DSN, e.g. the one started by `make runPostgres`.
+func TestStorage(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		s, err := New(dsn)
+		require.NoError(t, err)
+
+		_, err = s.db.Exec("TRUNCATE url, url_archive, click, url_tag, tag RESTART IDENTITY")
+		require.NoError(t, err)
+
+		_, err = s.db.Exec("TRUNCATE workspace_member")
+		require.NoError(t, err)
+
+		_, err = s.db.Exec("DELETE FROM workspace WHERE name <> $1", storage.DefaultWorkspace)
+		require.NoError(t, err)
+
+		return s
+	})
+}
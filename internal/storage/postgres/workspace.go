@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+func (s *Storage) CreateWorkspace(ws storage.Workspace) error {
+	const op = "storage.postgres.CreateWorkspace"
+
+	_, err := s.db.Exec("INSERT INTO workspace(name, created_at) VALUES($1, $2)", ws.Name, ws.CreatedAt.UTC())
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return storage.ErrWorkspaceExists
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetWorkspace(name string) (storage.Workspace, error) {
+	const op = "storage.postgres.GetWorkspace"
+
+	var ws storage.Workspace
+
+	err := s.db.QueryRow("SELECT name, created_at FROM workspace WHERE name = $1", name).Scan(&ws.Name, &ws.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Workspace{}, storage.ErrWorkspaceNotFound
+	}
+	if err != nil {
+		return storage.Workspace{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ws, nil
+}
+
+func (s *Storage) ListWorkspaces() ([]storage.Workspace, error) {
+	const op = "storage.postgres.ListWorkspaces"
+
+	rows, err := s.db.Query("SELECT name, created_at FROM workspace ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	list := []storage.Workspace{}
+	for rows.Next() {
+		var ws storage.Workspace
+		if err := rows.Scan(&ws.Name, &ws.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		list = append(list, ws)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return list, nil
+}
+
+func (s *Storage) SetMember(member storage.Member) error {
+	const op = "storage.postgres.SetMember"
+
+	// selecting the workspace reports a missing one without a foreign key error
+	result, err := s.db.Exec(`
+	INSERT INTO workspace_member(workspace, name, role)
+	SELECT name, $1, $2 FROM workspace WHERE name = $3
+	ON CONFLICT(workspace, name) DO UPDATE SET role = excluded.role`,
+		member.Name, member.Role, member.Workspace,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrWorkspaceNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) GetMember(workspace, name string) (storage.Member, error) {
+	const op = "storage.postgres.GetMember"
+
+	member := storage.Member{Workspace: workspace, Name: name}
+
+	err := s.db.QueryRow(
+		"SELECT role FROM workspace_member WHERE workspace = $1 AND name = $2", workspace, name,
+	).Scan(&member.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Member{}, storage.ErrMemberNotFound
+	}
+	if err != nil {
+		return storage.Member{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return member, nil
+}
+
+func (s *Storage) ListMembers(workspace string) ([]storage.Member, error) {
+	const op = "storage.postgres.ListMembers"
+
+	rows, err := s.db.Query(
+		"SELECT workspace, name, role FROM workspace_member WHERE workspace = $1 ORDER BY name", workspace,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	list := []storage.Member{}
+	for rows.Next() {
+		var member storage.Member
+		if err := rows.Scan(&member.Workspace, &member.Name, &member.Role); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		list = append(list, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return list, nil
+}
+
+func (s *Storage) RemoveMember(workspace, name string) error {
+	const op = "storage.postgres.RemoveMember"
+
+	result, err := s.db.Exec("DELETE FROM workspace_member WHERE workspace = $1 AND name = $2", workspace, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrMemberNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+func (s *Storage) SaveAPIKey(key storage.APIKey) (int64, error) {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	res, err := s.db.Exec(`
+	INSERT INTO api_key(name, prefix, hash, scopes, created_at)
+	VALUES(?, ?, ?, ?, ?)`,
+		key.Name, key.Prefix, key.Hash, strings.Join(key.Scopes, " "), key.CreatedAt.UTC(),
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return 0, storage.ErrAPIKeyExists
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetAPIKey(prefix string) (storage.APIKey, error) {
+	const op = "storage.sqlite.GetAPIKey"
+
+	key, err := scanAPIKey(s.db.QueryRow(`
+	SELECT id, name, prefix, hash, scopes, created_at, revoked_at
+	FROM api_key WHERE prefix = ?`, prefix))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) ListAPIKeys() ([]storage.APIKey, error) {
+	const op = "storage.sqlite.ListAPIKeys"
+
+	rows, err := s.db.Query(`
+	SELECT id, name, prefix, hash, scopes, created_at, revoked_at
+	FROM api_key ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	keys := []storage.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) RevokeAPIKey(id int64, now time.Time) error {
+	const op = "storage.sqlite.RevokeAPIKey"
+
+	result, err := s.db.Exec("UPDATE api_key SET revoked_at = coalesce(revoked_at, ?) WHERE id = ?", now.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (storage.APIKey, error) {
+	var (
+		key       storage.APIKey
+		scopes    string
+		revokedAt sql.NullTime
+	)
+
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &scopes, &key.CreatedAt, &revokedAt); err != nil {
+		return storage.APIKey{}, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	key.RevokedAt = fromNullTime(revokedAt)
+
+	return key, nil
+}
@@ -0,0 +1,164 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	// backupStepPages is the number of pages copied while the source is locked.
+	backupStepPages = 1024
+	// backupStepPause lets writers in between the steps. A write by another
+	// connection restarts the copy, so the snapshot is always consistent.
+	backupStepPause = 5 * time.Millisecond
+)
+
+// Backup writes a consistent copy of the database to path with the
+// SQLite online backup API. The server keeps serving while it runs.
+func (s *Storage) Backup(ctx context.Context, path string) error {
+	const op = "storage.sqlite.Backup"
+
+	if err := backup(ctx, s.db, path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// File is a database opened by another process, e.g. by the running
+// server while the backup command makes a copy of it.
+type File string
+
+// Backup writes a consistent copy of the database file to path.
+func (f File) Backup(ctx context.Context, path string) error {
+	const op = "storage.sqlite.File.Backup"
+
+	// sql.Open would silently create a missing database
+	if _, err := os.Stat(string(f)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+string(f)+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer db.Close()
+
+	if err := backup(ctx, db, path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func backup(ctx context.Context, src *sql.DB, path string) error {
+	dst, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+
+	return dstConn.Raw(func(dstDriver any) error {
+		return srcConn.Raw(func(srcDriver any) error {
+			dstSQLite, ok := dstDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("destination is not a sqlite connection")
+			}
+
+			srcSQLite, ok := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("source is not a sqlite connection")
+			}
+
+			b, err := dstSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return fmt.Errorf("start backup: %w", err)
+			}
+
+			for {
+				done, err := b.Step(backupStepPages)
+				if err != nil {
+					_ = b.Finish()
+					return fmt.Errorf("backup step: %w", err)
+				}
+
+				if done {
+					return b.Finish()
+				}
+
+				select {
+				case <-ctx.Done():
+					_ = b.Finish()
+					return ctx.Err()
+				case <-time.After(backupStepPause):
+				}
+			}
+		})
+	})
+}
+
+// ErrCorrupted is returned by CheckIntegrity for a damaged database.
+var ErrCorrupted = errors.New("database integrity check failed")
+
+// CheckIntegrity opens the database at path read-only and runs
+// PRAGMA integrity_check on it.
+func CheckIntegrity(path string) error {
+	const op = "storage.sqlite.CheckIntegrity"
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", op, ErrCorrupted, err)
+	}
+	defer rows.Close()
+
+	var problems []string
+
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w: %w", op, ErrCorrupted, err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: %w: %v", op, ErrCorrupted, problems)
+	}
+
+	return nil
+}
@@ -0,0 +1,3 @@
+DROP TABLE url_archive;
+DROP INDEX idx_url_expires_at;
+ALTER TABLE url DROP COLUMN expires_at;
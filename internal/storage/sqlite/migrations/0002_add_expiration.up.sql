@@ -0,0 +1,9 @@
+ALTER TABLE url ADD COLUMN expires_at DATETIME;
+CREATE INDEX idx_url_expires_at ON url(expires_at);
+
+CREATE TABLE url_archive(
+	id INTEGER PRIMARY KEY,
+	alias TEXT NOT NULL,
+	url TEXT NOT NULL,
+	expires_at DATETIME,
+	archived_at DATETIME NOT NULL);
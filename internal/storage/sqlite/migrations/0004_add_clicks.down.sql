@@ -0,0 +1,2 @@
+DROP TRIGGER click_cleanup;
+DROP TABLE click;
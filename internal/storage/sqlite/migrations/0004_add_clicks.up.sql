@@ -0,0 +1,14 @@
+CREATE TABLE click(
+	id INTEGER PRIMARY KEY,
+	url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	clicked_at DATETIME NOT NULL,
+	referrer TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '');
+CREATE INDEX idx_click_url_id ON click(url_id, clicked_at);
+
+-- foreign keys are off by default in sqlite, so cascade by hand
+CREATE TRIGGER click_cleanup AFTER DELETE ON url
+BEGIN
+	DELETE FROM click WHERE url_id = OLD.id;
+END;
@@ -0,0 +1,17 @@
+ALTER TABLE url ADD COLUMN created_at DATETIME;
+ALTER TABLE url ADD COLUMN domain TEXT NOT NULL DEFAULT '';
+
+-- links created before this migration share one creation time,
+-- the id keeps their order
+UPDATE url SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now');
+
+-- host of the target url: cut the scheme, the path, userinfo and the port
+UPDATE url SET domain = lower(substr(url, instr(url, '://') + 3)) WHERE instr(url, '://') > 0;
+UPDATE url SET domain = substr(domain, 1, instr(domain, '/') - 1) WHERE instr(domain, '/') > 0;
+UPDATE url SET domain = substr(domain, 1, instr(domain, '?') - 1) WHERE instr(domain, '?') > 0;
+UPDATE url SET domain = substr(domain, 1, instr(domain, '#') - 1) WHERE instr(domain, '#') > 0;
+UPDATE url SET domain = substr(domain, instr(domain, '@') + 1) WHERE instr(domain, '@') > 0;
+UPDATE url SET domain = substr(domain, 1, instr(domain, ':') - 1) WHERE instr(domain, ':') > 0;
+
+CREATE INDEX idx_url_created_at ON url(created_at, id);
+CREATE INDEX idx_url_domain ON url(domain);
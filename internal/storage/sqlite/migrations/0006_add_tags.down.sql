@@ -0,0 +1,3 @@
+DROP TRIGGER url_tag_cleanup;
+DROP TABLE url_tag;
+DROP TABLE tag;
@@ -0,0 +1,15 @@
+CREATE TABLE tag(
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE);
+
+CREATE TABLE url_tag(
+	url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+	PRIMARY KEY (url_id, tag_id));
+CREATE INDEX idx_url_tag_tag_id ON url_tag(tag_id);
+
+-- foreign keys are off by default in sqlite, so cascade by hand
+CREATE TRIGGER url_tag_cleanup AFTER DELETE ON url
+BEGIN
+	DELETE FROM url_tag WHERE url_id = OLD.id;
+END;
@@ -0,0 +1,2 @@
+DROP INDEX idx_url_url;
+ALTER TABLE url DROP COLUMN generated;
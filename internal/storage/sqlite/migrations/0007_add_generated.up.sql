@@ -0,0 +1,5 @@
+-- generated marks aliases made up by the service, only those are
+-- reused by deduplication. Aliases of older links count as chosen.
+ALTER TABLE url ADD COLUMN generated BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_url_url ON url(url);
@@ -0,0 +1,41 @@
+-- aliases are unique again, so only the links of the default workspace are kept
+DELETE FROM url WHERE workspace <> 'default';
+
+CREATE TABLE url_old(
+	id INTEGER PRIMARY KEY,
+	alias TEXT NOT NULL UNIQUE,
+	url TEXT NOT NULL,
+	expires_at DATETIME,
+	max_clicks INTEGER,
+	clicks INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME,
+	domain TEXT NOT NULL DEFAULT '',
+	generated BOOLEAN NOT NULL DEFAULT 0,
+	owner TEXT NOT NULL DEFAULT '');
+
+INSERT INTO url_old(id, alias, url, expires_at, max_clicks, clicks, created_at, domain, generated, owner)
+SELECT id, alias, url, expires_at, max_clicks, clicks, created_at, domain, generated, owner FROM url;
+
+DROP TABLE url;
+ALTER TABLE url_old RENAME TO url;
+
+CREATE INDEX idx_alias ON url(alias);
+CREATE INDEX idx_url_expires_at ON url(expires_at);
+CREATE INDEX idx_url_created_at ON url(created_at, id);
+CREATE INDEX idx_url_domain ON url(domain);
+CREATE INDEX idx_url_url ON url(url);
+CREATE INDEX idx_url_owner ON url(owner);
+
+CREATE TRIGGER click_cleanup AFTER DELETE ON url
+BEGIN
+	DELETE FROM click WHERE url_id = OLD.id;
+END;
+
+CREATE TRIGGER url_tag_cleanup AFTER DELETE ON url
+BEGIN
+	DELETE FROM url_tag WHERE url_id = OLD.id;
+END;
+
+ALTER TABLE url_archive DROP COLUMN workspace;
+DROP TABLE workspace_member;
+DROP TABLE workspace;
@@ -0,0 +1,56 @@
+CREATE TABLE workspace(
+	name TEXT PRIMARY KEY,
+	created_at DATETIME NOT NULL);
+
+-- links created before workspaces belong to the default one
+INSERT INTO workspace(name, created_at) VALUES ('default', strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'));
+
+-- name is the name of an api key, keys with the same name share the role
+CREATE TABLE workspace_member(
+	workspace TEXT NOT NULL REFERENCES workspace(name) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	role TEXT NOT NULL,
+	PRIMARY KEY (workspace, name));
+
+-- aliases become unique per workspace. sqlite cannot change the unique
+-- constraint of a column, so the table is rebuilt with the same ids
+CREATE TABLE url_new(
+	id INTEGER PRIMARY KEY,
+	workspace TEXT NOT NULL,
+	alias TEXT NOT NULL,
+	url TEXT NOT NULL,
+	expires_at DATETIME,
+	max_clicks INTEGER,
+	clicks INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME,
+	domain TEXT NOT NULL DEFAULT '',
+	generated BOOLEAN NOT NULL DEFAULT 0,
+	owner TEXT NOT NULL DEFAULT '',
+	UNIQUE (workspace, alias));
+
+INSERT INTO url_new(id, workspace, alias, url, expires_at, max_clicks, clicks, created_at, domain, generated, owner)
+SELECT id, 'default', alias, url, expires_at, max_clicks, clicks, created_at, domain, generated, owner FROM url;
+
+-- dropping the table drops its indexes and triggers, the search
+-- triggers are recreated by the next start
+DROP TABLE url;
+ALTER TABLE url_new RENAME TO url;
+
+CREATE INDEX idx_url_expires_at ON url(expires_at);
+CREATE INDEX idx_url_created_at ON url(created_at, id);
+CREATE INDEX idx_url_domain ON url(domain);
+CREATE INDEX idx_url_url ON url(url);
+CREATE INDEX idx_url_owner ON url(owner);
+
+-- foreign keys are off by default in sqlite, so cascade by hand
+CREATE TRIGGER click_cleanup AFTER DELETE ON url
+BEGIN
+	DELETE FROM click WHERE url_id = OLD.id;
+END;
+
+CREATE TRIGGER url_tag_cleanup AFTER DELETE ON url
+BEGIN
+	DELETE FROM url_tag WHERE url_id = OLD.id;
+END;
+
+ALTER TABLE url_archive ADD COLUMN workspace TEXT NOT NULL DEFAULT 'default';
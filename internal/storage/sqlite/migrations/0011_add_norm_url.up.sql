@@ -0,0 +1,8 @@
+-- norm_url is the url normalized by storage.NormalizeURL, deduplication
+-- looks links up by it while url keeps the spelling of the user.
+-- Generated links stored normalized urls so far, the only ones looked up.
+ALTER TABLE url ADD COLUMN norm_url TEXT NOT NULL DEFAULT '';
+UPDATE url SET norm_url = url;
+
+DROP INDEX idx_url_url;
+CREATE INDEX idx_url_norm_url ON url(norm_url);
@@ -0,0 +1,195 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+// The full-text index is not a versioned migration: FTS5 is only
+// compiled into go-sqlite3 with the sqlite_fts5 build tag, and the
+// same database may be opened by binaries built with and without it.
+// Searchable columns are listed in the table and in every trigger;
+// after adding one, bump the name of the insert trigger so that the
+// index is rebuilt on the next start.
+const ftsSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS url_fts USING fts5(
+	alias, url, content='url', content_rowid='id');
+
+CREATE TRIGGER url_fts_insert AFTER INSERT ON url BEGIN
+	INSERT INTO url_fts(rowid, alias, url) VALUES (new.id, new.alias, new.url);
+END;
+
+CREATE TRIGGER url_fts_delete AFTER DELETE ON url BEGIN
+	INSERT INTO url_fts(url_fts, rowid, alias, url) VALUES ('delete', old.id, old.alias, old.url);
+END;
+
+CREATE TRIGGER url_fts_update AFTER UPDATE OF alias, url ON url BEGIN
+	INSERT INTO url_fts(url_fts, rowid, alias, url) VALUES ('delete', old.id, old.alias, old.url);
+	INSERT INTO url_fts(rowid, alias, url) VALUES (new.id, new.alias, new.url);
+END;
+
+INSERT INTO url_fts(url_fts) VALUES ('rebuild');`
+
+// Dropping the triggers needs no FTS5 support, unlike touching url_fts.
+const ftsDropTriggers = `
+DROP TRIGGER IF EXISTS url_fts_insert;
+DROP TRIGGER IF EXISTS url_fts_delete;
+DROP TRIGGER IF EXISTS url_fts_update;`
+
+// setupSearch reports whether FTS5 is available and creates the index
+// if it is missing. Without FTS5 it removes the triggers left by a build
+// with FTS5, as they would fail every write to url.
+func setupSearch(db *sql.DB) (bool, error) {
+	const op = "storage.sqlite.setupSearch"
+
+	var fts bool
+
+	err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !fts {
+		if _, err := db.Exec(ftsDropTriggers); err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return false, nil
+	}
+
+	var exists bool
+
+	err = db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = 'url_fts_insert')",
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if exists {
+		return true, nil
+	}
+
+	// the index may be stale after writes made without the triggers
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(ftsDropTriggers + ftsSchema); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+func (s *Storage) SearchUrls(workspace, owner, query string, limit, offset int) ([]storage.Link, error) {
+	const op = "storage.sqlite.SearchUrls"
+
+	terms := storage.SearchTerms(query)
+	if len(terms) == 0 {
+		return []storage.Link{}, nil
+	}
+
+	var (
+		stmt string
+		args []any
+	)
+
+	if s.fts {
+		stmt, args = ftsSearch(workspace, owner, terms)
+	} else {
+		stmt, args = substringSearch(workspace, owner, terms)
+	}
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	links := make([]storage.Link, 0, limit)
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.loadTags(links); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+// ftsSearch matches every term as a word prefix and ranks with bm25,
+// an alias match weighing ten times a url match.
+func ftsSearch(workspace, owner string, terms []string) (string, []any) {
+	match := make([]string, 0, len(terms))
+	for _, term := range terms {
+		// terms are letters and digits only, quoting makes them literal
+		match = append(match, `"`+term+`"*`)
+	}
+
+	where := []string{"url_fts MATCH ?", "url.workspace = ?"}
+	args := []any{strings.Join(match, " "), workspace}
+
+	if owner != "" {
+		where = append(where, "url.owner = ?")
+		args = append(args, owner)
+	}
+
+	return fmt.Sprintf(`
+	SELECT url.id, url.alias, url.url, url.created_at, url.expires_at, url.max_clicks, url.clicks, url.owner
+	FROM url_fts JOIN url ON url.id = url_fts.rowid
+	WHERE %s
+	ORDER BY bm25(url_fts, 10.0, 1.0), url.id
+	LIMIT ? OFFSET ?`, strings.Join(where, " AND ")), args
+}
+
+// substringSearch is the fallback without FTS5: every term has to be
+// a substring of the alias or the url, links with more terms in the
+// alias go first. It scans the whole table.
+func substringSearch(workspace, owner string, terms []string) (string, []any) {
+	var rank []string
+
+	where := []string{"workspace = ?"}
+	args := []any{workspace}
+
+	if owner != "" {
+		where = append(where, "owner = ?")
+		args = append(args, owner)
+	}
+
+	for _, term := range terms {
+		where = append(where, "(instr(lower(alias), ?) > 0 OR instr(lower(url), ?) > 0)")
+		args = append(args, term, term)
+	}
+
+	for _, term := range terms {
+		rank = append(rank, "(instr(lower(alias), ?) > 0)")
+		args = append(args, term)
+	}
+
+	return fmt.Sprintf(`
+	SELECT id, alias, url, created_at, expires_at, max_clicks, clicks, owner FROM url
+	WHERE %s
+	ORDER BY %s DESC, id
+	LIMIT ? OFFSET ?`, strings.Join(where, " AND "), strings.Join(rank, " + ")), args
+}
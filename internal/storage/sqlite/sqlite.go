@@ -2,15 +2,28 @@ package sqlite
 
 import (
 	"database/sql"
+	"embed"
 	"errors"
 	"fmt"
 	"github.com/mattn/go-sqlite3"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/popvaleks/url-shortener/internal/storage/migrator"
+	"io/fs"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+var _ storage.Repository = (*Storage)(nil)
+
 type Storage struct {
 	db *sql.DB
+	// fts is set when search uses the FTS5 index.
+	fts bool
 }
 
 func New(dbPath string) (*Storage, error) {
@@ -21,64 +34,180 @@ func New(dbPath string) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmt, err := db.Prepare(`
-	CREATE TABLE IF NOT EXISTS url(
-		id INTEGER PRIMARY KEY,
-		alias TEXT NOT NULL UNIQUE,
-		url TEXT NOT NULL);
-	CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
-	`)
-
+	m, err := NewMigrator(db)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = stmt.Exec()
+	if _, err := m.Up(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	fts, err := setupSearch(db)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	return &Storage{db: db, fts: fts}, nil
+}
+
+// NewMigrator returns a migrator over the embedded sqlite migrations.
+func NewMigrator(db *sql.DB) (*migrator.Migrator, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrator.New(db, migrator.SQLite, fsys)
 }
 
-func (s *Storage) SaveUrl(inputUrl string, alias string) (int64, error) {
+func (s *Storage) SaveUrl(workspace, inputUrl, alias string, opts storage.SaveOptions) (int64, error) {
 	const op = "storage.sqlite.SaveUrl"
 
-	stmt, err := s.db.Prepare("INSERT INTO url(url, alias) VALUES(?, ?)")
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := insertUrl(tx, workspace, storage.NewLink{Url: inputUrl, Alias: alias, Opts: opts})
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	res, err := stmt.Exec(inputUrl, alias)
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) SaveUrls(workspace string, links []storage.NewLink, atomic bool) ([]storage.SaveResult, error) {
+	const op = "storage.sqlite.SaveUrls"
 
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	results := make([]storage.SaveResult, len(links))
+	failed := false
+
+	for i, link := range links {
+		// the savepoint drops the tags of a link whose insert failed halfway
+		if _, err := tx.Exec("SAVEPOINT batch_item"); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		id, err := insertUrl(tx, workspace, link)
+		switch {
+		case errors.Is(err, storage.ErrUrlExists):
+			results[i].Err = storage.ErrUrlExists
+			failed = true
+
+			if _, err := tx.Exec("ROLLBACK TO SAVEPOINT batch_item"); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		case err != nil:
+			return nil, fmt.Errorf("%s: item %d: %w", op, i, err)
+		default:
+			results[i].ID = id
+		}
+
+		if _, err := tx.Exec("RELEASE SAVEPOINT batch_item"); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if atomic && failed {
+		for i := range results {
+			results[i].ID = 0
+		}
+
+		return results, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return results, nil
+}
+
+// insertUrl stores link with its tags. It returns ErrUrlExists if the alias is taken.
+func insertUrl(tx *sql.Tx, workspace string, link storage.NewLink) (int64, error) {
+	res, err := tx.Exec(`
+	INSERT INTO url(workspace, url, norm_url, alias, domain, created_at, expires_at, max_clicks, generated, owner)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		workspace, link.Url, storage.NormalizeURL(link.Url), link.Alias, storage.Domain(link.Url), time.Now().UTC(),
+		toNullTime(link.Opts.ExpiresAt), toNullInt(link.Opts.MaxClicks), link.Opts.Generated, link.Opts.Owner,
+	)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
-			return 0, fmt.Errorf("%s: %w", op, sqliteErr)
+			return 0, storage.ErrUrlExists
 		}
 
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, err
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	if err := addTags(tx, id, link.Opts.Tags); err != nil {
+		return 0, err
 	}
 
 	return id, nil
 }
 
-func (s *Storage) GetUrl(alias string) (string, error) {
-	const op = "storage.sqlite.GetUrl"
+func (s *Storage) FindAlias(workspace, target, owner string) (string, error) {
+	const op = "storage.sqlite.FindAlias"
+
+	var alias string
 
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+	err := s.db.QueryRow(`
+	SELECT alias FROM url
+	WHERE norm_url = ? AND workspace = ? AND owner = ? AND generated AND expires_at IS NULL AND max_clicks IS NULL
+	ORDER BY id LIMIT 1`,
+		storage.NormalizeURL(target), workspace, owner,
+	).Scan(&alias)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrUrlNotFound
+	}
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	var url string
+	return alias, nil
+}
+
+func (s *Storage) GetUrl(workspace, alias string) (string, error) {
+	const op = "storage.sqlite.GetUrl"
+
+	link, err := s.getLink(workspace, alias)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", storage.ErrUrlNotFound
+		}
+
+		return "", fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if err := link.CheckUsable(time.Now()); err != nil {
+		return "", err
+	}
+
+	return link.Url, nil
+}
+
+func (s *Storage) HitUrl(workspace, alias string) (string, error) {
+	const op = "storage.sqlite.HitUrl"
 
-	err = stmt.QueryRow(alias).Scan(&url)
+	link, err := s.getLink(workspace, alias)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", storage.ErrUrlNotFound
@@ -87,18 +216,73 @@ func (s *Storage) GetUrl(alias string) (string, error) {
 		return "", fmt.Errorf("%s: execute statement: %w", op, err)
 	}
 
-	return url, nil
+	if err := link.CheckUsable(time.Now()); err != nil {
+		return "", err
+	}
+
+	if link.MaxClicks == nil {
+		return link.Url, nil
+	}
+
+	// The limit is checked again by the update itself: sqlite runs a
+	// single statement under the database write lock, so concurrent
+	// hits cannot push clicks past max_clicks.
+	result, err := s.db.Exec(
+		"UPDATE url SET clicks = clicks + 1 WHERE id = ? AND clicks < max_clicks",
+		link.ID,
+	)
+	if err != nil {
+		return "", fmt.Errorf("%s: count click: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return "", storage.ErrClickLimit
+	}
+
+	return link.Url, nil
+}
+
+func (s *Storage) getLink(workspace, alias string) (storage.Link, error) {
+	return scanLink(s.db.QueryRow(
+		"SELECT id, alias, url, created_at, expires_at, max_clicks, clicks, owner FROM url WHERE workspace = ? AND alias = ?",
+		workspace, alias,
+	))
+}
+
+// scanLink reads a row selected as
+// id, alias, url, created_at, expires_at, max_clicks, clicks, owner.
+func scanLink(row interface{ Scan(dest ...any) error }) (storage.Link, error) {
+	var (
+		link      storage.Link
+		expiresAt sql.NullTime
+		maxClicks sql.NullInt64
+	)
+
+	err := row.Scan(&link.ID, &link.Alias, &link.Url, &link.CreatedAt, &expiresAt, &maxClicks, &link.Clicks, &link.Owner)
+	if err != nil {
+		return storage.Link{}, err
+	}
+
+	link.ExpiresAt = fromNullTime(expiresAt)
+	link.MaxClicks = fromNullInt(maxClicks)
+
+	return link, nil
 }
 
-func (s *Storage) DeleteUrl(alias string) error {
+func (s *Storage) DeleteUrl(workspace, alias string) error {
 	const op = "storage.sqlite.DeleteUrl"
 
-	stmt, err := s.db.Prepare("DELETE FROM url WHERE alias = ?")
+	stmt, err := s.db.Prepare("DELETE FROM url WHERE workspace = ? AND alias = ?")
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	result, err := stmt.Exec(alias)
+	result, err := stmt.Exec(workspace, alias)
 	if err != nil {
 		return fmt.Errorf("%s: execute statement: %w", op, err)
 	}
@@ -115,50 +299,440 @@ func (s *Storage) DeleteUrl(alias string) error {
 	return nil
 }
 
-func (s *Storage) GetAllUrls() (map[string]string, error) {
-	const op = "storage.sqlite.GetAllUrls"
-	stmt, err := s.db.Prepare("SELECT url, alias FROM url")
+func (s *Storage) ListUrls(params storage.ListParams) (storage.Page, error) {
+	const op = "storage.sqlite.ListUrls"
+
+	if params.Limit <= 0 {
+		return storage.Page{}, fmt.Errorf("%s: limit must be positive", op)
+	}
+
+	where := []string{"workspace = ?"}
+	args := []any{params.Workspace}
+
+	if params.AliasPrefix != "" {
+		where = append(where, "substr(alias, 1, ?) = ?")
+		args = append(args, utf8.RuneCountInString(params.AliasPrefix), params.AliasPrefix)
+	}
+
+	if params.Domain != "" {
+		subdomain := "." + params.Domain
+		where = append(where, "(domain = ? OR substr(domain, -?) = ?)")
+		args = append(args, params.Domain, utf8.RuneCountInString(subdomain), subdomain)
+	}
+
+	key, cmp, order := "created_at", ">", "ASC"
+	if params.Sort == storage.SortAlias {
+		key = "alias"
+	}
+	if params.Desc {
+		cmp, order = "<", "DESC"
+	}
+
+	if params.Owner != "" {
+		where = append(where, "owner = ?")
+		args = append(args, params.Owner)
+	}
+
+	if params.Tag != "" {
+		where = append(where, `id IN (
+		SELECT url_tag.url_id FROM url_tag JOIN tag ON tag.id = url_tag.tag_id WHERE tag.name = ?)`)
+		args = append(args, storage.NormalizeTag(params.Tag))
+	}
+
+	if params.After != nil {
+		where = append(where, fmt.Sprintf("(%s, id) %s (?, ?)", key, cmp))
+		if params.Sort == storage.SortAlias {
+			args = append(args, params.After.Alias, params.After.ID)
+		} else {
+			args = append(args, params.After.CreatedAt.UTC(), params.After.ID)
+		}
+	}
+
+	query := "SELECT id, alias, url, created_at, expires_at, max_clicks, clicks, owner FROM url WHERE " +
+		strings.Join(where, " AND ")
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", key, order, order)
+	// one extra row tells whether there is a next page
+	args = append(args, params.Limit+1)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return storage.Page{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	links := make([]storage.Link, 0, params.Limit+1)
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return storage.Page{}, fmt.Errorf("%s: %w", op, err)
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return storage.Page{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	page := storage.Page{Links: links}
+	if len(links) > params.Limit {
+		page.Links = links[:params.Limit]
+		page.Next = storage.CursorOf(page.Links[params.Limit-1])
+	}
+
+	if err := s.loadTags(page.Links); err != nil {
+		return storage.Page{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+func (s *Storage) AddTags(workspace, alias string, tags []string) ([]string, error) {
+	const op = "storage.sqlite.AddTags"
 
-	rows, err := stmt.Query()
+	tx, err := s.db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+
+	err = tx.QueryRow("SELECT id FROM url WHERE workspace = ? AND alias = ?", workspace, alias).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrUrlNotFound
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := addTags(tx, id, tags); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	links := []storage.Link{{ID: id}}
+	if err := s.loadTags(links); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links[0].Tags, nil
+}
+
+func (s *Storage) RemoveTag(workspace, alias, tag string) error {
+	const op = "storage.sqlite.RemoveTag"
+
+	result, err := s.db.Exec(`
+	DELETE FROM url_tag
+	WHERE url_id = (SELECT id FROM url WHERE workspace = ? AND alias = ?)
+	AND tag_id = (SELECT id FROM tag WHERE name = ?)`,
+		workspace, alias, storage.NormalizeTag(tag),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var exists bool
+
+	err = s.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM url WHERE workspace = ? AND alias = ?)", workspace, alias,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !exists {
+		return storage.ErrUrlNotFound
+	}
+
+	return storage.ErrTagNotFound
+}
+
+func (s *Storage) DeleteByTag(workspace, tag string) (int64, error) {
+	const op = "storage.sqlite.DeleteByTag"
+
+	result, err := s.db.Exec(`
+	DELETE FROM url WHERE workspace = ? AND id IN (
+		SELECT url_tag.url_id FROM url_tag JOIN tag ON tag.id = url_tag.tag_id WHERE tag.name = ?)`,
+		workspace, storage.NormalizeTag(tag),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	return rowsAffected, nil
+}
+
+// addTags labels the link with tags, creating the missing ones.
+func addTags(tx *sql.Tx, urlID int64, tags []string) error {
+	for _, tag := range storage.NormalizeTags(tags) {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tag(name) VALUES(?)", tag); err != nil {
+			return fmt.Errorf("create tag: %w", err)
+		}
+
+		_, err := tx.Exec(
+			"INSERT OR IGNORE INTO url_tag(url_id, tag_id) SELECT ?, id FROM tag WHERE name = ?",
+			urlID, tag,
+		)
+		if err != nil {
+			return fmt.Errorf("label url: %w", err)
+		}
+	}
 
-	urlMap := make(map[string]string)
+	return nil
+}
+
+// loadTags fills in the tags of links.
+func (s *Storage) loadTags(links []storage.Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*storage.Link, len(links))
+	args := make([]any, 0, len(links))
+
+	for i := range links {
+		byID[links[i].ID] = &links[i]
+		args = append(args, links[i].ID)
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+	SELECT url_tag.url_id, tag.name FROM url_tag JOIN tag ON tag.id = url_tag.tag_id
+	WHERE url_tag.url_id IN (%s) ORDER BY tag.name`,
+		strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", "),
+	), args...)
+	if err != nil {
+		return fmt.Errorf("load tags: %w", err)
+	}
+	defer rows.Close()
 
 	for rows.Next() {
-		var url, alias string
-		if err := rows.Scan(&url, &alias); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+		var (
+			id  int64
+			tag string
+		)
+		if err := rows.Scan(&id, &tag); err != nil {
+			return fmt.Errorf("load tags: %w", err)
 		}
-		urlMap[alias] = url
+
+		link := byID[id]
+		link.Tags = append(link.Tags, tag)
 	}
 
-	return urlMap, nil
+	return rows.Err()
 }
 
-func (s *Storage) UpdateUrl(url, alias string) (string, error) {
-	const op = "storage.sqlite.updateUrl"
+// exportChunkSize is the number of links ExportUrls reads per query.
+const exportChunkSize = 500
+
+func (s *Storage) ExportUrls(workspace, owner string, fn func(storage.Link) error) error {
+	const op = "storage.sqlite.ExportUrls"
 
-	fmt.Println(url, alias)
+	var lastID int64
+
+	for {
+		links, err := s.exportChunk(workspace, owner, lastID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, link := range links {
+			if err := fn(link); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if len(links) < exportChunkSize {
+			return nil
+		}
+
+		lastID = links[len(links)-1].ID
+	}
+}
+
+// exportChunk reads the links following afterID. The rows are closed
+// before the tags are loaded and before the caller writes anything.
+func (s *Storage) exportChunk(workspace, owner string, afterID int64) ([]storage.Link, error) {
+	rows, err := s.db.Query(`
+	SELECT id, alias, url, created_at, expires_at, max_clicks, clicks, owner FROM url
+	WHERE workspace = ? AND (? = '' OR owner = ?) AND id > ? ORDER BY id LIMIT ?`,
+		workspace, owner, owner, afterID, exportChunkSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]storage.Link, 0, exportChunkSize)
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+
+	if err := s.loadTags(links); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (s *Storage) ImportUrls(workspace string, links []storage.Link, onConflict string, dryRun bool) (storage.ImportReport, error) {
+	const op = "storage.sqlite.ImportUrls"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.ImportReport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var report storage.ImportReport
+
+	for _, link := range links {
+		var id int64
+
+		err := tx.QueryRow("SELECT id FROM url WHERE workspace = ? AND alias = ?", workspace, link.Alias).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := importUrl(tx, workspace, link); err != nil {
+				return storage.ImportReport{}, fmt.Errorf("%s: %w", op, err)
+			}
+			report.Created++
+
+			continue
+		}
+		if err != nil {
+			return storage.ImportReport{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		report.Conflicts = append(report.Conflicts, link.Alias)
+
+		if onConflict != storage.ConflictOverwrite {
+			report.Skipped++
+
+			continue
+		}
+
+		if err := overwriteUrl(tx, id, link); err != nil {
+			return storage.ImportReport{}, fmt.Errorf("%s: %w", op, err)
+		}
+		report.Updated++
+	}
+
+	if onConflict == storage.ConflictFail && len(report.Conflicts) > 0 {
+		return storage.ImportReport{Conflicts: report.Conflicts}, storage.ErrUrlExists
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.ImportReport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return report, nil
+}
+
+func importUrl(tx *sql.Tx, workspace string, link storage.Link) error {
+	createdAt := link.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	res, err := tx.Exec(`
+	INSERT INTO url(workspace, url, norm_url, alias, domain, created_at, expires_at, max_clicks, clicks, owner)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		workspace, link.Url, storage.NormalizeURL(link.Url), link.Alias, storage.Domain(link.Url), createdAt.UTC(),
+		toNullTime(link.ExpiresAt), toNullInt(link.MaxClicks), link.Clicks, link.Owner,
+	)
+	if err != nil {
+		return fmt.Errorf("insert %s: %w", link.Alias, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	return addTags(tx, id, link.Tags)
+}
+
+// overwriteUrl replaces the link with id by link. The id stays,
+// so the click history of the alias is kept.
+func overwriteUrl(tx *sql.Tx, id int64, link storage.Link) error {
+	var createdAt *time.Time
+	if !link.CreatedAt.IsZero() {
+		createdAt = &link.CreatedAt
+	}
+
+	_, err := tx.Exec(`
+	UPDATE url SET url = ?, norm_url = ?, domain = ?, created_at = COALESCE(?, created_at),
+		expires_at = ?, max_clicks = ?, clicks = ?, owner = ?
+	WHERE id = ?`,
+		link.Url, storage.NormalizeURL(link.Url), storage.Domain(link.Url), toNullTime(createdAt),
+		toNullTime(link.ExpiresAt), toNullInt(link.MaxClicks), link.Clicks, link.Owner, id,
+	)
+	if err != nil {
+		return fmt.Errorf("overwrite %s: %w", link.Alias, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM url_tag WHERE url_id = ?", id); err != nil {
+		return fmt.Errorf("overwrite %s: %w", link.Alias, err)
+	}
+
+	return addTags(tx, id, link.Tags)
+}
+
+func (s *Storage) UpdateUrl(workspace, url, alias string) (string, error) {
+	const op = "storage.sqlite.UpdateUrl"
 
 	var exists bool
 	r := s.db.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM url WHERE alias = :alias)",
+		"SELECT EXISTS(SELECT 1 FROM url WHERE workspace = :workspace AND alias = :alias)",
+		sql.Named("workspace", workspace),
 		sql.Named("alias", alias),
 	)
 
 	err := r.Scan(&exists)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	if exists == false {
 		return "", storage.ErrAliasNotFound
 	}
 
-	result, err := s.db.Exec("UPDATE url SET url = :url WHERE alias = :alias",
+	result, err := s.db.Exec("UPDATE url SET url = :url, norm_url = :norm_url, domain = :domain WHERE workspace = :workspace AND alias = :alias",
 		sql.Named("url", url),
+		sql.Named("norm_url", storage.NormalizeURL(url)),
+		sql.Named("domain", storage.Domain(url)),
+		sql.Named("workspace", workspace),
 		sql.Named("alias", alias),
 	)
 
@@ -178,3 +752,275 @@ func (s *Storage) UpdateUrl(url, alias string) (string, error) {
 
 	return alias, nil
 }
+
+func (s *Storage) GetOwner(workspace, alias string) (string, error) {
+	const op = "storage.sqlite.GetOwner"
+
+	var owner string
+
+	err := s.db.QueryRow("SELECT owner FROM url WHERE workspace = ? AND alias = ?", workspace, alias).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return owner, nil
+}
+
+func (s *Storage) SetOwner(workspace, alias, owner string) error {
+	const op = "storage.sqlite.SetOwner"
+
+	result, err := s.db.Exec("UPDATE url SET owner = ? WHERE workspace = ? AND alias = ?", owner, workspace, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrUrlNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) RenameAlias(workspace, alias, newAlias string) error {
+	const op = "storage.sqlite.RenameAlias"
+
+	result, err := s.db.Exec("UPDATE url SET alias = :new_alias, generated = 0 WHERE workspace = :workspace AND alias = :alias",
+		sql.Named("new_alias", newAlias),
+		sql.Named("workspace", workspace),
+		sql.Named("alias", alias),
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return storage.ErrUrlExists
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrAliasNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) UpdateLink(workspace, alias, newAlias, url string) error {
+	const op = "storage.sqlite.UpdateLink"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+
+	err = tx.QueryRow("SELECT id FROM url WHERE workspace = ? AND alias = ?", workspace, alias).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrAliasNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if newAlias != "" && newAlias != alias {
+		_, err := tx.Exec("UPDATE url SET alias = ?, generated = 0 WHERE id = ?", newAlias, id)
+		if err != nil {
+			var sqliteErr sqlite3.Error
+			if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+				return storage.ErrUrlExists
+			}
+
+			return fmt.Errorf("%s: rename: %w", op, err)
+		}
+	}
+
+	if url != "" {
+		_, err := tx.Exec("UPDATE url SET url = ?, norm_url = ?, domain = ? WHERE id = ?",
+			url, storage.NormalizeURL(url), storage.Domain(url), id,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: update url: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) PurgeExpired(now time.Time) (int64, error) {
+	const op = "storage.sqlite.PurgeExpired"
+
+	result, err := s.db.Exec("DELETE FROM url WHERE expires_at <= ?", now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (s *Storage) ArchiveExpired(now time.Time) (int64, error) {
+	const op = "storage.sqlite.ArchiveExpired"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(`
+	INSERT INTO url_archive(workspace, alias, url, expires_at, archived_at)
+	SELECT workspace, alias, url, expires_at, ? FROM url WHERE expires_at <= ?`,
+		now.UTC(), now.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: copy to archive: %w", op, err)
+	}
+
+	result, err := tx.Exec("DELETE FROM url WHERE expires_at <= ?", now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: delete archived: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (s *Storage) SaveClicks(clicks []storage.Click) error {
+	const op = "storage.sqlite.SaveClicks"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO click(url_id, clicked_at, referrer, user_agent, ip)
+	SELECT id, ?, ?, ?, ? FROM url WHERE workspace = ? AND alias = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	for _, click := range clicks {
+		_, err := stmt.Exec(click.At.UTC(), click.Referrer, click.UserAgent, click.IP, click.Workspace, click.Alias)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetStats(workspace, alias string) (storage.Stats, error) {
+	const op = "storage.sqlite.GetStats"
+
+	var id int64
+
+	err := s.db.QueryRow("SELECT id FROM url WHERE workspace = ? AND alias = ?", workspace, alias).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.Stats{}, storage.ErrUrlNotFound
+		}
+
+		return storage.Stats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.Query(`
+	SELECT substr(clicked_at, 1, 10) AS day, COUNT(*) FROM click
+	WHERE url_id = ? GROUP BY day ORDER BY day`, id)
+	if err != nil {
+		return storage.Stats{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	stats := storage.Stats{PerDay: []storage.DayCount{}}
+
+	for rows.Next() {
+		var day storage.DayCount
+		if err := rows.Scan(&day.Date, &day.Count); err != nil {
+			return storage.Stats{}, fmt.Errorf("%s: %w", op, err)
+		}
+		stats.Total += day.Count
+		stats.PerDay = append(stats.PerDay, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return storage.Stats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// toNullTime converts an optional time into a query argument.
+// Times are stored in UTC so that sqlite can compare them as text.
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func fromNullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
+
+func toNullInt(n *int64) sql.NullInt64 {
+	if n == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: *n, Valid: true}
+}
+
+func fromNullInt(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+
+	return &n.Int64
+}
@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/popvaleks/url-shortener/internal/storage"
+)
+
+func (s *Storage) CreateWorkspace(ws storage.Workspace) error {
+	const op = "storage.sqlite.CreateWorkspace"
+
+	_, err := s.db.Exec("INSERT INTO workspace(name, created_at) VALUES(?, ?)", ws.Name, ws.CreatedAt.UTC())
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintPrimaryKey) {
+			return storage.ErrWorkspaceExists
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetWorkspace(name string) (storage.Workspace, error) {
+	const op = "storage.sqlite.GetWorkspace"
+
+	var ws storage.Workspace
+
+	err := s.db.QueryRow("SELECT name, created_at FROM workspace WHERE name = ?", name).Scan(&ws.Name, &ws.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Workspace{}, storage.ErrWorkspaceNotFound
+	}
+	if err != nil {
+		return storage.Workspace{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ws, nil
+}
+
+func (s *Storage) ListWorkspaces() ([]storage.Workspace, error) {
+	const op = "storage.sqlite.ListWorkspaces"
+
+	rows, err := s.db.Query("SELECT name, created_at FROM workspace ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	list := []storage.Workspace{}
+	for rows.Next() {
+		var ws storage.Workspace
+		if err := rows.Scan(&ws.Name, &ws.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		list = append(list, ws)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return list, nil
+}
+
+func (s *Storage) SetMember(member storage.Member) error {
+	const op = "storage.sqlite.SetMember"
+
+	// foreign keys are off, so the workspace is checked by the insert itself
+	result, err := s.db.Exec(`
+	INSERT INTO workspace_member(workspace, name, role)
+	SELECT name, ?, ? FROM workspace WHERE name = ?
+	ON CONFLICT(workspace, name) DO UPDATE SET role = excluded.role`,
+		member.Name, member.Role, member.Workspace,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrWorkspaceNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) GetMember(workspace, name string) (storage.Member, error) {
+	const op = "storage.sqlite.GetMember"
+
+	member := storage.Member{Workspace: workspace, Name: name}
+
+	err := s.db.QueryRow(
+		"SELECT role FROM workspace_member WHERE workspace = ? AND name = ?", workspace, name,
+	).Scan(&member.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Member{}, storage.ErrMemberNotFound
+	}
+	if err != nil {
+		return storage.Member{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return member, nil
+}
+
+func (s *Storage) ListMembers(workspace string) ([]storage.Member, error) {
+	const op = "storage.sqlite.ListMembers"
+
+	rows, err := s.db.Query(
+		"SELECT workspace, name, role FROM workspace_member WHERE workspace = ? ORDER BY name", workspace,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	list := []storage.Member{}
+	for rows.Next() {
+		var member storage.Member
+		if err := rows.Scan(&member.Workspace, &member.Name, &member.Role); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		list = append(list, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return list, nil
+}
+
+func (s *Storage) RemoveMember(workspace, name string) error {
+	const op = "storage.sqlite.RemoveMember"
+
+	result, err := s.db.Exec("DELETE FROM workspace_member WHERE workspace = ? AND name = ?", workspace, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrMemberNotFound
+	}
+
+	return nil
+}
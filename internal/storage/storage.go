@@ -2,10 +2,409 @@ package storage
 
 import (
 	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
 )
 
 var (
 	ErrUrlNotFound   = errors.New("url not found")
 	ErrUrlExists     = errors.New("url already exists")
 	ErrAliasNotFound = errors.New("alias not found")
+	ErrUrlExpired    = errors.New("url expired")
+	ErrClickLimit    = errors.New("click limit reached")
+	ErrTagNotFound   = errors.New("tag not found")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyExists   = errors.New("api key already exists")
+
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrWorkspaceExists   = errors.New("workspace already exists")
+	ErrMemberNotFound    = errors.New("member not found")
 )
+
+// DefaultWorkspace holds the links created before workspaces and the
+// ones created without a workspace in the path or the host. It always exists.
+const DefaultWorkspace = "default"
+
+// SaveOptions holds optional attributes of a new link.
+type SaveOptions struct {
+	// ExpiresAt is the moment the link stops resolving, nil for a permanent link.
+	ExpiresAt *time.Time
+	// MaxClicks is the number of redirects after which the link stops
+	// resolving, nil for an unlimited link.
+	MaxClicks *int64
+	// Tags label the link, see NormalizeTags.
+	Tags []string
+	// Generated marks an alias made up by the service rather than chosen
+	// by the user. Only such links are returned by FindAlias.
+	Generated bool
+	// Owner is the name of the principal that created the link,
+	// empty for a link without an owner.
+	Owner string
+}
+
+// NewLink is an item of a batch for SaveUrls.
+type NewLink struct {
+	Url   string
+	Alias string
+	Opts  SaveOptions
+}
+
+// SaveResult is the outcome of saving one NewLink.
+type SaveResult struct {
+	// ID is the new row id, zero if the link was not saved.
+	ID int64
+	// Err is ErrUrlExists if the alias is taken, also by an earlier item of the batch.
+	Err error
+}
+
+// Conflict policies of ImportUrls for aliases that already exist.
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictFail      = "fail"
+)
+
+// ImportReport describes the changes made by ImportUrls,
+// or the ones it would make in a dry run.
+type ImportReport struct {
+	Created int
+	Updated int
+	Skipped int
+	// Conflicts lists the aliases that already existed, in input order.
+	Conflicts []string
+}
+
+// Link is a stored short link.
+type Link struct {
+	ID        int64
+	Alias     string
+	Url       string
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+	MaxClicks *int64
+	// Clicks counts redirects of a click-limited link.
+	Clicks int64
+	// Tags are sorted by name. Only listings fill them in.
+	Tags []string
+	// Owner is empty for a link without an owner.
+	Owner string
+}
+
+// Expired reports whether the link has expired at now.
+func (l Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(now)
+}
+
+// Exhausted reports whether the link has used up its clicks.
+func (l Link) Exhausted() bool {
+	return l.MaxClicks != nil && l.Clicks >= *l.MaxClicks
+}
+
+// CheckUsable returns ErrUrlExpired or ErrClickLimit if the link
+// must not be resolved at now.
+func (l Link) CheckUsable(now time.Time) error {
+	if l.Expired(now) {
+		return ErrUrlExpired
+	}
+
+	if l.Exhausted() {
+		return ErrClickLimit
+	}
+
+	return nil
+}
+
+const (
+	// SortCreated orders links by creation time.
+	SortCreated = "created"
+	// SortAlias orders links by alias.
+	SortAlias = "alias"
+)
+
+// ListParams selects a page of links.
+type ListParams struct {
+	// Sort is SortCreated or SortAlias, links with equal keys are ordered by id.
+	Sort string
+	Desc bool
+	// Limit is the maximum number of links on the page.
+	Limit int
+	// After continues the listing after the last link of the previous page.
+	After *Cursor
+	// AliasPrefix keeps links whose alias starts with it.
+	AliasPrefix string
+	// Domain keeps links to the domain and its subdomains.
+	Domain string
+	// Tag keeps links labelled with the tag.
+	Tag string
+	// Owner keeps links of the owner.
+	Owner string
+	// Workspace is the workspace listed, it is required.
+	Workspace string
+}
+
+// Cursor is the position of a link in a listing.
+type Cursor struct {
+	CreatedAt time.Time
+	Alias     string
+	ID        int64
+}
+
+// Page is a part of a listing.
+type Page struct {
+	Links []Link
+	// Next points at the last link of the page, nil if there are no more links.
+	Next *Cursor
+}
+
+// CursorOf returns the position of link.
+func CursorOf(link Link) *Cursor {
+	return &Cursor{CreatedAt: link.CreatedAt, Alias: link.Alias, ID: link.ID}
+}
+
+// Domain returns the lower-cased host of rawURL without the port
+// or an empty string if rawURL is not a valid url.
+func Domain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+// NormalizeURL lower-cases the scheme and the host of rawURL, drops the
+// default port and makes an empty path "/", so that urls pointing to the
+// same target compare equal. Anything else, the query included, is kept
+// as is. A url without a host is returned unchanged.
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443" {
+		port = ""
+	}
+
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+
+	return u.String()
+}
+
+// NormalizeTag trims and lower-cases a tag name.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// NormalizeTags normalizes tag names and drops empty and repeated ones.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+// SearchTerms splits a search query into lower-cased words.
+// Backends match every term as a word prefix or a substring
+// of the alias or the target url.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Click is a single successful redirect of a link.
+type Click struct {
+	Workspace string
+	Alias     string
+	At        time.Time
+	Referrer  string
+	UserAgent string
+	IP        string
+}
+
+// DayCount is the number of clicks during one UTC day.
+type DayCount struct {
+	// Date is formatted as 2006-01-02.
+	Date  string
+	Count int64
+}
+
+// Stats aggregates clicks of a link.
+type Stats struct {
+	Total int64
+	// PerDay is ordered by date and skips days without clicks.
+	PerDay []DayCount
+}
+
+// APIKey is a key of the management API. The key itself is not stored,
+// only its hash.
+type APIKey struct {
+	ID   int64
+	Name string
+	// Prefix is the public start of the key, the key is looked up by it.
+	Prefix    string
+	Hash      string
+	Scopes    []string
+	CreatedAt time.Time
+	// RevokedAt is nil for a live key.
+	RevokedAt *time.Time
+}
+
+// Workspace is a namespace of aliases: the same alias may point to
+// different urls in different workspaces.
+type Workspace struct {
+	Name      string
+	CreatedAt time.Time
+}
+
+// Member gives the principal Name a role in Workspace.
+type Member struct {
+	Workspace string
+	Name      string
+	Role      string
+}
+
+// Repository is the contract every storage backend has to satisfy.
+// Handlers depend on narrower interfaces; storagetest.Run checks
+// that a backend implements all of them with the same semantics.
+type Repository interface {
+	// Methods taking a workspace work on the links of that workspace
+	// only, an alias of another workspace is reported as not found.
+	//
+	// SaveUrl stores inputUrl under alias and returns the new row id.
+	// It returns ErrUrlExists if the alias is already taken.
+	SaveUrl(workspace, inputUrl, alias string, opts SaveOptions) (int64, error)
+	// SaveUrls stores links in one transaction and returns a result per link.
+	// With atomic set nothing is saved unless every link can be, otherwise
+	// links with taken aliases are skipped. Other errors abort the batch.
+	SaveUrls(workspace string, links []NewLink, atomic bool) ([]SaveResult, error)
+	// FindAlias returns the alias of the oldest link of owner to target
+	// with a generated alias, no expiration and no click limit, or
+	// ErrUrlNotFound. Urls are compared in the form of NormalizeURL,
+	// while links keep the url as it was saved.
+	FindAlias(workspace, target, owner string) (string, error)
+	// GetUrl returns the url stored under alias, ErrUrlNotFound,
+	// ErrUrlExpired for a link that expired but is not purged yet
+	// or ErrClickLimit for a link that used up its clicks.
+	GetUrl(workspace, alias string) (string, error)
+	// HitUrl is GetUrl for a redirect: for a click-limited link it
+	// atomically checks the limit and counts the click, so that
+	// concurrent callers never resolve the link more than MaxClicks times.
+	HitUrl(workspace, alias string) (string, error)
+	// DeleteUrl removes alias or returns ErrUrlNotFound.
+	DeleteUrl(workspace, alias string) error
+	// ListUrls returns a page of links, expired ones included.
+	ListUrls(params ListParams) (Page, error)
+	// SearchUrls returns links whose alias or target url contain every
+	// term of query, best matches first. Alias matches rank higher.
+	// A non-empty owner keeps links of the owner.
+	SearchUrls(workspace, owner, query string, limit, offset int) ([]Link, error)
+	// ExportUrls calls fn for every link in id order, tags included.
+	// A non-empty owner keeps links of the owner.
+	// Links are read in chunks, so the export holds no lock between them.
+	// An error returned by fn stops the export.
+	ExportUrls(workspace, owner string, fn func(Link) error) error
+	// ImportUrls stores links in one transaction. Links keep their
+	// CreatedAt unless it is zero, their Clicks, Tags and Owner. Aliases
+	// that already exist, also earlier in links, are skipped or
+	// overwritten according to onConflict. With ConflictFail any conflict
+	// makes it return ErrUrlExists and a report of the conflicts without
+	// saving anything. With dryRun nothing is saved either, the report
+	// shows what would change.
+	ImportUrls(workspace string, links []Link, onConflict string, dryRun bool) (ImportReport, error)
+	// AddTags labels alias with tags and returns all its tags.
+	// Tags are normalized, the ones the link already has are ignored.
+	// It returns ErrUrlNotFound if the alias does not exist.
+	AddTags(workspace, alias string, tags []string) ([]string, error)
+	// RemoveTag removes tag from alias. It returns ErrUrlNotFound
+	// or ErrTagNotFound if the link does not have the tag.
+	RemoveTag(workspace, alias, tag string) error
+	// DeleteByTag deletes every link of the workspace labelled with tag
+	// and returns their number.
+	DeleteByTag(workspace, tag string) (int64, error)
+	// UpdateUrl points alias to url and returns the alias.
+	// It returns ErrAliasNotFound if the alias does not exist.
+	UpdateUrl(workspace, url, alias string) (string, error)
+	// GetOwner returns the owner of alias, empty for a link without
+	// an owner, or ErrUrlNotFound.
+	GetOwner(workspace, alias string) (string, error)
+	// SetOwner makes owner the owner of alias or returns ErrUrlNotFound.
+	SetOwner(workspace, alias, owner string) error
+	// RenameAlias gives the link of alias the alias newAlias, keeping
+	// its tags and clicks. A renamed link is no longer reused by
+	// FindAlias. It returns ErrAliasNotFound or ErrUrlExists if newAlias
+	// is taken.
+	RenameAlias(workspace, alias, newAlias string) error
+	// UpdateLink renames alias to newAlias and points it to url in one
+	// transaction, an empty newAlias or url keeps the current one. It
+	// returns ErrAliasNotFound or ErrUrlExists if newAlias is taken, and
+	// then changes nothing.
+	UpdateLink(workspace, alias, newAlias, url string) error
+	// PurgeExpired deletes links expired at now in all workspaces and
+	// returns their number.
+	PurgeExpired(now time.Time) (int64, error)
+	// ArchiveExpired moves links expired at now to the archive
+	// and returns their number. Archived aliases can be reused.
+	ArchiveExpired(now time.Time) (int64, error)
+	// SaveClicks stores click events. Clicks of aliases that no longer
+	// exist are dropped. Clicks are removed together with their link.
+	SaveClicks(clicks []Click) error
+	// GetStats aggregates the clicks of alias or returns ErrUrlNotFound.
+	GetStats(workspace, alias string) (Stats, error)
+	// SaveAPIKey stores key and returns its id.
+	// It returns ErrAPIKeyExists if the prefix is taken.
+	SaveAPIKey(key APIKey) (int64, error)
+	// GetAPIKey returns the key with prefix, revoked or not,
+	// or ErrAPIKeyNotFound.
+	GetAPIKey(prefix string) (APIKey, error)
+	// ListAPIKeys returns all keys in id order, revoked ones included.
+	ListAPIKeys() ([]APIKey, error)
+	// RevokeAPIKey marks the key revoked at now or returns ErrAPIKeyNotFound.
+	// A key revoked before keeps its RevokedAt.
+	RevokeAPIKey(id int64, now time.Time) error
+	// CreateWorkspace stores ws or returns ErrWorkspaceExists.
+	CreateWorkspace(ws Workspace) error
+	// GetWorkspace returns the workspace called name or ErrWorkspaceNotFound.
+	GetWorkspace(name string) (Workspace, error)
+	// ListWorkspaces returns all workspaces ordered by name.
+	ListWorkspaces() ([]Workspace, error)
+	// SetMember adds the member or changes its role. It returns
+	// ErrWorkspaceNotFound if the workspace does not exist.
+	SetMember(member Member) error
+	// GetMember returns the member name of workspace or ErrMemberNotFound.
+	GetMember(workspace, name string) (Member, error)
+	// ListMembers returns the members of workspace ordered by name.
+	ListMembers(workspace string) ([]Member, error)
+	// RemoveMember removes the member name of workspace or returns ErrMemberNotFound.
+	RemoveMember(workspace, name string) error
+	Close() error
+}
@@ -0,0 +1,1301 @@
+// Package storagetest is a conformance suite for storage.Repository
+// implementations. Every backend runs it from its own tests so that
+// handlers observe identical semantics regardless of the storage in use.
+package storagetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/popvaleks/url-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// defaultWS is the workspace the tests work in unless they test workspaces.
+const defaultWS = storage.DefaultWorkspace
+
+// Factory returns a new empty repository. It is called once per subtest;
+// closing the repository is handled by the suite.
+type Factory func(t *testing.T) storage.Repository
+
+// Run executes the whole suite against repositories created by newRepo.
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo storage.Repository)
+	}{
+		{"save and get", testSaveAndGet},
+		{"duplicate alias", testDuplicateAlias},
+		{"missing alias", testMissingAlias},
+		{"find alias", testFindAlias},
+		{"owner", testOwner},
+		{"batch atomic", testBatchAtomic},
+		{"batch best effort", testBatchBestEffort},
+		{"update", testUpdate},
+		{"rename", testRename},
+		{"update link", testUpdateLink},
+		{"delete", testDelete},
+		{"get all", testGetAll},
+		{"list pages", testListPages},
+		{"list sort", testListSort},
+		{"list filters", testListFilters},
+		{"search", testSearch},
+		{"search stays in sync", testSearchSync},
+		{"export", testExport},
+		{"import", testImport},
+		{"import overwrite", testImportOverwrite},
+		{"import fail and dry run", testImportFailAndDryRun},
+		{"tags", testTags},
+		{"list by tag", testListByTag},
+		{"delete by tag", testDeleteByTag},
+		{"expiration", testExpiration},
+		{"purge expired", testPurgeExpired},
+		{"archive expired", testArchiveExpired},
+		{"hit", testHit},
+		{"click limit", testClickLimit},
+		{"concurrent click limit", testConcurrentClickLimit},
+		{"stats", testStats},
+		{"stats of deleted link", testStatsOfDeletedLink},
+		{"api keys", testAPIKeys},
+		{"workspaces", testWorkspaces},
+		{"workspace isolation", testWorkspaceIsolation},
+		{"workspace members", testMembers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			t.Cleanup(func() { _ = repo.Close() })
+
+			tt.test(t, repo)
+		})
+	}
+}
+
+func testSaveAndGet(t *testing.T, repo storage.Repository) {
+	id1, err := repo.SaveUrl(defaultWS, "https://example.com", "example", storage.SaveOptions{})
+	require.NoError(t, err)
+	assert.Positive(t, id1)
+
+	id2, err := repo.SaveUrl(defaultWS, "https://example.com", "example2", storage.SaveOptions{})
+	require.NoError(t, err, "the same url may be saved under several aliases")
+	assert.NotEqual(t, id1, id2)
+
+	url, err := repo.GetUrl(defaultWS, "example")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+}
+
+func testDuplicateAlias(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "example", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	_, err = repo.SaveUrl(defaultWS, "https://google.com", "example", storage.SaveOptions{})
+	assert.ErrorIs(t, err, storage.ErrUrlExists)
+
+	url, err := repo.GetUrl(defaultWS, "example")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url, "a rejected duplicate must not overwrite the original")
+}
+
+func testBatchAtomic(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "taken", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	results, err := repo.SaveUrls(defaultWS, []storage.NewLink{
+		{Url: "https://a.example.com", Alias: "a", Opts: storage.SaveOptions{Tags: []string{"batch"}}},
+		{Url: "https://b.example.com", Alias: "taken"},
+		{Url: "https://c.example.com", Alias: "c"},
+	}, true)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, storage.ErrUrlExists)
+	assert.NoError(t, results[2].Err)
+	for _, res := range results {
+		assert.Zero(t, res.ID, "nothing is saved when one link of an atomic batch fails")
+	}
+
+	assert.Equal(t, []string{"taken"}, aliasesOf(listAll(t, repo)))
+
+	results, err = repo.SaveUrls(defaultWS, []storage.NewLink{
+		{Url: "https://a.example.com", Alias: "a", Opts: storage.SaveOptions{Tags: []string{"batch"}}},
+		{Url: "https://c.example.com", Alias: "c"},
+	}, true)
+	require.NoError(t, err)
+
+	for _, res := range results {
+		assert.NoError(t, res.Err)
+		assert.NotZero(t, res.ID)
+	}
+	assert.NotEqual(t, results[0].ID, results[1].ID)
+
+	all := listAll(t, repo)
+	assert.Equal(t, []string{"taken", "a", "c"}, aliasesOf(all))
+	assert.Equal(t, []string{"batch"}, all[1].Tags)
+}
+
+func testBatchBestEffort(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "taken", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	results, err := repo.SaveUrls(defaultWS, []storage.NewLink{
+		{Url: "https://a.example.com", Alias: "a", Opts: storage.SaveOptions{Tags: []string{"batch"}}},
+		{Url: "https://b.example.com", Alias: "taken", Opts: storage.SaveOptions{Tags: []string{"lost"}}},
+		{Url: "https://c.example.com", Alias: "c"},
+		// повтор алиаса внутри одного пакета
+		{Url: "https://d.example.com", Alias: "a"},
+	}, false)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	assert.NoError(t, results[0].Err)
+	assert.NotZero(t, results[0].ID)
+	assert.ErrorIs(t, results[1].Err, storage.ErrUrlExists)
+	assert.Zero(t, results[1].ID)
+	assert.NoError(t, results[2].Err)
+	assert.NotZero(t, results[2].ID)
+	assert.ErrorIs(t, results[3].Err, storage.ErrUrlExists)
+	assert.Zero(t, results[3].ID)
+
+	url, err := repo.GetUrl(defaultWS, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.example.com", url, "a duplicate in the batch must not overwrite the first link")
+
+	url, err = repo.GetUrl(defaultWS, "taken")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+
+	all := listAll(t, repo)
+	assert.Equal(t, []string{"taken", "a", "c"}, aliasesOf(all))
+	assert.Empty(t, all[0].Tags, "tags of a skipped link are not saved")
+	assert.Equal(t, []string{"batch"}, all[1].Tags)
+}
+
+func testFindAlias(t *testing.T, repo storage.Repository) {
+	const target = "https://example.com/"
+
+	expiresAt := time.Now().Add(time.Hour)
+	maxClicks := int64(5)
+
+	for _, link := range []struct {
+		alias string
+		url   string
+		opts  storage.SaveOptions
+	}{
+		{"chosen", target, storage.SaveOptions{}},
+		{"temporary", target, storage.SaveOptions{Generated: true, ExpiresAt: &expiresAt}},
+		{"limited", target, storage.SaveOptions{Generated: true, MaxClicks: &maxClicks}},
+		{"other", "https://example.org/", storage.SaveOptions{Generated: true}},
+		{"first", "HTTPS://Example.com:443", storage.SaveOptions{Generated: true}},
+		{"second", target, storage.SaveOptions{Generated: true}},
+	} {
+		_, err := repo.SaveUrl(defaultWS, link.url, link.alias, link.opts)
+		require.NoError(t, err)
+	}
+
+	alias, err := repo.FindAlias(defaultWS, target, "")
+	require.NoError(t, err)
+	assert.Equal(t, "first", alias, "the oldest permanent generated alias is reused")
+
+	url, err := repo.GetUrl(defaultWS, "first")
+	require.NoError(t, err)
+	assert.Equal(t, "HTTPS://Example.com:443", url, "the url is kept as it was saved")
+
+	require.NoError(t, repo.DeleteUrl(defaultWS, "first"))
+
+	alias, err = repo.FindAlias(defaultWS, target, "")
+	require.NoError(t, err)
+	assert.Equal(t, "second", alias)
+
+	alias, err = repo.FindAlias(defaultWS, "https://EXAMPLE.com", "")
+	require.NoError(t, err)
+	assert.Equal(t, "second", alias, "targets are compared normalized")
+
+	_, err = repo.FindAlias(defaultWS, "https://example.com/?", "")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound, "the query is not normalized")
+
+	_, err = repo.FindAlias(defaultWS, "https://missing.example.com/", "")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func testOwner(t *testing.T, repo storage.Repository) {
+	const target = "https://example.com/"
+
+	for _, link := range []struct {
+		alias string
+		owner string
+	}{
+		{"unowned", ""},
+		{"ops1", "ops"},
+		{"dev1", "dev"},
+		{"ops2", "ops"},
+	} {
+		_, err := repo.SaveUrl(defaultWS, target, link.alias, storage.SaveOptions{Generated: true, Owner: link.owner})
+		require.NoError(t, err)
+	}
+
+	owner, err := repo.GetOwner(defaultWS, "ops1")
+	require.NoError(t, err)
+	assert.Equal(t, "ops", owner)
+
+	owner, err = repo.GetOwner(defaultWS, "unowned")
+	require.NoError(t, err)
+	assert.Empty(t, owner)
+
+	alias, err := repo.FindAlias(defaultWS, target, "dev")
+	require.NoError(t, err)
+	assert.Equal(t, "dev1", alias, "links of other owners are not reused")
+
+	_, err = repo.FindAlias(defaultWS, target, "qa")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	page, err := repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortAlias, Limit: 10, Owner: "ops"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ops1", "ops2"}, aliasesOf(page.Links))
+	assert.Equal(t, "ops", page.Links[0].Owner)
+
+	found, err := repo.SearchUrls(defaultWS, "ops", "ops", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ops1", "ops2"}, aliasesOf(found))
+
+	found, err = repo.SearchUrls(defaultWS, "dev", "ops", 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, found, "links of other owners are not found")
+
+	var exported []storage.Link
+	require.NoError(t, repo.ExportUrls(defaultWS, "ops", func(link storage.Link) error {
+		exported = append(exported, link)
+		return nil
+	}))
+	assert.Equal(t, []string{"ops1", "ops2"}, aliasesOf(exported))
+
+	require.NoError(t, repo.SetOwner(defaultWS, "ops1", "dev"))
+	require.NoError(t, repo.RenameAlias(defaultWS, "ops1", "moved"))
+
+	owner, err = repo.GetOwner(defaultWS, "moved")
+	require.NoError(t, err)
+	assert.Equal(t, "dev", owner, "the owner follows a renamed link")
+
+	page, err = repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortAlias, Limit: 10, Owner: "dev"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev1", "moved"}, aliasesOf(page.Links))
+
+	_, err = repo.GetOwner(defaultWS, "missing")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+	assert.ErrorIs(t, repo.SetOwner(defaultWS, "missing", "ops"), storage.ErrUrlNotFound)
+}
+
+func testMissingAlias(t *testing.T, repo storage.Repository) {
+	_, err := repo.GetUrl(defaultWS, "missing")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	err = repo.DeleteUrl(defaultWS, "missing")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = repo.UpdateUrl(defaultWS, "https://example.com", "missing")
+	assert.ErrorIs(t, err, storage.ErrAliasNotFound)
+
+	err = repo.RenameAlias(defaultWS, "missing", "other")
+	assert.ErrorIs(t, err, storage.ErrAliasNotFound)
+}
+
+func testUpdate(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "example", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	alias, err := repo.UpdateUrl(defaultWS, "https://example.org", "example")
+	require.NoError(t, err)
+	assert.Equal(t, "example", alias)
+
+	url, err := repo.GetUrl(defaultWS, "example")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", url)
+
+	alias, err = repo.UpdateUrl(defaultWS, "https://example.org", "example")
+	require.NoError(t, err, "updating to the same url is not an error")
+	assert.Equal(t, "example", alias)
+}
+
+func testRename(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com/", "abc123", storage.SaveOptions{Tags: []string{"promo"}, Generated: true})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl(defaultWS, "https://example.org", "taken", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.RenameAlias(defaultWS, "abc123", "example"))
+
+	_, err = repo.GetUrl(defaultWS, "abc123")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	url, err := repo.GetUrl(defaultWS, "example")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/", url)
+
+	page, err := repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortAlias, Limit: 10, Tag: "promo"})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, "example", page.Links[0].Alias, "tags follow the link")
+
+	_, err = repo.FindAlias(defaultWS, "https://example.com/", "")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound, "a renamed link is not generated any more")
+
+	err = repo.RenameAlias(defaultWS, "example", "taken")
+	assert.ErrorIs(t, err, storage.ErrUrlExists)
+
+	require.NoError(t, repo.RenameAlias(defaultWS, "example", "example"), "renaming to the same alias is not an error")
+}
+
+func testUpdateLink(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "abc123", storage.SaveOptions{Tags: []string{"promo"}, Generated: true})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl(defaultWS, "https://example.org", "taken", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	err = repo.UpdateLink(defaultWS, "abc123", "taken", "https://example.net")
+	assert.ErrorIs(t, err, storage.ErrUrlExists)
+
+	url, err := repo.GetUrl(defaultWS, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url, "a failed rename keeps the url")
+
+	require.NoError(t, repo.UpdateLink(defaultWS, "abc123", "example", "https://example.net/shop"))
+
+	_, err = repo.GetUrl(defaultWS, "abc123")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	url, err = repo.GetUrl(defaultWS, "example")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.net/shop", url)
+
+	page, err := repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortAlias, Limit: 10, Domain: "example.net", Tag: "promo"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example"}, aliasesOf(page.Links), "the domain is updated, tags follow the link")
+
+	_, err = repo.FindAlias(defaultWS, "https://example.net/shop", "")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound, "a renamed link is not generated any more")
+
+	require.NoError(t, repo.UpdateLink(defaultWS, "example", "", "https://example.net/sale"), "an empty alias keeps it")
+	require.NoError(t, repo.UpdateLink(defaultWS, "example", "example", ""), "an empty url keeps it")
+
+	url, err = repo.GetUrl(defaultWS, "example")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.net/sale", url)
+
+	err = repo.UpdateLink(defaultWS, "missing", "other", "https://example.com")
+	assert.ErrorIs(t, err, storage.ErrAliasNotFound)
+}
+
+func testDelete(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "example", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteUrl(defaultWS, "example"))
+
+	_, err = repo.GetUrl(defaultWS, "example")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	err = repo.DeleteUrl(defaultWS, "example")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = repo.SaveUrl(defaultWS, "https://example.org", "example", storage.SaveOptions{})
+	assert.NoError(t, err, "a deleted alias can be reused")
+}
+
+func testGetAll(t *testing.T, repo storage.Repository) {
+	all := listAll(t, repo)
+	assert.Empty(t, all)
+
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "abc", storage.SaveOptions{})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl(defaultWS, "https://google.com", "def", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	all = listAll(t, repo)
+	assert.Equal(t, map[string]string{
+		"abc": "https://example.com",
+		"def": "https://google.com",
+	}, urlMap(all))
+}
+
+func testExpiration(t *testing.T, repo storage.Repository) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	_, err := repo.SaveUrl(defaultWS, "https://example.com/old", "old", storage.SaveOptions{ExpiresAt: &past})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl(defaultWS, "https://example.com/new", "new", storage.SaveOptions{ExpiresAt: &future})
+	require.NoError(t, err)
+
+	_, err = repo.GetUrl(defaultWS, "old")
+	assert.ErrorIs(t, err, storage.ErrUrlExpired)
+
+	url, err := repo.GetUrl(defaultWS, "new")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", url)
+
+	all := listAll(t, repo)
+	require.Len(t, all, 2, "expired links are listed until purged")
+
+	for _, link := range all {
+		require.NotNil(t, link.ExpiresAt, link.Alias)
+
+		expected := future
+		if link.Alias == "old" {
+			expected = past
+		}
+		assert.WithinDuration(t, expected, *link.ExpiresAt, time.Millisecond, link.Alias)
+	}
+}
+
+func testPurgeExpired(t *testing.T, repo storage.Repository) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	_, err := repo.SaveUrl(defaultWS, "https://example.com/old", "old", storage.SaveOptions{ExpiresAt: &past})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl(defaultWS, "https://example.com/new", "new", storage.SaveOptions{ExpiresAt: &future})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl(defaultWS, "https://example.com", "permanent", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	purged, err := repo.PurgeExpired(now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, purged)
+
+	_, err = repo.GetUrl(defaultWS, "old")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	all := listAll(t, repo)
+	assert.Len(t, all, 2)
+
+	purged, err = repo.PurgeExpired(now)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+}
+
+func testArchiveExpired(t *testing.T, repo storage.Repository) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+
+	_, err := repo.SaveUrl(defaultWS, "https://example.com/old", "old", storage.SaveOptions{ExpiresAt: &past})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl(defaultWS, "https://example.com", "permanent", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	archived, err := repo.ArchiveExpired(now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, archived)
+
+	_, err = repo.GetUrl(defaultWS, "old")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = repo.SaveUrl(defaultWS, "https://example.org", "old", storage.SaveOptions{})
+	assert.NoError(t, err, "an archived alias can be reused")
+
+	url, err := repo.GetUrl(defaultWS, "permanent")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+}
+
+func testHit(t *testing.T, repo storage.Repository) {
+	past := time.Now().Add(-time.Minute)
+
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "example", storage.SaveOptions{})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl(defaultWS, "https://example.com/old", "old", storage.SaveOptions{ExpiresAt: &past})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		url, err := repo.HitUrl(defaultWS, "example")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", url)
+	}
+
+	_, err = repo.HitUrl(defaultWS, "old")
+	assert.ErrorIs(t, err, storage.ErrUrlExpired)
+
+	_, err = repo.HitUrl(defaultWS, "missing")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func testClickLimit(t *testing.T, repo storage.Repository) {
+	maxClicks := int64(2)
+
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "invite", storage.SaveOptions{MaxClicks: &maxClicks})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		url, err := repo.HitUrl(defaultWS, "invite")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", url)
+	}
+
+	_, err = repo.HitUrl(defaultWS, "invite")
+	assert.ErrorIs(t, err, storage.ErrClickLimit)
+
+	_, err = repo.GetUrl(defaultWS, "invite")
+	assert.ErrorIs(t, err, storage.ErrClickLimit)
+
+	all := listAll(t, repo)
+	require.Len(t, all, 1)
+	require.NotNil(t, all[0].MaxClicks)
+	assert.EqualValues(t, 2, *all[0].MaxClicks)
+	assert.EqualValues(t, 2, all[0].Clicks)
+}
+
+func testConcurrentClickLimit(t *testing.T, repo storage.Repository) {
+	const (
+		maxClicks = 10
+		workers   = 50
+	)
+
+	limit := int64(maxClicks)
+
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "invite", storage.SaveOptions{MaxClicks: &limit})
+	require.NoError(t, err)
+
+	var (
+		wg       sync.WaitGroup
+		resolved atomic.Int64
+		limited  atomic.Int64
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := repo.HitUrl(defaultWS, "invite")
+			switch {
+			case err == nil:
+				resolved.Add(1)
+			case errors.Is(err, storage.ErrClickLimit):
+				limited.Add(1)
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.EqualValues(t, maxClicks, resolved.Load())
+	assert.EqualValues(t, workers-maxClicks, limited.Load())
+}
+
+func testStats(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "example", storage.SaveOptions{})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl(defaultWS, "https://example.org", "other", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	stats, err := repo.GetStats(defaultWS, "example")
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total)
+	assert.Empty(t, stats.PerDay)
+
+	day1 := time.Date(2025, 3, 1, 23, 59, 0, 0, time.UTC)
+	day2 := time.Date(2025, 3, 2, 0, 1, 0, 0, time.UTC)
+
+	err = repo.SaveClicks([]storage.Click{
+		{Workspace: defaultWS, Alias: "example", At: day1, Referrer: "https://news.example", UserAgent: "curl/8.0", IP: "10.0.0.1"},
+		{Workspace: defaultWS, Alias: "example", At: day2, IP: "10.0.0.2"},
+		{Workspace: defaultWS, Alias: "example", At: day2.Add(time.Hour), IP: "10.0.0.2"},
+		{Workspace: defaultWS, Alias: "other", At: day1},
+		{Workspace: defaultWS, Alias: "missing", At: day1},
+	})
+	require.NoError(t, err)
+
+	stats, err = repo.GetStats(defaultWS, "example")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, stats.Total)
+	assert.Equal(t, []storage.DayCount{
+		{Date: "2025-03-01", Count: 1},
+		{Date: "2025-03-02", Count: 2},
+	}, stats.PerDay)
+
+	stats, err = repo.GetStats(defaultWS, "other")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats.Total)
+
+	_, err = repo.GetStats(defaultWS, "missing")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func testStatsOfDeletedLink(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "example", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	err = repo.SaveClicks([]storage.Click{{Workspace: defaultWS, Alias: "example", At: time.Now()}})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteUrl(defaultWS, "example"))
+
+	_, err = repo.GetStats(defaultWS, "example")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = repo.SaveUrl(defaultWS, "https://example.org", "example", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	stats, err := repo.GetStats(defaultWS, "example")
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total, "a reused alias starts without clicks")
+}
+
+func testListPages(t *testing.T, repo storage.Repository) {
+	aliases := []string{"e", "d", "c", "b", "a"}
+	for _, alias := range aliases {
+		_, err := repo.SaveUrl(defaultWS, "https://example.com/"+alias, alias, storage.SaveOptions{})
+		require.NoError(t, err)
+	}
+
+	for _, desc := range []bool{false, true} {
+		params := storage.ListParams{Workspace: defaultWS, Sort: storage.SortCreated, Desc: desc, Limit: 2}
+
+		var listed []string
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 3, "the listing must end after three pages")
+
+			page, err := repo.ListUrls(params)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Links), 2)
+
+			for _, link := range page.Links {
+				assert.False(t, link.CreatedAt.IsZero(), link.Alias)
+				listed = append(listed, link.Alias)
+			}
+
+			if page.Next == nil {
+				break
+			}
+			params.After = page.Next
+		}
+
+		expected := aliases
+		if desc {
+			expected = []string{"a", "b", "c", "d", "e"}
+		}
+		assert.Equal(t, expected, listed, "desc=%v", desc)
+	}
+
+	page, err := repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortCreated, Limit: 5})
+	require.NoError(t, err)
+	assert.Len(t, page.Links, 5)
+	assert.Nil(t, page.Next, "an exactly full page is the last one")
+}
+
+func testListSort(t *testing.T, repo storage.Repository) {
+	for _, alias := range []string{"b", "c", "a"} {
+		_, err := repo.SaveUrl(defaultWS, "https://example.com/"+alias, alias, storage.SaveOptions{})
+		require.NoError(t, err)
+	}
+
+	page, err := repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortAlias, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, aliasesOf(page.Links))
+	require.NotNil(t, page.Next)
+
+	page, err = repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortAlias, Limit: 2, After: page.Next})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, aliasesOf(page.Links))
+	assert.Nil(t, page.Next)
+
+	page, err = repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortAlias, Desc: true, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b", "a"}, aliasesOf(page.Links))
+
+	page, err = repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortCreated, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c", "a"}, aliasesOf(page.Links))
+}
+
+func testListFilters(t *testing.T, repo storage.Repository) {
+	links := map[string]string{
+		"promo1":  "https://Example.com/spring",
+		"promo2":  "https://shop.example.com:8443/sale?x=1",
+		"Promo3":  "https://example.org",
+		"news":    "http://user@example.com",
+		"promo_4": "https://notexample.com",
+	}
+	for alias, url := range links {
+		_, err := repo.SaveUrl(defaultWS, url, alias, storage.SaveOptions{})
+		require.NoError(t, err)
+	}
+
+	list := func(params storage.ListParams) []string {
+		params.Workspace, params.Sort, params.Limit = defaultWS, storage.SortAlias, 10
+
+		page, err := repo.ListUrls(params)
+		require.NoError(t, err)
+
+		return aliasesOf(page.Links)
+	}
+
+	assert.Equal(t, []string{"promo1", "promo2", "promo_4"}, list(storage.ListParams{AliasPrefix: "promo"}),
+		"the prefix is case-sensitive and _ is not a wildcard")
+	assert.Equal(t, []string{"promo_4"}, list(storage.ListParams{AliasPrefix: "promo_"}))
+	assert.Equal(t, []string{"news", "promo1", "promo2"}, list(storage.ListParams{Domain: "example.com"}),
+		"subdomains match, other domains with the same suffix do not")
+	assert.Equal(t, []string{"promo2"}, list(storage.ListParams{Domain: "shop.example.com"}))
+	assert.Equal(t, []string{"promo1", "promo2"}, list(storage.ListParams{AliasPrefix: "promo", Domain: "example.com"}))
+
+	_, err := repo.UpdateUrl(defaultWS, "https://example.net", "promo1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"news", "promo2"}, list(storage.ListParams{Domain: "example.com"}),
+		"update moves the link to the new domain")
+}
+
+func testSearch(t *testing.T, repo storage.Repository) {
+	links := map[string]string{
+		"p1":      "https://example.com/pricing",
+		"faq":     "https://example.com/docs/pricing-faq",
+		"pricing": "https://example.org/plans",
+		"other":   "https://google.com/search",
+	}
+	for alias, url := range links {
+		_, err := repo.SaveUrl(defaultWS, url, alias, storage.SaveOptions{})
+		require.NoError(t, err)
+	}
+
+	search := func(query string, limit, offset int) []string {
+		found, err := repo.SearchUrls(defaultWS, "", query, limit, offset)
+		require.NoError(t, err)
+
+		return aliasesOf(found)
+	}
+
+	found := search("pricing", 10, 0)
+	require.Len(t, found, 3)
+	assert.Equal(t, "pricing", found[0], "an alias match ranks first")
+	assert.ElementsMatch(t, []string{"p1", "faq"}, found[1:])
+
+	assert.ElementsMatch(t, []string{"p1", "faq"}, search("Example.com PRIC", 10, 0),
+		"every term has to match, terms match word prefixes case-insensitively")
+	assert.Equal(t, []string{"faq"}, search("example.com/docs", 10, 0))
+	assert.Empty(t, search("pricing google", 10, 0))
+	assert.Empty(t, search("  ?! ", 10, 0), "a query without words finds nothing")
+
+	assert.Equal(t, found[:1], search("pricing", 1, 0))
+	assert.Equal(t, found[1:2], search("pricing", 1, 1))
+	assert.Empty(t, search("pricing", 10, 3))
+}
+
+func testSearchSync(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com/pricing", "p1", storage.SaveOptions{})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl(defaultWS, "https://example.com/pricing?ref=mail", "p2", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	_, err = repo.UpdateUrl(defaultWS, "https://example.com/blog", "p1")
+	require.NoError(t, err)
+
+	found, err := repo.SearchUrls(defaultWS, "", "pricing", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p2"}, aliasesOf(found))
+
+	found, err = repo.SearchUrls(defaultWS, "", "blog", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p1"}, aliasesOf(found))
+
+	require.NoError(t, repo.DeleteUrl(defaultWS, "p2"))
+
+	found, err = repo.SearchUrls(defaultWS, "", "pricing", 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	_, err = repo.SaveUrl(defaultWS, "https://example.com/pricing", "p2", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	found, err = repo.SearchUrls(defaultWS, "", "pricing", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p2"}, aliasesOf(found), "a reused alias is found once")
+}
+
+func testExport(t *testing.T, repo storage.Repository) {
+	// больше одной порции чтения у SQL-хранилищ
+	const n = 1201
+
+	links := make([]storage.NewLink, 0, n)
+	for i := range n {
+		links = append(links, storage.NewLink{Url: fmt.Sprintf("https://example.com/%d", i), Alias: fmt.Sprintf("l%d", i)})
+	}
+	links[7].Opts.Tags = []string{"b", "a"}
+
+	_, err := repo.SaveUrls(defaultWS, links, true)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteUrl(defaultWS, "l3"))
+
+	var exported []storage.Link
+
+	err = repo.ExportUrls(defaultWS, "", func(link storage.Link) error {
+		exported = append(exported, link)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, exported, n-1)
+
+	for i := 1; i < len(exported); i++ {
+		require.Less(t, exported[i-1].ID, exported[i].ID, "links are exported in id order")
+	}
+	assert.Equal(t, "l0", exported[0].Alias)
+	assert.Equal(t, "l4", exported[3].Alias)
+	assert.Equal(t, []string{"a", "b"}, exported[6].Tags)
+
+	stop := errors.New("stop")
+	calls := 0
+
+	err = repo.ExportUrls(defaultWS, "", func(link storage.Link) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func testImport(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "taken", storage.SaveOptions{Tags: []string{"old"}})
+	require.NoError(t, err)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	maxClicks := int64(10)
+
+	report, err := repo.ImportUrls(defaultWS, []storage.Link{
+		{Alias: "a", Url: "https://a.example.com", CreatedAt: createdAt, ExpiresAt: &expiresAt,
+			MaxClicks: &maxClicks, Clicks: 4, Tags: []string{"Promo"}, Owner: "ops"},
+		{Alias: "taken", Url: "https://other.example.com"},
+		{Alias: "b", Url: "https://b.example.com"},
+		{Alias: "a", Url: "https://a2.example.com"},
+	}, storage.ConflictSkip, false)
+	require.NoError(t, err)
+	assert.Equal(t, storage.ImportReport{Created: 2, Skipped: 2, Conflicts: []string{"taken", "a"}}, report)
+
+	all := listAll(t, repo)
+	require.Equal(t, []string{"a", "taken", "b"}, aliasesOf(all), "imported links keep their creation time")
+
+	a := all[0]
+	assert.Equal(t, "https://a.example.com", a.Url)
+	assert.True(t, createdAt.Equal(a.CreatedAt))
+	require.NotNil(t, a.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*a.ExpiresAt))
+	assert.Equal(t, &maxClicks, a.MaxClicks)
+	assert.Equal(t, int64(4), a.Clicks)
+	assert.Equal(t, []string{"promo"}, a.Tags)
+	assert.Equal(t, "ops", a.Owner)
+
+	assert.Equal(t, "https://example.com", all[1].Url)
+	assert.Equal(t, []string{"old"}, all[1].Tags)
+	assert.WithinDuration(t, time.Now(), all[2].CreatedAt, time.Minute, "a link without creation time is created now")
+
+	page, err := repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortCreated, Limit: 10, Domain: "a.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, aliasesOf(page.Links))
+}
+
+func testImportOverwrite(t *testing.T, repo storage.Repository) {
+	maxClicks := int64(5)
+
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "taken", storage.SaveOptions{
+		MaxClicks: &maxClicks,
+		Tags:      []string{"old"},
+		Owner:     "ops",
+	})
+	require.NoError(t, err)
+	before := listAll(t, repo)[0]
+
+	_, err = repo.HitUrl(defaultWS, "taken")
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveClicks([]storage.Click{{Workspace: defaultWS, Alias: "taken", At: time.Now()}}))
+
+	report, err := repo.ImportUrls(defaultWS, []storage.Link{
+		{Alias: "taken", Url: "https://new.example.com", Tags: []string{"new"}, Owner: "dev"},
+		{Alias: "fresh", Url: "https://fresh.example.com"},
+	}, storage.ConflictOverwrite, false)
+	require.NoError(t, err)
+	assert.Equal(t, storage.ImportReport{Created: 1, Updated: 1, Conflicts: []string{"taken"}}, report)
+
+	url, err := repo.GetUrl(defaultWS, "taken")
+	require.NoError(t, err)
+	assert.Equal(t, "https://new.example.com", url)
+
+	all := listAll(t, repo)
+	require.Equal(t, []string{"taken", "fresh"}, aliasesOf(all))
+
+	taken := all[0]
+	assert.Equal(t, before.ID, taken.ID, "an overwritten link keeps its id")
+	assert.True(t, before.CreatedAt.Equal(taken.CreatedAt), "a zero creation time keeps the current one")
+	assert.Nil(t, taken.MaxClicks)
+	assert.Equal(t, []string{"new"}, taken.Tags, "tags are replaced")
+	assert.Equal(t, "dev", taken.Owner, "the owner is replaced")
+
+	stats, err := repo.GetStats(defaultWS, "taken")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Total, "clicks of an overwritten link are kept")
+
+	found, err := repo.SearchUrls(defaultWS, "", "new example", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"taken"}, aliasesOf(found))
+}
+
+func testImportFailAndDryRun(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "taken", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	links := []storage.Link{
+		{Alias: "a", Url: "https://a.example.com"},
+		{Alias: "taken", Url: "https://other.example.com"},
+	}
+
+	report, err := repo.ImportUrls(defaultWS, links, storage.ConflictFail, false)
+	assert.ErrorIs(t, err, storage.ErrUrlExists)
+	assert.Equal(t, storage.ImportReport{Conflicts: []string{"taken"}}, report)
+	assert.Equal(t, []string{"taken"}, aliasesOf(listAll(t, repo)), "a failed import saves nothing")
+
+	report, err = repo.ImportUrls(defaultWS, links, storage.ConflictOverwrite, true)
+	require.NoError(t, err)
+	assert.Equal(t, storage.ImportReport{Created: 1, Updated: 1, Conflicts: []string{"taken"}}, report)
+
+	report, err = repo.ImportUrls(defaultWS, links[:1], storage.ConflictFail, true)
+	require.NoError(t, err)
+	assert.Equal(t, storage.ImportReport{Created: 1}, report)
+
+	url, err := repo.GetUrl(defaultWS, "taken")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url, "a dry run changes nothing")
+	assert.Equal(t, []string{"taken"}, aliasesOf(listAll(t, repo)))
+}
+
+func testTags(t *testing.T, repo storage.Repository) {
+	_, err := repo.SaveUrl(defaultWS, "https://example.com", "example", storage.SaveOptions{
+		Tags: []string{"Spring", "team-a", " spring ", ""},
+	})
+	require.NoError(t, err)
+
+	all := listAll(t, repo)
+	require.Len(t, all, 1)
+	assert.Equal(t, []string{"spring", "team-a"}, all[0].Tags, "tags are normalized and deduplicated")
+
+	tags, err := repo.AddTags(defaultWS, "example", []string{"promo", "TEAM-A"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"promo", "spring", "team-a"}, tags)
+
+	_, err = repo.AddTags(defaultWS, "missing", []string{"promo"})
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	require.NoError(t, repo.RemoveTag(defaultWS, "example", "PROMO"))
+	assert.ErrorIs(t, repo.RemoveTag(defaultWS, "example", "promo"), storage.ErrTagNotFound)
+	assert.ErrorIs(t, repo.RemoveTag(defaultWS, "missing", "spring"), storage.ErrUrlNotFound)
+
+	all = listAll(t, repo)
+	require.Len(t, all, 1)
+	assert.Equal(t, []string{"spring", "team-a"}, all[0].Tags)
+
+	require.NoError(t, repo.DeleteUrl(defaultWS, "example"))
+	_, err = repo.SaveUrl(defaultWS, "https://example.com", "example", storage.SaveOptions{})
+	require.NoError(t, err)
+
+	all = listAll(t, repo)
+	require.Len(t, all, 1)
+	assert.Empty(t, all[0].Tags, "tags are removed together with the link")
+}
+
+func testListByTag(t *testing.T, repo storage.Repository) {
+	links := map[string][]string{
+		"a": {"spring", "team-a"},
+		"b": {"spring"},
+		"c": {"team-a"},
+		"d": nil,
+	}
+	for alias, tags := range links {
+		_, err := repo.SaveUrl(defaultWS, "https://example.com/"+alias, alias, storage.SaveOptions{Tags: tags})
+		require.NoError(t, err)
+	}
+
+	list := func(tag string) []string {
+		page, err := repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortAlias, Limit: 10, Tag: tag})
+		require.NoError(t, err)
+
+		return aliasesOf(page.Links)
+	}
+
+	assert.Equal(t, []string{"a", "b"}, list("Spring"))
+	assert.Equal(t, []string{"a", "c"}, list("team-a"))
+	assert.Empty(t, list("unknown"))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, list(""))
+
+	page, err := repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortAlias, Limit: 10, Tag: "spring"})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 2)
+	assert.Equal(t, []string{"spring", "team-a"}, page.Links[0].Tags, "a filtered link keeps all its tags")
+}
+
+func testDeleteByTag(t *testing.T, repo storage.Repository) {
+	links := map[string][]string{
+		"a": {"spring", "team-a"},
+		"b": {"spring"},
+		"c": {"team-a"},
+	}
+	for alias, tags := range links {
+		_, err := repo.SaveUrl(defaultWS, "https://example.com/"+alias, alias, storage.SaveOptions{Tags: tags})
+		require.NoError(t, err)
+	}
+
+	deleted, err := repo.DeleteByTag(defaultWS, "SPRING")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, deleted)
+
+	assert.Equal(t, []string{"c"}, aliasesOf(listAll(t, repo)))
+
+	_, err = repo.GetUrl(defaultWS, "a")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	deleted, err = repo.DeleteByTag(defaultWS, "spring")
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+// listAll returns every stored link.
+func testAPIKeys(t *testing.T, repo storage.Repository) {
+	keys, err := repo.ListAPIKeys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	createdAt := time.Now().Add(-time.Hour)
+
+	id1, err := repo.SaveAPIKey(storage.APIKey{
+		Name: "ci", Prefix: "aaaa1111", Hash: "hash1", Scopes: []string{"read", "write"}, CreatedAt: createdAt,
+	})
+	require.NoError(t, err)
+	id2, err := repo.SaveAPIKey(storage.APIKey{
+		Name: "ops", Prefix: "bbbb2222", Hash: "hash2", Scopes: []string{"admin"}, CreatedAt: createdAt,
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, id1, id2)
+
+	_, err = repo.SaveAPIKey(storage.APIKey{Name: "again", Prefix: "aaaa1111", Hash: "hash3", CreatedAt: createdAt})
+	assert.ErrorIs(t, err, storage.ErrAPIKeyExists)
+
+	key, err := repo.GetAPIKey("aaaa1111")
+	require.NoError(t, err)
+	assert.Equal(t, id1, key.ID)
+	assert.Equal(t, "ci", key.Name)
+	assert.Equal(t, "hash1", key.Hash)
+	assert.Equal(t, []string{"read", "write"}, key.Scopes)
+	assert.WithinDuration(t, createdAt, key.CreatedAt, time.Second)
+	assert.Nil(t, key.RevokedAt)
+
+	_, err = repo.GetAPIKey("cccc3333")
+	assert.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	revokedAt := time.Now()
+	require.NoError(t, repo.RevokeAPIKey(id1, revokedAt))
+	require.NoError(t, repo.RevokeAPIKey(id1, revokedAt.Add(time.Hour)), "revoking twice is not an error")
+	assert.ErrorIs(t, repo.RevokeAPIKey(id2+100, revokedAt), storage.ErrAPIKeyNotFound)
+
+	key, err = repo.GetAPIKey("aaaa1111")
+	require.NoError(t, err, "revoked keys are still found")
+	require.NotNil(t, key.RevokedAt)
+	assert.WithinDuration(t, revokedAt, *key.RevokedAt, time.Second, "the first revocation counts")
+
+	keys, err = repo.ListAPIKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, []int64{id1, id2}, []int64{keys[0].ID, keys[1].ID})
+	assert.NotNil(t, keys[0].RevokedAt)
+	assert.Nil(t, keys[1].RevokedAt)
+}
+
+func listAll(t *testing.T, repo storage.Repository) []storage.Link {
+	t.Helper()
+
+	page, err := repo.ListUrls(storage.ListParams{Workspace: defaultWS, Sort: storage.SortCreated, Limit: 1000})
+	require.NoError(t, err)
+
+	return page.Links
+}
+
+func aliasesOf(links []storage.Link) []string {
+	aliases := make([]string, 0, len(links))
+	for _, link := range links {
+		aliases = append(aliases, link.Alias)
+	}
+
+	return aliases
+}
+
+func urlMap(links []storage.Link) map[string]string {
+	m := make(map[string]string, len(links))
+	for _, link := range links {
+		m[link.Alias] = link.Url
+	}
+
+	return m
+}
+
+func testWorkspaces(t *testing.T, repo storage.Repository) {
+	ws, err := repo.GetWorkspace(storage.DefaultWorkspace)
+	require.NoError(t, err, "the default workspace always exists")
+	assert.Equal(t, storage.DefaultWorkspace, ws.Name)
+
+	createdAt := time.Now().Add(-time.Hour)
+
+	require.NoError(t, repo.CreateWorkspace(storage.Workspace{Name: "docs", CreatedAt: createdAt}))
+	require.NoError(t, repo.CreateWorkspace(storage.Workspace{Name: "blog", CreatedAt: createdAt}))
+
+	err = repo.CreateWorkspace(storage.Workspace{Name: "docs", CreatedAt: createdAt})
+	assert.ErrorIs(t, err, storage.ErrWorkspaceExists)
+
+	ws, err = repo.GetWorkspace("docs")
+	require.NoError(t, err)
+	assert.Equal(t, "docs", ws.Name)
+	assert.WithinDuration(t, createdAt, ws.CreatedAt, time.Second)
+
+	_, err = repo.GetWorkspace("missing")
+	assert.ErrorIs(t, err, storage.ErrWorkspaceNotFound)
+
+	list, err := repo.ListWorkspaces()
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(list))
+	for _, ws := range list {
+		names = append(names, ws.Name)
+	}
+	assert.Equal(t, []string{"blog", storage.DefaultWorkspace, "docs"}, names)
+}
+
+func testWorkspaceIsolation(t *testing.T, repo storage.Repository) {
+	require.NoError(t, repo.CreateWorkspace(storage.Workspace{Name: "docs", CreatedAt: time.Now()}))
+
+	_, err := repo.SaveUrl(defaultWS, "https://example.com/help", "help", storage.SaveOptions{
+		Tags: []string{"spring"}, Generated: true, Owner: "ops",
+	})
+	require.NoError(t, err)
+	_, err = repo.SaveUrl("docs", "https://docs.example.com/help", "help", storage.SaveOptions{
+		Tags: []string{"spring"}, Generated: true, Owner: "ops",
+	})
+	require.NoError(t, err, "the same alias is free in another workspace")
+
+	_, err = repo.SaveUrl("docs", "https://docs.example.com/other", "help", storage.SaveOptions{})
+	assert.ErrorIs(t, err, storage.ErrUrlExists)
+
+	url, err := repo.GetUrl(defaultWS, "help")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/help", url)
+
+	url, err = repo.HitUrl("docs", "help")
+	require.NoError(t, err)
+	assert.Equal(t, "https://docs.example.com/help", url)
+
+	_, err = repo.FindAlias("docs", "https://example.com/help", "ops")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound, "links of other workspaces are not reused")
+
+	found, err := repo.SearchUrls("docs", "", "help", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "https://docs.example.com/help", found[0].Url)
+
+	require.NoError(t, repo.SaveClicks([]storage.Click{{Workspace: "docs", Alias: "help", At: time.Now()}}))
+
+	stats, err := repo.GetStats(defaultWS, "help")
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total)
+
+	stats, err = repo.GetStats("docs", "help")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats.Total)
+
+	_, err = repo.UpdateUrl("docs", "https://docs.example.com/faq", "help")
+	require.NoError(t, err)
+	require.NoError(t, repo.SetOwner("docs", "help", "dev"))
+	require.NoError(t, repo.RenameAlias("docs", "help", "faq"))
+
+	url, err = repo.GetUrl(defaultWS, "help")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/help", url, "changes stay in their workspace")
+
+	owner, err := repo.GetOwner(defaultWS, "help")
+	require.NoError(t, err)
+	assert.Equal(t, "ops", owner)
+
+	_, err = repo.GetOwner("docs", "help")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	page, err := repo.ListUrls(storage.ListParams{Workspace: "docs", Sort: storage.SortAlias, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"faq"}, aliasesOf(page.Links))
+
+	var exported []string
+	require.NoError(t, repo.ExportUrls("docs", "", func(link storage.Link) error {
+		exported = append(exported, link.Alias)
+		return nil
+	}))
+	assert.Equal(t, []string{"faq"}, exported)
+
+	report, err := repo.ImportUrls("docs", []storage.Link{{Alias: "help", Url: "https://docs.example.com/help"}},
+		storage.ConflictFail, false)
+	require.NoError(t, err, "aliases of other workspaces are no conflict")
+	assert.Equal(t, 1, report.Created)
+
+	deleted, err := repo.DeleteByTag("docs", "spring")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+
+	_, err = repo.GetUrl("docs", "faq")
+	assert.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = repo.GetUrl(defaultWS, "help")
+	require.NoError(t, err, "deleting by tag stays in the workspace")
+
+	require.NoError(t, repo.DeleteUrl("docs", "help"))
+	_, err = repo.GetUrl(defaultWS, "help")
+	require.NoError(t, err)
+}
+
+func testMembers(t *testing.T, repo storage.Repository) {
+	err := repo.SetMember(storage.Member{Workspace: "missing", Name: "ops", Role: "editor"})
+	assert.ErrorIs(t, err, storage.ErrWorkspaceNotFound)
+
+	require.NoError(t, repo.CreateWorkspace(storage.Workspace{Name: "docs", CreatedAt: time.Now()}))
+
+	require.NoError(t, repo.SetMember(storage.Member{Workspace: "docs", Name: "ops", Role: "viewer"}))
+	require.NoError(t, repo.SetMember(storage.Member{Workspace: "docs", Name: "dev", Role: "admin"}))
+	require.NoError(t, repo.SetMember(storage.Member{Workspace: "docs", Name: "ops", Role: "editor"}))
+	require.NoError(t, repo.SetMember(storage.Member{Workspace: defaultWS, Name: "ops", Role: "viewer"}))
+
+	member, err := repo.GetMember("docs", "ops")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Member{Workspace: "docs", Name: "ops", Role: "editor"}, member)
+
+	_, err = repo.GetMember("docs", "qa")
+	assert.ErrorIs(t, err, storage.ErrMemberNotFound)
+
+	members, err := repo.ListMembers("docs")
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Member{
+		{Workspace: "docs", Name: "dev", Role: "admin"},
+		{Workspace: "docs", Name: "ops", Role: "editor"},
+	}, members)
+
+	require.NoError(t, repo.RemoveMember("docs", "ops"))
+	assert.ErrorIs(t, repo.RemoveMember("docs", "ops"), storage.ErrMemberNotFound)
+
+	_, err = repo.GetMember("docs", "ops")
+	assert.ErrorIs(t, err, storage.ErrMemberNotFound)
+
+	member, err = repo.GetMember(defaultWS, "ops")
+	require.NoError(t, err)
+	assert.Equal(t, "viewer", member.Role)
+
+	members, err = repo.ListMembers("blog")
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}